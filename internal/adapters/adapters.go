@@ -5,41 +5,95 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"os/exec"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/derekurban/profilex-cli/internal/store"
 )
 
 type Status struct {
-	LoggedIn bool   `json:"logged_in"`
-	Method   string `json:"method,omitempty"`
-	Raw      string `json:"raw,omitempty"`
+	LoggedIn     bool       `json:"logged_in"`
+	Method       string     `json:"method,omitempty"`
+	AuthKind     AuthKind   `json:"auth_kind,omitempty"`
+	Email        string     `json:"email,omitempty"`
+	Organization string     `json:"organization,omitempty"`
+	Plan         string     `json:"plan,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Source       string     `json:"source,omitempty"`
+	Raw          string     `json:"raw,omitempty"`
+	// Refreshable is set when a refresh token is present, so the tool renews
+	// the access token behind ExpiresAt on its own.
+	Refreshable bool `json:"refreshable,omitempty"`
+}
+
+// LoginOptions selects how Login authenticates a profile. The zero value
+// starts the tool's interactive OAuth flow.
+type LoginOptions struct {
+	// APIKey logs in with an API key instead of OAuth. It is passed on stdin,
+	// never on the command line.
+	APIKey string
+}
+
+// EnvAssignment is one variable an adapter sets to isolate a profile.
+type EnvAssignment struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (e EnvAssignment) String() string {
+	return e.Name + "=" + e.Value
 }
 
 type Adapter interface {
 	Tool() store.Tool
 	Binary() string
-	EnvVar() string
+	// WithBinary returns a copy that runs path instead of the definition's
+	// binary. An empty path keeps the default.
+	WithBinary(path string) Adapter
+	// Version runs the tool's version command and returns the version it
+	// reports.
+	Version(ctx context.Context) (string, error)
+	// Env returns the variables that point the tool at profileDir. Single
+	// config dir tools set one; XDG-based tools may redirect several.
+	Env(profileDir string) []EnvAssignment
+	// CredentialFiles lists the files, relative to the profile directory,
+	// that hold live credentials. Vault mode seals these between runs.
+	CredentialFiles() []string
+	// ConflictingEnvVars lists inherited variables that would make the tool
+	// authenticate as a different account than the profile's.
+	ConflictingEnvVars() []string
+	// APIKeyEnvVar names the variable API-key profiles inject at launch.
+	APIKeyEnvVar() string
 	RunCommand(profileDir string, args []string) *exec.Cmd
+	// OfflineStatus reads account details from the profile's credential and
+	// config files. ok is false when no credentials were found.
+	OfflineStatus(profileDir string) (status Status, ok bool, err error)
+	// Status prefers OfflineStatus and falls back to probing the tool's CLI.
 	Status(ctx context.Context, profileDir string) (Status, error)
+	// LoginCommand returns the tool command that authenticates the profile.
+	LoginCommand(profileDir string, opts LoginOptions) (*exec.Cmd, error)
+	// LogoutCommand returns the tool command that clears the profile's
+	// credentials.
+	LogoutCommand(profileDir string) *exec.Cmd
 }
 
+// Get returns the adapter for a registered tool definition.
 func Get(tool store.Tool) (Adapter, error) {
-	switch tool {
-	case store.ToolClaude:
-		return Claude{}, nil
-	case store.ToolCodex:
-		return Codex{}, nil
-	default:
+	def, ok := Lookup(tool)
+	if !ok {
 		return nil, fmt.Errorf("unsupported tool: %s", tool)
 	}
+	return declared{def: def}, nil
 }
 
 func ensureBinary(binary string) error {
 	_, err := exec.LookPath(binary)
 	if err != nil {
+		if strings.ContainsAny(binary, `/\`) {
+			return fmt.Errorf("pinned binary %s not found or not executable", binary)
+		}
 		return fmt.Errorf("%s not found in PATH", binary)
 	}
 	return nil
@@ -50,75 +104,203 @@ func runCombined(ctx context.Context, cmd *exec.Cmd) (string, error) {
 	return strings.TrimSpace(string(b)), err
 }
 
-type Claude struct{}
+// declared implements Adapter from a Definition.
+type declared struct {
+	def    Definition
+	binary string
+}
 
-func (Claude) Tool() store.Tool { return store.ToolClaude }
-func (Claude) Binary() string   { return "claude" }
-func (Claude) EnvVar() string   { return "CLAUDE_CONFIG_DIR" }
+func (a declared) Tool() store.Tool { return store.Tool(a.def.Name) }
 
-func (c Claude) withEnv(profileDir string, args ...string) *exec.Cmd {
-	cmd := exec.Command(c.Binary(), args...)
-	cmd.Env = append(os.Environ(), c.EnvVar()+"="+profileDir)
-	return cmd
+func (a declared) Binary() string {
+	if a.binary != "" {
+		return a.binary
+	}
+	return a.def.Binary
 }
 
-func (c Claude) RunCommand(profileDir string, args []string) *exec.Cmd {
-	return c.withEnv(profileDir, args...)
+func (a declared) WithBinary(path string) Adapter {
+	a.binary = strings.TrimSpace(path)
+	return a
 }
 
-func (c Claude) Status(ctx context.Context, profileDir string) (Status, error) {
-	if err := ensureBinary(c.Binary()); err != nil {
-		return Status{}, err
+var versionPattern = regexp.MustCompile(`v?(\d+\.\d+(?:\.\d+)?(?:[-+][0-9A-Za-z.-]+)?)`)
+
+func (a declared) Version(ctx context.Context) (string, error) {
+	if err := ensureBinary(a.Binary()); err != nil {
+		return "", err
+	}
+	args := []string{"--version"}
+	if a.def.Version != nil {
+		args = a.def.Version.Args
 	}
-	cmd := exec.CommandContext(ctx, c.Binary(), "auth", "status", "--json")
-	cmd.Env = append(os.Environ(), c.EnvVar()+"="+profileDir)
+	cmd := exec.CommandContext(ctx, a.Binary(), args...)
 	out, err := runCombined(ctx, cmd)
 	if err != nil {
-		return Status{}, err
-	}
-	var parsed struct {
-		LoggedIn   bool   `json:"loggedIn"`
-		AuthMethod string `json:"authMethod"`
+		return "", fmt.Errorf("%s %s: %w", a.Binary(), strings.Join(args, " "), err)
 	}
-	if jErr := json.Unmarshal([]byte(out), &parsed); jErr != nil {
-		return Status{Raw: out}, nil
+	line, _, _ := strings.Cut(out, "\n")
+	line = strings.TrimSpace(line)
+	if m := versionPattern.FindStringSubmatch(line); m != nil {
+		return m[1], nil
 	}
-	return Status{LoggedIn: parsed.LoggedIn, Method: parsed.AuthMethod, Raw: out}, nil
+	return line, nil
+}
+
+func (a declared) Env(profileDir string) []EnvAssignment {
+	return a.def.EnvAssignments(profileDir)
 }
 
-type Codex struct{}
+func (a declared) APIKeyEnvVar() string { return a.def.APIKeyEnv }
+
+func (a declared) CredentialFiles() []string { return a.def.CredentialFiles }
 
-func (Codex) Tool() store.Tool { return store.ToolCodex }
-func (Codex) Binary() string   { return "codex" }
-func (Codex) EnvVar() string   { return "CODEX_HOME" }
+func (a declared) ConflictingEnvVars() []string { return a.def.ConflictingEnv }
 
-func (c Codex) withEnv(profileDir string, args ...string) *exec.Cmd {
-	cmd := exec.Command(c.Binary(), args...)
-	cmd.Env = append(os.Environ(), c.EnvVar()+"="+profileDir)
+func (a declared) withEnv(profileDir string, args ...string) *exec.Cmd {
+	cmd := exec.Command(a.Binary(), args...)
+	cmd.Env = profileEnviron(a, profileDir)
 	return cmd
 }
 
-func (c Codex) RunCommand(profileDir string, args []string) *exec.Cmd {
-	return c.withEnv(profileDir, args...)
+func (a declared) RunCommand(profileDir string, args []string) *exec.Cmd {
+	return a.withEnv(profileDir, args...)
+}
+
+func (a declared) OfflineStatus(profileDir string) (Status, bool, error) {
+	read, ok := credentialReaders[a.def.Credentials]
+	if !ok {
+		return Status{}, false, nil
+	}
+	return read(profileDir)
 }
 
-func (c Codex) Status(ctx context.Context, profileDir string) (Status, error) {
-	if err := ensureBinary(c.Binary()); err != nil {
+func (a declared) Status(ctx context.Context, profileDir string) (Status, error) {
+	if st, ok, err := a.OfflineStatus(profileDir); err == nil && ok {
+		return st, nil
+	}
+	spec := a.def.Status
+	if spec == nil {
+		return Status{}, nil
+	}
+	if err := ensureBinary(a.Binary()); err != nil {
 		return Status{}, err
 	}
-	cmd := exec.CommandContext(ctx, c.Binary(), "login", "status")
-	cmd.Env = append(os.Environ(), c.EnvVar()+"="+profileDir)
+	cmd := exec.CommandContext(ctx, a.Binary(), spec.Args...)
+	cmd.Env = profileEnviron(a, profileDir)
 	out, err := runCombined(ctx, cmd)
+	if spec.Parser == StatusParserJSON {
+		if err != nil {
+			return Status{}, err
+		}
+		return parseJSONStatus(spec, out), nil
+	}
+	return parseTextStatus(spec, out, err)
+}
+
+func parseJSONStatus(spec *StatusSpec, out string) Status {
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		return Status{Source: SourceCLI, Raw: out}
+	}
+	loggedIn, _ := jsonField(parsed, spec.LoggedInField).(bool)
+	method, _ := jsonField(parsed, spec.MethodField).(string)
+	email, _ := jsonField(parsed, spec.EmailField).(string)
+	st := Status{LoggedIn: loggedIn, Method: method, Email: email, Source: SourceCLI, Raw: out}
+	if loggedIn {
+		st.AuthKind = authKindForMethod(method)
+	}
+	return st
+}
+
+// jsonField follows a dotted path through nested objects.
+func jsonField(v map[string]any, path string) any {
+	if path == "" {
+		return nil
+	}
+	var cur any = v
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = obj[key]
+	}
+	return cur
+}
+
+func parseTextStatus(spec *StatusSpec, out string, err error) (Status, error) {
 	low := strings.ToLower(out)
-	if strings.Contains(low, "not logged") || strings.Contains(low, "logged out") {
-		return Status{LoggedIn: false, Raw: out}, nil
+	if containsAny(low, spec.LoggedOutPatterns) != "" {
+		return Status{LoggedIn: false, Source: SourceCLI, Raw: out}, nil
 	}
 	if err == nil {
-		return Status{LoggedIn: true, Raw: out}, nil
+		st := Status{LoggedIn: true, Source: SourceCLI, Raw: out}
+		if containsAny(low, spec.APIKeyPatterns) != "" {
+			st.AuthKind = AuthAPIKey
+			st.Method = "api_key"
+		} else if m := containsAny(low, spec.OAuthPatterns); m != "" {
+			st.AuthKind = AuthOAuth
+			st.Method = m
+		}
+		return st, nil
 	}
 	var ee *exec.ExitError
 	if errors.As(err, &ee) {
-		return Status{LoggedIn: false, Raw: out}, nil
+		return Status{LoggedIn: false, Source: SourceCLI, Raw: out}, nil
 	}
 	return Status{}, err
 }
+
+// containsAny returns the first pattern found in low, lowercased.
+func containsAny(low string, patterns []string) string {
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if p != "" && strings.Contains(low, p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// LoginCommand runs the definition's login command, or its login_api_key
+// command with the key on stdin.
+func (a declared) LoginCommand(profileDir string, opts LoginOptions) (*exec.Cmd, error) {
+	if opts.APIKey != "" {
+		spec := a.def.LoginAPIKey
+		if spec == nil {
+			if a.def.APIKeyEnv != "" {
+				return nil, fmt.Errorf("%s does not support API key login; set %s instead", a.def.Name, a.def.APIKeyEnv)
+			}
+			return nil, fmt.Errorf("%s does not support API key login", a.def.Name)
+		}
+		cmd := a.withEnv(profileDir, spec.Args...)
+		if spec.Stdin {
+			cmd.Stdin = strings.NewReader(opts.APIKey + "\n")
+		}
+		return cmd, nil
+	}
+	if a.def.Login == nil {
+		return nil, fmt.Errorf("%s has no login command", a.def.Name)
+	}
+	return a.withEnv(profileDir, a.def.Login.Args...), nil
+}
+
+// LogoutCommand returns nil when the definition has no logout command.
+func (a declared) LogoutCommand(profileDir string) *exec.Cmd {
+	if a.def.Logout == nil {
+		return nil
+	}
+	return a.withEnv(profileDir, a.def.Logout.Args...)
+}
+
+func authKindForMethod(method string) AuthKind {
+	low := strings.ToLower(method)
+	if strings.Contains(low, "api") && strings.Contains(low, "key") {
+		return AuthAPIKey
+	}
+	if low == "" {
+		return ""
+	}
+	return AuthOAuth
+}
@@ -1,8 +1,14 @@
 package adapters
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/derekurban/profilex-cli/internal/store"
 )
@@ -16,8 +22,17 @@ func hasEnvPrefix(env []string, prefix string) bool {
 	return false
 }
 
+func mustGet(t *testing.T, tool store.Tool) Adapter {
+	t.Helper()
+	a, err := Get(tool)
+	if err != nil {
+		t.Fatalf("get %s adapter: %v", tool, err)
+	}
+	return a
+}
+
 func TestClaudeCommandEnvironment(t *testing.T) {
-	var a Claude
+	a := mustGet(t, store.ToolClaude)
 	cmd := a.RunCommand("/tmp/claude-p", []string{"auth", "login"})
 	if got := cmd.Args; len(got) < 3 || got[0] != "claude" || got[1] != "auth" || got[2] != "login" {
 		t.Fatalf("unexpected run args: %#v", got)
@@ -27,15 +42,28 @@ func TestClaudeCommandEnvironment(t *testing.T) {
 	}
 }
 
+func TestRunCommandStripsConflictingEnv(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "sk-other")
+	t.Setenv("CLAUDE_CONFIG_DIR", "/tmp/other-profile")
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	cmd := mustGet(t, store.ToolClaude).RunCommand("/tmp/claude-p", nil)
+	if hasEnvPrefix(cmd.Env, "ANTHROPIC_API_KEY=") || hasEnvPrefix(cmd.Env, "CLAUDE_CONFIG_DIR=/tmp/other-profile") {
+		t.Fatalf("inherited account variables should be stripped: %v", cmd.Env)
+	}
+	if !hasEnvPrefix(cmd.Env, "OPENAI_API_KEY=") {
+		t.Fatalf("variables for other tools should be kept")
+	}
+}
+
 func TestClaudeEnvVar(t *testing.T) {
-	var a Claude
-	if a.EnvVar() != "CLAUDE_CONFIG_DIR" {
-		t.Fatalf("unexpected env var: %s", a.EnvVar())
+	a := mustGet(t, store.ToolClaude)
+	if env := a.Env("/tmp/claude-p"); len(env) != 1 || env[0].String() != "CLAUDE_CONFIG_DIR=/tmp/claude-p" {
+		t.Fatalf("unexpected env: %v", env)
 	}
 }
 
 func TestCodexCommandEnvironment(t *testing.T) {
-	var a Codex
+	a := mustGet(t, store.ToolCodex)
 	cmd := a.RunCommand("/tmp/codex-p", []string{"--profile", "deep"})
 	if got := cmd.Args; len(got) < 1 || got[0] != "codex" {
 		t.Fatalf("unexpected run args: %#v", got)
@@ -46,9 +74,9 @@ func TestCodexCommandEnvironment(t *testing.T) {
 }
 
 func TestCodexEnvVar(t *testing.T) {
-	var a Codex
-	if a.EnvVar() != "CODEX_HOME" {
-		t.Fatalf("unexpected env var: %s", a.EnvVar())
+	a := mustGet(t, store.ToolCodex)
+	if env := a.Env("/tmp/codex-p"); len(env) != 1 || env[0].String() != "CODEX_HOME=/tmp/codex-p" {
+		t.Fatalf("unexpected env: %v", env)
 	}
 }
 
@@ -63,3 +91,217 @@ func TestGetAdapter(t *testing.T) {
 		t.Fatalf("unknown adapter should fail")
 	}
 }
+
+func TestLoginLogoutCommands(t *testing.T) {
+	cmd, err := mustGet(t, store.ToolClaude).LoginCommand("/tmp/claude-p", LoginOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cmd.Args; len(got) != 2 || got[1] != "/login" || !hasEnvPrefix(cmd.Env, "CLAUDE_CONFIG_DIR=/tmp/claude-p") {
+		t.Fatalf("unexpected claude login: %#v", got)
+	}
+	if _, err := mustGet(t, store.ToolClaude).LoginCommand("/tmp/claude-p", LoginOptions{APIKey: "sk-ant"}); err == nil {
+		t.Fatalf("claude API key login should be rejected")
+	}
+	if got := mustGet(t, store.ToolClaude).LogoutCommand("/tmp/claude-p").Args; strings.Join(got[1:], " ") != "auth logout" {
+		t.Fatalf("unexpected claude logout: %#v", got)
+	}
+
+	cmd, err = mustGet(t, store.ToolCodex).LoginCommand("/tmp/codex-p", LoginOptions{APIKey: "sk-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cmd.Args; strings.Join(got[1:], " ") != "login --with-api-key" || !hasEnvPrefix(cmd.Env, "CODEX_HOME=/tmp/codex-p") {
+		t.Fatalf("unexpected codex login: %#v", got)
+	}
+	if strings.Contains(strings.Join(cmd.Args, " "), "sk-test") || cmd.Stdin == nil {
+		t.Fatalf("API key must be passed on stdin, not argv")
+	}
+	if got := mustGet(t, store.ToolCodex).LogoutCommand("/tmp/codex-p").Args; strings.Join(got[1:], " ") != "logout" {
+		t.Fatalf("unexpected codex logout: %#v", got)
+	}
+}
+
+func fakeJWT(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	b, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(b) + ".sig"
+}
+
+func TestClaudeOfflineStatusReadsCredentialFiles(t *testing.T) {
+	dir := t.TempDir()
+	exp := time.Now().Add(3 * time.Hour).Truncate(time.Millisecond).UTC()
+	creds := fmt.Sprintf(`{"claudeAiOauth":{"accessToken":"a","refreshToken":"r","expiresAt":%d,"subscriptionType":"max"}}`, exp.UnixMilli())
+	if err := os.WriteFile(filepath.Join(dir, ".credentials.json"), []byte(creds), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"oauthAccount":{"emailAddress":"dev@example.com","organizationName":"Acme"}}`
+	if err := os.WriteFile(filepath.Join(dir, ".claude.json"), []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	st, ok, err := mustGet(t, store.ToolClaude).OfflineStatus(dir)
+	if err != nil || !ok {
+		t.Fatalf("expected offline status, ok=%v err=%v", ok, err)
+	}
+	if !st.LoggedIn || st.AuthKind != AuthOAuth || st.Email != "dev@example.com" || st.Organization != "Acme" || st.Plan != "max" {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if st.ExpiresAt == nil || !st.ExpiresAt.Equal(exp) || !st.Refreshable {
+		t.Fatalf("unexpected expiry: %v refreshable=%v", st.ExpiresAt, st.Refreshable)
+	}
+}
+
+func TestCodexOfflineStatusReadsAuthJSON(t *testing.T) {
+	dir := t.TempDir()
+	exp := time.Now().Add(24 * time.Hour).Unix()
+	idToken := fakeJWT(t, map[string]any{
+		"email": "me@example.com",
+		"https://api.openai.com/auth": map[string]any{
+			"chatgpt_plan_type": "pro",
+			"organizations":     []any{map[string]any{"id": "org-1", "title": "Personal", "is_default": true}},
+		},
+	})
+	accessToken := fakeJWT(t, map[string]any{"exp": exp})
+	auth := fmt.Sprintf(`{"OPENAI_API_KEY":null,"tokens":{"id_token":%q,"access_token":%q,"refresh_token":"r"}}`, idToken, accessToken)
+	if err := os.WriteFile(filepath.Join(dir, "auth.json"), []byte(auth), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	st, ok, err := mustGet(t, store.ToolCodex).OfflineStatus(dir)
+	if err != nil || !ok {
+		t.Fatalf("expected offline status, ok=%v err=%v", ok, err)
+	}
+	if st.Email != "me@example.com" || st.Plan != "pro" || st.Organization != "Personal" || st.AuthKind != AuthOAuth {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if st.ExpiresAt == nil || st.ExpiresAt.Unix() != exp || !st.Refreshable {
+		t.Fatalf("unexpected expiry: %v refreshable=%v", st.ExpiresAt, st.Refreshable)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "auth.json"), []byte(`{"OPENAI_API_KEY":"sk-test"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	st, ok, err = mustGet(t, store.ToolCodex).OfflineStatus(dir)
+	if err != nil || !ok || st.AuthKind != AuthAPIKey {
+		t.Fatalf("expected api key auth, got %+v ok=%v err=%v", st, ok, err)
+	}
+}
+
+func TestOfflineStatusMissingFiles(t *testing.T) {
+	if _, ok, err := mustGet(t, store.ToolClaude).OfflineStatus(t.TempDir()); ok || err != nil {
+		t.Fatalf("expected no offline claude status, ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := mustGet(t, store.ToolCodex).OfflineStatus(t.TempDir()); ok || err != nil {
+		t.Fatalf("expected no offline codex status, ok=%v err=%v", ok, err)
+	}
+}
+
+func TestLoadDirRegistersCustomDefinition(t *testing.T) {
+	dir := t.TempDir()
+	def := `{
+  "name": "gemini",
+  "binary": "gemini",
+  "config_env": "GEMINI_CONFIG_DIR",
+  "api_key_env": "GEMINI_API_KEY",
+  "conflicting_env": ["GEMINI_API_KEY", "GOOGLE_API_KEY"],
+  "settings_files": ["settings.json"],
+  "status": {"args": ["auth", "status"], "parser": "text", "logged_out_patterns": ["not signed in"], "oauth_patterns": ["google"]}
+}`
+	if err := os.WriteFile(filepath.Join(dir, "gemini.json"), []byte(def), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"name": "Bad Name"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDir(dir); err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Fatalf("expected error naming the invalid file, got %v", err)
+	}
+
+	tool, ok := store.IsSupportedTool("gemini")
+	if !ok {
+		t.Fatalf("gemini should be a supported tool after loading")
+	}
+	a := mustGet(t, tool)
+	t.Setenv("GOOGLE_API_KEY", "other")
+	cmd := a.RunCommand("/tmp/gemini-p", []string{"chat"})
+	if cmd.Args[0] != "gemini" || !hasEnvPrefix(cmd.Env, "GEMINI_CONFIG_DIR=/tmp/gemini-p") || hasEnvPrefix(cmd.Env, "GOOGLE_API_KEY=") {
+		t.Fatalf("unexpected command: %v %v", cmd.Args, cmd.Env)
+	}
+	if _, err := a.LoginCommand("/tmp/gemini-p", LoginOptions{APIKey: "k"}); err == nil || !strings.Contains(err.Error(), "GEMINI_API_KEY") {
+		t.Fatalf("expected API key login to point at GEMINI_API_KEY, got %v", err)
+	}
+	if a.LogoutCommand("/tmp/gemini-p") != nil {
+		t.Fatalf("definition without logout should have no logout command")
+	}
+}
+
+func TestDefinitionStatusParsers(t *testing.T) {
+	text := &StatusSpec{Parser: StatusParserText, LoggedOutPatterns: []string{"not logged"}, APIKeyPatterns: []string{"api key"}, OAuthPatterns: []string{"chatgpt"}}
+	if st, _ := parseTextStatus(text, "Logged in using ChatGPT", nil); !st.LoggedIn || st.Method != "chatgpt" || st.AuthKind != AuthOAuth {
+		t.Fatalf("unexpected text status: %+v", st)
+	}
+	if st, _ := parseTextStatus(text, "Not logged in", nil); st.LoggedIn {
+		t.Fatalf("logged-out pattern should win: %+v", st)
+	}
+
+	js := &StatusSpec{Parser: StatusParserJSON, LoggedInField: "auth.ok", MethodField: "auth.method", EmailField: "user.email"}
+	st := parseJSONStatus(js, `{"auth": {"ok": true, "method": "api_key"}, "user": {"email": "a@example.com"}}`)
+	if !st.LoggedIn || st.AuthKind != AuthAPIKey || st.Email != "a@example.com" {
+		t.Fatalf("unexpected json status: %+v", st)
+	}
+}
+
+func TestParseDefinitionRejectsUnsafePaths(t *testing.T) {
+	for _, raw := range []string{
+		`{"name": "x", "binary": "x", "config_env": "X_HOME", "settings_files": ["../escape.json"]}`,
+		`{"name": "x", "binary": "x", "config_env": "X_HOME", "credential_files": ["/etc/passwd"]}`,
+		`{"name": "x", "binary": "x", "config_env": "X_HOME", "settings_files": ["auth"], "credential_files": ["auth/token.json"]}`,
+		`{"name": "x", "binary": "x", "config_env": "X HOME"}`,
+		`{"name": "x", "binary": "x", "config_env": "X_HOME", "unknown": true}`,
+		`{"name": "x", "binary": "x", "config_env": "X_HOME", "credentials": "claude", "credential_files": [".credentials.json"]}`,
+	} {
+		if _, err := ParseDefinition([]byte(raw)); err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestXDGDefinitionSetsEveryAssignment(t *testing.T) {
+	def, err := ParseDefinition([]byte(`{
+  "name": "crush",
+  "binary": "crush",
+  "env": {"XDG_CONFIG_HOME": "{profile}/config", "XDG_DATA_HOME": "{profile}/data", "XDG_STATE_HOME": "{profile}/state"},
+  "session_leaf": "data/crush/sessions"
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(def); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_DATA_HOME", "/home/me/.local/share")
+	cmd := mustGet(t, "crush").RunCommand("/tmp/crush-p", nil)
+	for _, want := range []string{"XDG_CONFIG_HOME=/tmp/crush-p/config", "XDG_DATA_HOME=/tmp/crush-p/data", "XDG_STATE_HOME=/tmp/crush-p/state"} {
+		if !hasEnvPrefix(cmd.Env, filepath.FromSlash(want)) {
+			t.Fatalf("missing %s in %v", want, cmd.Env)
+		}
+	}
+	if hasEnvPrefix(cmd.Env, "XDG_DATA_HOME=/home/me") {
+		t.Fatalf("inherited XDG_DATA_HOME should be replaced")
+	}
+	leaves := def.SessionEnvLeaves()
+	if len(leaves) != 1 || leaves[0].Name != "XDG_DATA_HOME" || leaves[0].Value != "crush/sessions" {
+		t.Fatalf("unexpected session env leaves: %v", leaves)
+	}
+
+	for _, bad := range []string{`"../x"`, `"/abs"`, `"{home}/x"`} {
+		raw := `{"name": "x", "binary": "x", "env": {"XDG_DATA_HOME": ` + bad + `}}`
+		if _, err := ParseDefinition([]byte(raw)); err == nil {
+			t.Fatalf("expected env template %s to be rejected", bad)
+		}
+	}
+}
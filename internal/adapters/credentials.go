@@ -0,0 +1,189 @@
+package adapters
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type AuthKind string
+
+const (
+	AuthOAuth  AuthKind = "oauth"
+	AuthAPIKey AuthKind = "api_key"
+)
+
+const (
+	SourceCredentials = "credentials"
+	SourceCLI         = "cli"
+	SourceSecret      = "secret"
+	SourceVault       = "vault"
+)
+
+// readJSONFile decodes path into v. It reports false when the file does not
+// exist so callers can fall back to the CLI probe.
+func readJSONFile(path string, v any) (bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return false, nil
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// claudeCredentialStatus reads <dir>/.credentials.json (OAuth tokens) and
+// <dir>/.claude.json (account metadata and API key) without running claude.
+func claudeCredentialStatus(profileDir string) (Status, bool, error) {
+	var creds struct {
+		OAuth *struct {
+			AccessToken      string   `json:"accessToken"`
+			RefreshToken     string   `json:"refreshToken"`
+			ExpiresAt        int64    `json:"expiresAt"`
+			Scopes           []string `json:"scopes"`
+			SubscriptionType string   `json:"subscriptionType"`
+		} `json:"claudeAiOauth"`
+	}
+	hasCreds, err := readJSONFile(filepath.Join(profileDir, ".credentials.json"), &creds)
+	if err != nil {
+		return Status{}, false, err
+	}
+
+	var cfg struct {
+		OAuthAccount *struct {
+			EmailAddress     string `json:"emailAddress"`
+			OrganizationName string `json:"organizationName"`
+			OrganizationUUID string `json:"organizationUuid"`
+		} `json:"oauthAccount"`
+		PrimaryAPIKey string `json:"primaryApiKey"`
+	}
+	hasCfg, err := readJSONFile(filepath.Join(profileDir, ".claude.json"), &cfg)
+	if err != nil {
+		return Status{}, false, err
+	}
+
+	st := Status{Source: SourceCredentials}
+	if cfg.OAuthAccount != nil {
+		st.Email = cfg.OAuthAccount.EmailAddress
+		st.Organization = firstNonEmpty(cfg.OAuthAccount.OrganizationName, cfg.OAuthAccount.OrganizationUUID)
+	}
+	switch {
+	case hasCreds && creds.OAuth != nil && (creds.OAuth.AccessToken != "" || creds.OAuth.RefreshToken != ""):
+		st.LoggedIn = true
+		st.AuthKind = AuthOAuth
+		st.Method = "claude.ai"
+		st.Plan = creds.OAuth.SubscriptionType
+		st.Refreshable = strings.TrimSpace(creds.OAuth.RefreshToken) != ""
+		if creds.OAuth.ExpiresAt > 0 {
+			exp := time.UnixMilli(creds.OAuth.ExpiresAt).UTC()
+			st.ExpiresAt = &exp
+		}
+	case hasCfg && strings.TrimSpace(cfg.PrimaryAPIKey) != "":
+		st.LoggedIn = true
+		st.AuthKind = AuthAPIKey
+		st.Method = "api_key"
+	default:
+		return Status{}, false, nil
+	}
+	return st, true, nil
+}
+
+// codexCredentialStatus reads <dir>/auth.json without running codex. ChatGPT
+// logins carry account details in the id_token JWT claims.
+func codexCredentialStatus(profileDir string) (Status, bool, error) {
+	var auth struct {
+		APIKey *string `json:"OPENAI_API_KEY"`
+		Tokens *struct {
+			IDToken      string `json:"id_token"`
+			AccessToken  string `json:"access_token"`
+			RefreshToken string `json:"refresh_token"`
+			AccountID    string `json:"account_id"`
+		} `json:"tokens"`
+	}
+	ok, err := readJSONFile(filepath.Join(profileDir, "auth.json"), &auth)
+	if err != nil || !ok {
+		return Status{}, false, err
+	}
+
+	st := Status{Source: SourceCredentials}
+	if auth.Tokens != nil && (auth.Tokens.AccessToken != "" || auth.Tokens.RefreshToken != "" || auth.Tokens.IDToken != "") {
+		st.LoggedIn = true
+		st.AuthKind = AuthOAuth
+		st.Method = "chatgpt"
+		st.Refreshable = strings.TrimSpace(auth.Tokens.RefreshToken) != ""
+
+		var claims struct {
+			Email string `json:"email"`
+			Exp   int64  `json:"exp"`
+			Auth  struct {
+				PlanType      string `json:"chatgpt_plan_type"`
+				Organizations []struct {
+					ID        string `json:"id"`
+					Title     string `json:"title"`
+					IsDefault bool   `json:"is_default"`
+				} `json:"organizations"`
+			} `json:"https://api.openai.com/auth"`
+		}
+		if decodeJWTClaims(auth.Tokens.IDToken, &claims) == nil {
+			st.Email = claims.Email
+			st.Plan = claims.Auth.PlanType
+			for _, org := range claims.Auth.Organizations {
+				if org.IsDefault || st.Organization == "" {
+					st.Organization = firstNonEmpty(org.Title, org.ID)
+				}
+			}
+		}
+		var access struct {
+			Exp int64 `json:"exp"`
+		}
+		if decodeJWTClaims(auth.Tokens.AccessToken, &access) == nil && access.Exp > 0 {
+			exp := time.Unix(access.Exp, 0).UTC()
+			st.ExpiresAt = &exp
+		} else if claims.Exp > 0 {
+			exp := time.Unix(claims.Exp, 0).UTC()
+			st.ExpiresAt = &exp
+		}
+		return st, true, nil
+	}
+	if auth.APIKey != nil && strings.TrimSpace(*auth.APIKey) != "" {
+		st.LoggedIn = true
+		st.AuthKind = AuthAPIKey
+		st.Method = "api_key"
+		return st, true, nil
+	}
+	return Status{}, false, nil
+}
+
+// decodeJWTClaims decodes the payload segment of a JWT without verifying it.
+// The result is only used for display.
+func decodeJWTClaims(token string, v any) error {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return errors.New("not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, v)
+}
+
+func firstNonEmpty(v ...string) string {
+	for _, s := range v {
+		if s = strings.TrimSpace(s); s != "" {
+			return s
+		}
+	}
+	return ""
+}
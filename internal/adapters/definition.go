@@ -0,0 +1,417 @@
+package adapters
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// DefinitionDir is where user adapter definitions live, relative to the
+// ProfileX root.
+const DefinitionDir = "adapters.d"
+
+// ProfileDirToken is replaced by the profile directory in env templates.
+const ProfileDirToken = "{profile}"
+
+const (
+	StatusParserJSON = "json"
+	StatusParserText = "text"
+)
+
+// Definition describes a CLI tool declaratively. The built-in claude and
+// codex adapters are definitions too; files in adapters.d may add tools or
+// override a built-in by reusing its name.
+type Definition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Binary      string `json:"binary"`
+	// ConfigEnv is the variable the tool reads its config directory from.
+	// It is set to the profile directory.
+	ConfigEnv string `json:"config_env,omitempty"`
+	// Env sets further variables from templates rooted at the profile
+	// directory, e.g. "XDG_DATA_HOME": "{profile}/data".
+	Env            map[string]string `json:"env,omitempty"`
+	APIKeyEnv      string            `json:"api_key_env,omitempty"`
+	ConflictingEnv []string          `json:"conflicting_env,omitempty"`
+	// NativeConfigEnv overrides NativeConfigDirs when set in the environment.
+	NativeConfigEnv string `json:"native_config_env,omitempty"`
+	// NativeConfigDirs lists candidate locations of the tool's unmanaged
+	// config. The first existing one wins, else the first is used.
+	NativeConfigDirs []string `json:"native_config_dir,omitempty"`
+	// SessionLeaf is the subdirectory holding session history, shared
+	// between profiles with --share-sessions.
+	SessionLeaf     string   `json:"session_leaf,omitempty"`
+	SettingsFiles   []string `json:"settings_files,omitempty"`
+	CredentialFiles []string `json:"credential_files,omitempty"`
+	// Credentials names a built-in offline credential reader.
+	Credentials string `json:"credentials,omitempty"`
+	// UsageFormat names the session log format understood by `profilex usage`.
+	UsageFormat string       `json:"usage_format,omitempty"`
+	Status      *StatusSpec  `json:"status,omitempty"`
+	Login       *CommandSpec `json:"login,omitempty"`
+	LoginAPIKey *CommandSpec `json:"login_api_key,omitempty"`
+	Logout      *CommandSpec `json:"logout,omitempty"`
+	// Version defaults to `<binary> --version`.
+	Version *CommandSpec `json:"version,omitempty"`
+
+	// Source is the file the definition was loaded from, or "builtin".
+	Source string `json:"-"`
+}
+
+type CommandSpec struct {
+	Args []string `json:"args"`
+	// Stdin passes the API key on stdin rather than the command line.
+	Stdin bool `json:"stdin,omitempty"`
+}
+
+// StatusSpec describes the CLI probe used when no credential reader applies.
+// The json parser reads fields by dotted path; the text parser matches
+// case-insensitive substrings, and a non-zero exit means logged out.
+type StatusSpec struct {
+	Args              []string `json:"args"`
+	Parser            string   `json:"parser"`
+	LoggedInField     string   `json:"logged_in_field,omitempty"`
+	MethodField       string   `json:"method_field,omitempty"`
+	EmailField        string   `json:"email_field,omitempty"`
+	LoggedOutPatterns []string `json:"logged_out_patterns,omitempty"`
+	APIKeyPatterns    []string `json:"api_key_patterns,omitempty"`
+	OAuthPatterns     []string `json:"oauth_patterns,omitempty"`
+}
+
+//go:embed builtin/*.json
+var builtinFS embed.FS
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[store.Tool]Definition{}
+)
+
+var (
+	toolNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+	envNamePattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// usageFormats are the session log formats `profilex usage` can parse.
+var usageFormats = map[string]bool{"claude": true, "codex": true}
+
+var credentialReaders = map[string]func(string) (Status, bool, error){
+	"claude": claudeCredentialStatus,
+	"codex":  codexCredentialStatus,
+}
+
+// credentialSources are the files each credentials reader takes secrets
+// from. Adapters using a reader must list them in credential_files, so
+// settings presets never copy them and the vault seals them.
+var credentialSources = map[string][]string{
+	"claude": {".credentials.json", ".claude.json"},
+	"codex":  {"auth.json"},
+}
+
+func init() {
+	entries, err := builtinFS.ReadDir("builtin")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		b, err := builtinFS.ReadFile("builtin/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		def, err := ParseDefinition(b)
+		if err != nil {
+			panic(fmt.Sprintf("builtin adapter %s: %v", e.Name(), err))
+		}
+		def.Source = "builtin"
+		if err := Register(def); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// ParseDefinition decodes and validates a JSON definition.
+func ParseDefinition(b []byte) (Definition, error) {
+	var def Definition
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&def); err != nil {
+		return Definition{}, err
+	}
+	def.Name = strings.ToLower(strings.TrimSpace(def.Name))
+	if err := def.validate(); err != nil {
+		return Definition{}, err
+	}
+	return def, nil
+}
+
+func (d Definition) validate() error {
+	if !toolNamePattern.MatchString(d.Name) {
+		return fmt.Errorf("invalid adapter name %q (lowercase letters, digits, _ and -)", d.Name)
+	}
+	if strings.TrimSpace(d.Binary) == "" {
+		return fmt.Errorf("adapter %s: binary is required", d.Name)
+	}
+	if d.ConfigEnv == "" && len(d.Env) == 0 {
+		return fmt.Errorf("adapter %s: config_env or env is required", d.Name)
+	}
+	if d.ConfigEnv != "" && !envNamePattern.MatchString(d.ConfigEnv) {
+		return fmt.Errorf("adapter %s: config_env must be an environment variable name", d.Name)
+	}
+	for name, tmpl := range d.Env {
+		if !envNamePattern.MatchString(name) || name == d.ConfigEnv {
+			return fmt.Errorf("adapter %s: invalid env variable %q", d.Name, name)
+		}
+		if _, ok := envSubdir(tmpl); !ok {
+			return fmt.Errorf("adapter %s: env %s must be %q or %q", d.Name, name, ProfileDirToken, ProfileDirToken+"/<subdir>")
+		}
+	}
+	for _, name := range d.ConflictingEnv {
+		if name == d.ConfigEnv || d.Env[name] != "" {
+			return fmt.Errorf("adapter %s: %s is both assigned and listed in conflicting_env", d.Name, name)
+		}
+	}
+	for _, name := range append([]string{d.APIKeyEnv, d.NativeConfigEnv}, d.ConflictingEnv...) {
+		if name != "" && !envNamePattern.MatchString(name) {
+			return fmt.Errorf("adapter %s: invalid environment variable name %q", d.Name, name)
+		}
+	}
+	if d.SessionLeaf != "" && !isRelativeLeaf(d.SessionLeaf) {
+		return fmt.Errorf("adapter %s: session_leaf must be a relative path", d.Name)
+	}
+	for _, f := range append(append([]string{}, d.SettingsFiles...), d.CredentialFiles...) {
+		if !isRelativeLeaf(f) {
+			return fmt.Errorf("adapter %s: %q must be a relative path inside the profile", d.Name, f)
+		}
+	}
+	for _, f := range d.SettingsFiles {
+		settings := filepath.Clean(filepath.FromSlash(f))
+		for _, c := range d.CredentialFiles {
+			cred := filepath.Clean(filepath.FromSlash(c))
+			if settings == "." || settings == cred || strings.HasPrefix(cred, settings+string(filepath.Separator)) {
+				return fmt.Errorf("adapter %s: settings file %q would capture credential file %q", d.Name, f, c)
+			}
+		}
+	}
+	if d.Credentials != "" {
+		if _, ok := credentialReaders[d.Credentials]; !ok {
+			return fmt.Errorf("adapter %s: unknown credentials reader %q", d.Name, d.Credentials)
+		}
+		for _, src := range credentialSources[d.Credentials] {
+			if !slices.Contains(d.CredentialFiles, src) {
+				return fmt.Errorf("adapter %s: credentials reader %q reads %s, which must be listed in credential_files", d.Name, d.Credentials, src)
+			}
+		}
+	}
+	if d.UsageFormat != "" && !usageFormats[d.UsageFormat] {
+		return fmt.Errorf("adapter %s: unknown usage_format %q", d.Name, d.UsageFormat)
+	}
+	if d.Status != nil {
+		switch d.Status.Parser {
+		case StatusParserJSON:
+			if d.Status.LoggedInField == "" {
+				return fmt.Errorf("adapter %s: json status parser needs logged_in_field", d.Name)
+			}
+		case StatusParserText:
+		default:
+			return fmt.Errorf("adapter %s: status parser must be %q or %q", d.Name, StatusParserJSON, StatusParserText)
+		}
+		if len(d.Status.Args) == 0 {
+			return fmt.Errorf("adapter %s: status args are required", d.Name)
+		}
+	}
+	for _, c := range []struct {
+		name string
+		spec *CommandSpec
+	}{{"login", d.Login}, {"login_api_key", d.LoginAPIKey}, {"logout", d.Logout}, {"version", d.Version}} {
+		if c.spec != nil && len(c.spec.Args) == 0 {
+			return fmt.Errorf("adapter %s: %s args are required", d.Name, c.name)
+		}
+	}
+	if d.LoginAPIKey != nil && !d.LoginAPIKey.Stdin {
+		return fmt.Errorf("adapter %s: login_api_key must read the key from stdin", d.Name)
+	}
+	return nil
+}
+
+func isRelativeLeaf(p string) bool {
+	p = strings.TrimSpace(p)
+	if p == "" || filepath.IsAbs(p) {
+		return false
+	}
+	clean := filepath.Clean(filepath.FromSlash(p))
+	return clean != ".." && !strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
+
+// envSubdir returns the part of an env template after {profile}/, which may
+// be empty.
+func envSubdir(tmpl string) (string, bool) {
+	tmpl = strings.TrimSpace(tmpl)
+	if tmpl == ProfileDirToken {
+		return "", true
+	}
+	rest, ok := strings.CutPrefix(tmpl, ProfileDirToken+"/")
+	if !ok || !isRelativeLeaf(rest) {
+		return "", false
+	}
+	return filepath.Clean(filepath.FromSlash(rest)), true
+}
+
+// EnvAssignments computes the variables that point the tool at profileDir,
+// config_env first and the rest sorted by name.
+func (d Definition) EnvAssignments(profileDir string) []EnvAssignment {
+	out := []EnvAssignment{}
+	if d.ConfigEnv != "" {
+		out = append(out, EnvAssignment{Name: d.ConfigEnv, Value: profileDir})
+	}
+	names := make([]string, 0, len(d.Env))
+	for name := range d.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sub, _ := envSubdir(d.Env[name])
+		out = append(out, EnvAssignment{Name: name, Value: filepath.Join(profileDir, sub)})
+	}
+	return out
+}
+
+// SessionEnvLeaves maps each assigned variable to the session leaf relative
+// to the directory it points at. Variables whose directory does not contain
+// the session leaf are left out.
+func (d Definition) SessionEnvLeaves() []EnvAssignment {
+	if d.SessionLeaf == "" {
+		return nil
+	}
+	leaf := filepath.Clean(filepath.FromSlash(d.SessionLeaf))
+	out := []EnvAssignment{}
+	for _, a := range d.EnvAssignments("") {
+		rel := leaf
+		if a.Value != "" {
+			r, err := filepath.Rel(a.Value, leaf)
+			if err != nil || r == "." || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+				continue
+			}
+			rel = r
+		}
+		out = append(out, EnvAssignment{Name: a.Name, Value: filepath.ToSlash(rel)})
+	}
+	return out
+}
+
+// Register adds or replaces a definition and makes its tool name valid
+// throughout ProfileX.
+func Register(def Definition) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	tool := store.Tool(def.Name)
+	registry[tool] = def
+	store.RegisterTool(tool)
+	return nil
+}
+
+// LoadDir registers every *.json definition in dir. A missing directory is
+// not an error. Invalid files are reported together after the valid ones
+// have been registered.
+func LoadDir(dir string) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+	var errs []error
+	for _, path := range matches {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		def, err := ParseDefinition(b)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		def.Source = path
+		if err := Register(def); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Lookup returns the definition registered for tool.
+func Lookup(tool store.Tool) (Definition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	def, ok := registry[tool]
+	return def, ok
+}
+
+// Definitions lists registered definitions sorted by name.
+func Definitions() []Definition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	out := make([]Definition, 0, len(registry))
+	for _, def := range registry {
+		out = append(out, def)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Summary is the one-line description shown when choosing a tool.
+func (d Definition) Summary() string {
+	if d.Description != "" {
+		return d.Description
+	}
+	return d.Binary + " CLI"
+}
+
+// NativeConfigDir resolves the tool's unmanaged config directory.
+func (d Definition) NativeConfigDir() (string, error) {
+	if d.NativeConfigEnv != "" {
+		if custom := strings.TrimSpace(os.Getenv(d.NativeConfigEnv)); custom != "" {
+			return filepath.Clean(custom), nil
+		}
+	}
+	if len(d.NativeConfigDirs) == 0 {
+		return "", fmt.Errorf("adapter %s has no native_config_dir", d.Name)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	candidates := make([]string, 0, len(d.NativeConfigDirs))
+	for _, c := range d.NativeConfigDirs {
+		candidates = append(candidates, ExpandHome(c, home))
+	}
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && info.IsDir() {
+			return c, nil
+		}
+	}
+	return candidates[0], nil
+}
+
+// ExpandHome replaces a leading ~ with home.
+func ExpandHome(p, home string) string {
+	p = strings.TrimSpace(p)
+	if p == "~" {
+		return home
+	}
+	if strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		return filepath.Join(home, filepath.FromSlash(p[2:]))
+	}
+	return filepath.Clean(filepath.FromSlash(p))
+}
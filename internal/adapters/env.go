@@ -0,0 +1,46 @@
+package adapters
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// ScrubEnv removes the named variables from env. Matching is
+// case-insensitive on Windows, where variable names are.
+func ScrubEnv(env []string, names []string) (kept []string, removed []string) {
+	kept = make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if envNameIn(name, names) {
+			removed = append(removed, name)
+			continue
+		}
+		kept = append(kept, kv)
+	}
+	return kept, removed
+}
+
+// profileEnviron returns os.Environ() without variables that would point the
+// tool at another account, plus the profile's own assignments.
+func profileEnviron(a Adapter, profileDir string) []string {
+	assigned := a.Env(profileDir)
+	names := append([]string{}, a.ConflictingEnvVars()...)
+	for _, e := range assigned {
+		names = append(names, e.Name)
+	}
+	env, _ := ScrubEnv(os.Environ(), names)
+	for _, e := range assigned {
+		env = append(env, e.String())
+	}
+	return env
+}
+
+func envNameIn(name string, names []string) bool {
+	for _, n := range names {
+		if n == name || (runtime.GOOS == "windows" && strings.EqualFold(n, name)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// AddAlias records an extra shim name for a profile. Clashes with other
+// shim names and executables on PATH are the caller's to check.
+func (m *Manager) AddAlias(name string, tool store.Tool, profile string) (store.Alias, error) {
+	var out store.Alias
+	if err := store.ValidateAliasName(name); err != nil {
+		return out, err
+	}
+	err := m.store.Update(func(st *store.State) error {
+		if _, p := store.FindProfile(st, tool, profile); p == nil {
+			return fmt.Errorf("profile not found: %s/%s", tool, profile)
+		}
+		if _, a := store.FindAlias(st, name); a != nil {
+			return fmt.Errorf("alias %s already points at %s/%s", name, a.Tool, a.Profile)
+		}
+		out = store.Alias{Name: name, Tool: tool, Profile: profile, CreatedAt: time.Now().UTC()}
+		st.Aliases = append(st.Aliases, out)
+		return nil
+	})
+	return out, err
+}
+
+// RemoveAlias deletes an alias and returns what it pointed at.
+func (m *Manager) RemoveAlias(name string) (store.Alias, error) {
+	var out store.Alias
+	err := m.store.Update(func(st *store.State) error {
+		idx, a := store.FindAlias(st, name)
+		if a == nil {
+			return fmt.Errorf("alias not found: %s", name)
+		}
+		out = *a
+		st.Aliases = append(st.Aliases[:idx], st.Aliases[idx+1:]...)
+		return nil
+	})
+	return out, err
+}
+
+// SetShimNameTemplate sets the extra shim name every profile of tool gets.
+// An empty template clears it.
+func (m *Manager) SetShimNameTemplate(tool store.Tool, tmpl string) error {
+	tmpl = strings.TrimSpace(tmpl)
+	if tmpl != "" {
+		if err := store.ValidateShimNameTemplate(tmpl); err != nil {
+			return err
+		}
+	}
+	return m.store.Update(func(st *store.State) error {
+		if tmpl == "" {
+			delete(st.ShimNameTemplates, tool)
+			return nil
+		}
+		if st.ShimNameTemplates == nil {
+			st.ShimNameTemplates = map[store.Tool]string{}
+		}
+		st.ShimNameTemplates[tool] = tmpl
+		return nil
+	})
+}
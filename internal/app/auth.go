@@ -0,0 +1,94 @@
+package app
+
+import (
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// DefaultAuthWarnWindow is how far ahead of token expiry ProfileX starts
+// warning when no window is configured.
+const DefaultAuthWarnWindow = 24 * time.Hour
+
+type AuthHealth string
+
+const (
+	AuthHealthOK        AuthHealth = "ok"
+	AuthHealthExpiring  AuthHealth = "expiring"
+	AuthHealthExpired   AuthHealth = "expired"
+	AuthHealthLoggedOut AuthHealth = "logged_out"
+	AuthHealthUnknown   AuthHealth = "unknown"
+)
+
+// NeedsAttention reports whether the health should be surfaced as a warning.
+func (h AuthHealth) NeedsAttention() bool {
+	return h == AuthHealthExpiring || h == AuthHealthExpired
+}
+
+type AuthCheck struct {
+	Profile   store.Profile   `json:"profile"`
+	Health    AuthHealth      `json:"health"`
+	Status    adapters.Status `json:"status"`
+	ExpiresIn string          `json:"expires_in,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// EvaluateAuth classifies a status against the warning window. Statuses
+// without a known expiry (API keys, CLI probes) are never flagged, and
+// neither are ones with a refresh token: the expiry is then the short-lived
+// access token's, which the tool renews itself.
+func EvaluateAuth(st adapters.Status, now time.Time, window time.Duration) AuthHealth {
+	if !st.LoggedIn {
+		if st.Source == "" {
+			return AuthHealthUnknown
+		}
+		return AuthHealthLoggedOut
+	}
+	if st.ExpiresAt == nil || st.Refreshable {
+		return AuthHealthOK
+	}
+	if !st.ExpiresAt.After(now) {
+		return AuthHealthExpired
+	}
+	if st.ExpiresAt.Sub(now) <= window {
+		return AuthHealthExpiring
+	}
+	return AuthHealthOK
+}
+
+// CheckAuth evaluates every profile's credentials offline, without probing
+// the tool CLIs.
+func (m *Manager) CheckAuth(filterTool *store.Tool, window time.Duration) ([]AuthCheck, error) {
+	st, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := []AuthCheck{}
+	for _, p := range st.Profiles {
+		if filterTool != nil && p.Tool != *filterTool {
+			continue
+		}
+		dir, err := m.validatedManagedProfileDir(p)
+		if err != nil {
+			out = append(out, AuthCheck{Profile: p, Health: AuthHealthUnknown, Error: err.Error()})
+			continue
+		}
+		p.Dir = dir
+		check := AuthCheck{Profile: p, Health: AuthHealthUnknown}
+		status, ok, err := m.OfflineStatusForProfile(p)
+		switch {
+		case err != nil:
+			check.Error = err.Error()
+		case ok:
+			check.Status = status
+			check.Health = EvaluateAuth(status, now, window)
+			if status.ExpiresAt != nil && !status.Refreshable {
+				check.ExpiresIn = status.ExpiresAt.Sub(now).Round(time.Second).String()
+			}
+		}
+		out = append(out, check)
+	}
+	return out, nil
+}
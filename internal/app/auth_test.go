@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/secrets"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestEvaluateAuth(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+	cases := []struct {
+		name string
+		st   adapters.Status
+		want AuthHealth
+	}{
+		{"unknown", adapters.Status{}, AuthHealthUnknown},
+		{"logged out", adapters.Status{Source: adapters.SourceCLI}, AuthHealthLoggedOut},
+		{"api key", adapters.Status{LoggedIn: true, AuthKind: adapters.AuthAPIKey}, AuthHealthOK},
+		{"fresh", adapters.Status{LoggedIn: true, ExpiresAt: at(72 * time.Hour)}, AuthHealthOK},
+		{"expiring", adapters.Status{LoggedIn: true, ExpiresAt: at(2 * time.Hour)}, AuthHealthExpiring},
+		{"expired", adapters.Status{LoggedIn: true, ExpiresAt: at(-time.Minute)}, AuthHealthExpired},
+		{"expired access, refreshable", adapters.Status{LoggedIn: true, ExpiresAt: at(-time.Minute), Refreshable: true}, AuthHealthOK},
+		{"expiring access, refreshable", adapters.Status{LoggedIn: true, ExpiresAt: at(time.Hour), Refreshable: true}, AuthHealthOK},
+	}
+	for _, tc := range cases {
+		if got := EvaluateAuth(tc.st, now, 24*time.Hour); got != tc.want {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestCheckAuthFlagsExpiredProfiles(t *testing.T) {
+	m := newTestManager(t)
+	expired, _, err := m.EnsureProfile(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, _, err := m.EnsureProfile(store.ToolClaude, "personal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshing, _, err := m.EnsureProfile(store.ToolClaude, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.EnsureProfile(store.ToolCodex, "empty"); err != nil {
+		t.Fatal(err)
+	}
+	writeClaudeCreds := func(dir, refreshToken string, expiresAt time.Time) {
+		body := fmt.Sprintf(`{"claudeAiOauth":{"accessToken":"a","refreshToken":%q,"expiresAt":%d}}`, refreshToken, expiresAt.UnixMilli())
+		if err := os.WriteFile(filepath.Join(dir, ".credentials.json"), []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeClaudeCreds(expired.Dir, "", time.Now().Add(-time.Hour))
+	writeClaudeCreds(fresh.Dir, "", time.Now().Add(30*24*time.Hour))
+	// An expired access token is routine while a refresh token is present.
+	writeClaudeCreds(refreshing.Dir, "r", time.Now().Add(-time.Hour))
+
+	checks, err := m.CheckAuth(nil, DefaultAuthWarnWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]AuthHealth{}
+	for _, c := range checks {
+		got[string(c.Profile.Tool)+"/"+c.Profile.Name] = c.Health
+	}
+	want := map[string]AuthHealth{
+		"claude/work":     AuthHealthExpired,
+		"claude/personal": AuthHealthOK,
+		"claude/team":     AuthHealthOK,
+		"codex/empty":     AuthHealthUnknown,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s: expected %s, got %s", k, v, got[k])
+		}
+	}
+
+	tool := store.ToolCodex
+	checks, err = m.CheckAuth(&tool, DefaultAuthWarnWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 1 {
+		t.Fatalf("expected tool filter to keep 1 profile, got %d", len(checks))
+	}
+}
+
+func TestLoginRunsToolUnderProfileEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script stand-in for codex")
+	}
+	m := newTestManager(t)
+	p, _, err := m.EnsureProfile(store.ToolCodex, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bin := t.TempDir()
+	script := `#!/bin/sh
+case "$1 $2" in
+  "login status") echo "Not logged in"; exit 1 ;;
+  "login --with-api-key") key=$(cat); printf '{"OPENAI_API_KEY":"%s"}' "$key" > "$CODEX_HOME/auth.json" ;;
+  "logout ") rm -f "$CODEX_HOME/auth.json" ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(bin, "codex"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	st, err := m.Login(context.Background(), p, adapters.LoginOptions{APIKey: "sk-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.LoggedIn || st.AuthKind != adapters.AuthAPIKey {
+		t.Fatalf("expected refreshed API key status, got %+v", st)
+	}
+
+	st, err = m.Logout(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.LoggedIn {
+		t.Fatalf("expected logged out status, got %+v", st)
+	}
+	if _, err := os.Stat(filepath.Join(p.Dir, "auth.json")); !os.IsNotExist(err) {
+		t.Fatalf("logout should run under the profile's CODEX_HOME: %v", err)
+	}
+}
+
+func TestAPIKeyProfileInjectsKeyOnlyIntoLaunchEnv(t *testing.T) {
+	m := newTestManager(t)
+	p, _, err := m.EnsureProfile(store.ToolCodex, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(secrets.EnvKeyFile, "")
+	t.Setenv(secrets.EnvPassphrase, "")
+	if err := secrets.GenerateKeyFile(m.Secrets().DefaultKeyFile()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetAPIKey(store.ToolCodex, "ci", "sk-openai-test\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := m.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, stored := store.FindProfile(st, store.ToolCodex, "ci")
+	if stored == nil || stored.Auth != store.AuthModeAPIKey {
+		t.Fatalf("expected profile to switch to API key auth, got %+v", stored)
+	}
+	raw, err := os.ReadFile(filepath.Join(m.Root(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "sk-openai-test") {
+		t.Fatalf("API key leaked into state.json")
+	}
+
+	env, err := m.LaunchEnv(*stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env) != 1 || env[0] != "OPENAI_API_KEY=sk-openai-test" {
+		t.Fatalf("unexpected launch env: %v", env)
+	}
+	status, ok, err := m.OfflineStatusForProfile(*stored)
+	if err != nil || !ok || !status.LoggedIn || status.AuthKind != adapters.AuthAPIKey {
+		t.Fatalf("unexpected API key status: %+v ok=%v err=%v", status, ok, err)
+	}
+
+	if err := m.ClearAPIKey(store.ToolCodex, "ci"); err != nil {
+		t.Fatal(err)
+	}
+	if env, err := m.LaunchEnv(p); err != nil || len(env) != 0 {
+		t.Fatalf("cleared profile should not inject env: %v %v", env, err)
+	}
+}
+
+func TestRemoveProfileDropsAPIKeyWithoutPurge(t *testing.T) {
+	m := newTestManager(t)
+	if _, _, err := m.EnsureProfile(store.ToolCodex, "ci"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(secrets.EnvKeyFile, "")
+	t.Setenv(secrets.EnvPassphrase, "")
+	if err := secrets.GenerateKeyFile(m.Secrets().DefaultKeyFile()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetAPIKey(store.ToolCodex, "ci", "sk-openai-test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RemoveProfile(store.ToolCodex, "ci", false); err != nil {
+		t.Fatal(err)
+	}
+	if m.Secrets().Has(store.ToolCodex, "ci") {
+		t.Fatalf("expected remove to delete the profile's API key")
+	}
+
+	p, _, err := m.EnsureProfile(store.ToolCodex, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env, err := m.LaunchEnv(p); err != nil || len(env) != 0 {
+		t.Fatalf("a re-added profile must not pick up the old key: %v %v", env, err)
+	}
+}
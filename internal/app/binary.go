@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// BinaryInfo describes the tool executable a profile launches.
+type BinaryInfo struct {
+	Profile store.Profile `json:"profile"`
+	Binary  string        `json:"binary"`
+	// Path is the resolved executable; empty when it is missing.
+	Path string `json:"path,omitempty"`
+	// Pinned is "profile" or "tool" when the binary is pinned.
+	Pinned  string `json:"pinned,omitempty"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Missing reports whether the profile's binary could not be found.
+func (b BinaryInfo) Missing() bool {
+	return b.Path == ""
+}
+
+// adapterFor returns the profile's adapter with any pinned binary applied.
+func (m *Manager) adapterFor(profile store.Profile) (adapters.Adapter, error) {
+	adapter, err := adapters.Get(profile.Tool)
+	if err != nil {
+		return nil, err
+	}
+	bin := profile.Binary
+	if bin == "" {
+		st, err := m.Load()
+		if err != nil {
+			return nil, err
+		}
+		bin, _ = st.BinaryFor(profile)
+	}
+	return adapter.WithBinary(bin), nil
+}
+
+// ResolveBinary returns the executable the profile launches: its pin, the
+// tool-wide pin, or the adapter default looked up on PATH.
+func (m *Manager) ResolveBinary(profile store.Profile) (string, error) {
+	adapter, err := m.adapterFor(profile)
+	if err != nil {
+		return "", err
+	}
+	return adapter.Binary(), nil
+}
+
+// PinBinary pins path for one profile, or for every profile of the tool when
+// name is empty. An empty path clears the pin. The path is resolved to an
+// absolute executable so the pin survives PATH changes.
+func (m *Manager) PinBinary(tool store.Tool, name, path string) (string, error) {
+	resolved := ""
+	if path = strings.TrimSpace(path); path != "" {
+		found, err := exec.LookPath(path)
+		if err != nil {
+			return "", fmt.Errorf("binary %s not found or not executable", path)
+		}
+		if resolved, err = filepath.Abs(found); err != nil {
+			return "", err
+		}
+	}
+	err := m.store.Update(func(st *store.State) error {
+		if name == "" {
+			if st.ToolBinaries == nil {
+				st.ToolBinaries = map[store.Tool]string{}
+			}
+			if resolved == "" {
+				delete(st.ToolBinaries, tool)
+			} else {
+				st.ToolBinaries[tool] = resolved
+			}
+			return nil
+		}
+		idx, p := store.FindProfile(st, tool, name)
+		if p == nil {
+			return fmt.Errorf("profile not found: %s/%s", tool, name)
+		}
+		st.Profiles[idx].Binary = resolved
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// BinaryReport resolves and version-checks the binary of every profile.
+// Each distinct executable is run once.
+func (m *Manager) BinaryReport(ctx context.Context, filterTool *store.Tool) ([]BinaryInfo, error) {
+	st, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	prober := newBinaryProber()
+	out := []BinaryInfo{}
+	for _, p := range st.Profiles {
+		if filterTool != nil && p.Tool != *filterTool {
+			continue
+		}
+		pin, scope := st.BinaryFor(p)
+		adapter, err := adapters.Get(p.Tool)
+		if err != nil {
+			out = append(out, BinaryInfo{Profile: p, Binary: pin, Pinned: scope, Error: err.Error()})
+			continue
+		}
+		adapter = adapter.WithBinary(pin)
+		info := BinaryInfo{Profile: p, Binary: adapter.Binary(), Pinned: scope}
+		probed := prober.probe(ctx, adapter)
+		info.Path, info.Version, info.Error = probed.Path, probed.Version, probed.Error
+		out = append(out, info)
+	}
+	return out, nil
+}
+
+// binaryProber runs each distinct executable's version command once, even
+// when several status workers ask for it at the same time.
+type binaryProber struct {
+	mu     sync.Mutex
+	probes map[string]*binaryProbe
+}
+
+type binaryProbe struct {
+	once sync.Once
+	info BinaryInfo
+}
+
+func newBinaryProber() *binaryProber {
+	return &binaryProber{probes: map[string]*binaryProbe{}}
+}
+
+func (b *binaryProber) probe(ctx context.Context, adapter adapters.Adapter) BinaryInfo {
+	b.mu.Lock()
+	p, ok := b.probes[adapter.Binary()]
+	if !ok {
+		p = &binaryProbe{}
+		b.probes[adapter.Binary()] = p
+	}
+	b.mu.Unlock()
+	p.once.Do(func() { p.info = probeBinary(ctx, adapter) })
+	return p.info
+}
+
+func probeBinary(ctx context.Context, adapter adapters.Adapter) BinaryInfo {
+	var info BinaryInfo
+	path, err := exec.LookPath(adapter.Binary())
+	if err != nil {
+		info.Error = binaryNotFound(adapter.Binary()).Error()
+		return info
+	}
+	info.Path = path
+	ctxOne, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if v, err := adapter.Version(ctxOne); err != nil {
+		info.Error = err.Error()
+	} else {
+		info.Version = v
+	}
+	return info
+}
+
+func ensureToolBinary(adapter adapters.Adapter) error {
+	if _, err := exec.LookPath(adapter.Binary()); err != nil {
+		return binaryNotFound(adapter.Binary())
+	}
+	return nil
+}
+
+func binaryNotFound(bin string) error {
+	if strings.ContainsAny(bin, `/\`) {
+		return fmt.Errorf("pinned binary %s not found or not executable", bin)
+	}
+	return fmt.Errorf("%s not found in PATH", bin)
+}
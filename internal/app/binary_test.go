@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func writeFakeVersionBinary(t *testing.T, dir, name, version string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	script := "#!/bin/sh\nif [ \"$1\" = \"--version\" ]; then echo '" + version + " (Claude Code)'; exit 0; fi\necho ran\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPinBinaryOverridesToolAndProfile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the tool binary")
+	}
+	bin := t.TempDir()
+	writeFakeVersionBinary(t, bin, "claude", "2.1.0")
+	old := writeFakeVersionBinary(t, bin, "claude-old", "1.0.44")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	m := newTestManager(t)
+	for _, name := range []string{"work", "legacy"} {
+		if _, _, err := m.EnsureProfile(store.ToolClaude, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.PinBinary(store.ToolClaude, "legacy", old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.PinBinary(store.ToolClaude, "work", filepath.Join(bin, "missing")); err == nil {
+		t.Fatalf("pinning a missing binary should fail")
+	}
+
+	report, err := m.BinaryReport(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]BinaryInfo{}
+	for _, b := range report {
+		got[b.Profile.Name] = b
+	}
+	if got["legacy"].Version != "1.0.44" || got["legacy"].Pinned != "profile" {
+		t.Fatalf("unexpected legacy binary: %+v", got["legacy"])
+	}
+	if got["work"].Version != "2.1.0" || got["work"].Pinned != "" {
+		t.Fatalf("unexpected work binary: %+v", got["work"])
+	}
+
+	if _, err := m.PinBinary(store.ToolClaude, "", old); err != nil {
+		t.Fatal(err)
+	}
+	st, err := m.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, err := m.GetProfile(st, store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved, err := m.ResolveBinary(work); err != nil || resolved != old {
+		t.Fatalf("tool-wide pin should apply to unpinned profiles, got %q %v", resolved, err)
+	}
+
+	if err := os.Remove(old); err != nil {
+		t.Fatal(err)
+	}
+	report, err = m.BinaryReport(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range report {
+		if !b.Missing() {
+			t.Fatalf("expected pinned binary to be reported missing: %+v", b)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// EnvPolicyVar overrides the global isolation policy (strip or warn).
+const EnvPolicyVar = "PROFILEX_ENV_POLICY"
+
+type EnvAction string
+
+const (
+	EnvStripped EnvAction = "stripped"
+	EnvWarned   EnvAction = "warned"
+	EnvAllowed  EnvAction = "allowed"
+)
+
+// EnvConflict is an inherited variable that could make a profile's tool use
+// another account, and what the isolation policy does with it.
+type EnvConflict struct {
+	Name   string    `json:"name"`
+	Action EnvAction `json:"action"`
+}
+
+// ParseEnvPolicy accepts "strip" and "warn"; empty means the default.
+func ParseEnvPolicy(raw string) (store.EnvPolicy, error) {
+	switch p := store.EnvPolicy(strings.ToLower(strings.TrimSpace(raw))); p {
+	case store.EnvPolicyDefault, store.EnvPolicyStrip, store.EnvPolicyWarn:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid env policy %q (expected strip|warn)", raw)
+	}
+}
+
+// EffectiveEnvPolicy resolves the profile's policy, then PROFILEX_ENV_POLICY,
+// then strip.
+func EffectiveEnvPolicy(profile store.Profile) store.EnvPolicy {
+	if profile.EnvPolicy != store.EnvPolicyDefault {
+		return profile.EnvPolicy
+	}
+	if p, err := ParseEnvPolicy(os.Getenv(EnvPolicyVar)); err == nil && p != store.EnvPolicyDefault {
+		return p
+	}
+	return store.EnvPolicyStrip
+}
+
+// EnvConflicts lists conflicting variables present in the current
+// environment for the profile's tool.
+func (m *Manager) EnvConflicts(profile store.Profile) ([]EnvConflict, error) {
+	adapter, err := adapters.Get(profile.Tool)
+	if err != nil {
+		return nil, err
+	}
+	policy := EffectiveEnvPolicy(profile)
+	out := []EnvConflict{}
+	for _, name := range adapter.ConflictingEnvVars() {
+		if _, ok := os.LookupEnv(name); !ok {
+			continue
+		}
+		action := EnvStripped
+		switch {
+		case containsEnvName(profile.EnvAllow, name):
+			action = EnvAllowed
+		case policy == store.EnvPolicyWarn:
+			action = EnvWarned
+		}
+		out = append(out, EnvConflict{Name: name, Action: action})
+	}
+	return out, nil
+}
+
+// StrippedEnvVars lists every conflicting variable the profile strips,
+// whether or not it is set now. ok is false when that depends on the
+// environment: the profile only warns, or PROFILEX_ENV_POLICY overrides it.
+func StrippedEnvVars(profile store.Profile) (names []string, ok bool) {
+	if profile.EnvPolicy == store.EnvPolicyWarn || os.Getenv(EnvPolicyVar) != "" {
+		return nil, false
+	}
+	adapter, err := adapters.Get(profile.Tool)
+	if err != nil {
+		return nil, false
+	}
+	names = []string{}
+	for _, name := range adapter.ConflictingEnvVars() {
+		if !containsEnvName(profile.EnvAllow, name) {
+			names = append(names, name)
+		}
+	}
+	return names, true
+}
+
+// isolateCommandEnv re-adds inherited conflicting variables that the
+// profile's policy keeps. Adapters strip all of them by default.
+func (m *Manager) isolateCommandEnv(profile store.Profile, env []string) ([]string, error) {
+	conflicts, err := m.EnvConflicts(profile)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conflicts {
+		if c.Action == EnvStripped {
+			continue
+		}
+		env = append(env, c.Name+"="+os.Getenv(c.Name))
+	}
+	return env, nil
+}
+
+// SetEnvIsolation updates a profile's policy and allow-list. allow and
+// disallow are applied in that order; policy is left alone when nil.
+func (m *Manager) SetEnvIsolation(tool store.Tool, name string, policy *store.EnvPolicy, allow, disallow []string) (store.Profile, error) {
+	var out store.Profile
+	err := m.store.Update(func(st *store.State) error {
+		idx, p := store.FindProfile(st, tool, name)
+		if p == nil {
+			return fmt.Errorf("profile not found: %s/%s", tool, name)
+		}
+		if policy != nil {
+			st.Profiles[idx].EnvPolicy = *policy
+		}
+		list := append([]string{}, p.EnvAllow...)
+		for _, v := range allow {
+			if v = strings.TrimSpace(v); v != "" && !containsEnvName(list, v) {
+				list = append(list, v)
+			}
+		}
+		kept := list[:0]
+		for _, v := range list {
+			if !containsEnvName(disallow, v) {
+				kept = append(kept, v)
+			}
+		}
+		sort.Strings(kept)
+		if len(kept) == 0 {
+			kept = nil
+		}
+		st.Profiles[idx].EnvAllow = kept
+		out = st.Profiles[idx]
+		return nil
+	})
+	return out, err
+}
+
+func containsEnvName(list []string, name string) bool {
+	for _, v := range list {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/secrets"
 	"github.com/derekurban/profilex-cli/internal/store"
 )
 
@@ -35,14 +36,13 @@ func (e *SharedSkillsMergeRequiredError) Error() string {
 	return fmt.Sprintf("%s already contains skills; merge into shared skills at %q to enable sharing", e.LocalDir, e.SharedDir)
 }
 
-type StatusRow struct {
-	Profile store.Profile   `json:"profile"`
-	Status  adapters.Status `json:"status"`
-	Error   string          `json:"error,omitempty"`
-}
-
 type Manager struct {
-	store *store.Store
+	store   *store.Store
+	secrets *secrets.Store
+	prompt  secrets.PromptFunc
+	// adapterErr records adapters.d files that failed to load. Valid
+	// definitions are still registered.
+	adapterErr error
 }
 
 func NewManager(root string) (*Manager, error) {
@@ -50,7 +50,8 @@ func NewManager(root string) (*Manager, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Manager{store: s}, nil
+	adapterErr := adapters.LoadDir(filepath.Join(root, adapters.DefinitionDir))
+	return &Manager{store: s, secrets: secrets.New(root), adapterErr: adapterErr}, nil
 }
 
 func NewDefaultManager() (*Manager, error) {
@@ -61,6 +62,11 @@ func NewDefaultManager() (*Manager, error) {
 	return NewManager(root)
 }
 
+// AdapterLoadError reports adapter definitions that could not be loaded.
+func (m *Manager) AdapterLoadError() error {
+	return m.adapterErr
+}
+
 func (m *Manager) Root() string {
 	return m.store.Root()
 }
@@ -100,6 +106,9 @@ func (m *Manager) EnsureProfile(tool store.Tool, name string) (store.Profile, bo
 		if err := os.MkdirAll(dir, 0o755); err != nil {
 			return err
 		}
+		if err := createEnvDirs(tool, dir); err != nil {
+			return err
+		}
 
 		outProfile = store.Profile{
 			Tool:      tool,
@@ -123,8 +132,8 @@ func (m *Manager) EnsureProfile(tool store.Tool, name string) (store.Profile, bo
 // EnableSharedSessions wires the profile's session/history subdirectory to a
 // shared directory under <root>/shared/<tool>/<leaf>.
 //
-// Claude uses "projects" as its session/history folder and Codex uses
-// "sessions".
+// The leaf comes from the tool's adapter definition: "projects" for Claude,
+// "sessions" for Codex.
 func (m *Manager) EnableSharedSessions(profile store.Profile) (string, error) {
 	profileDir, err := m.validatedManagedProfileDir(profile)
 	if err != nil {
@@ -464,10 +473,19 @@ func (m *Manager) SetDefault(tool store.Tool, name string) error {
 	})
 }
 
+// SetCompiledShims turns compiled shim environments on or off.
+func (m *Manager) SetCompiledShims(enabled bool) error {
+	return m.store.Update(func(st *store.State) error {
+		st.CompiledShims = enabled
+		return nil
+	})
+}
+
 func (m *Manager) RenameProfile(tool store.Tool, oldName, newName string) error {
 	if err := store.ValidateProfileName(newName); err != nil {
 		return err
 	}
+	defer m.invalidateStatus(store.Profile{Tool: tool, Name: oldName})
 	return m.store.Update(func(st *store.State) error {
 		idx, p := store.FindProfile(st, tool, oldName)
 		if p == nil {
@@ -491,6 +509,12 @@ func (m *Manager) RenameProfile(tool store.Tool, oldName, newName string) error
 		if err := os.Rename(oldDir, newDir); err != nil {
 			return err
 		}
+		if err := m.secrets.Rename(tool, oldName, newName); err != nil {
+			return err
+		}
+		if err := m.secrets.Vault().Rename(tool, oldName, newName); err != nil {
+			return err
+		}
 
 		st.Profiles[idx].Name = newName
 		st.Profiles[idx].Dir = newDir
@@ -501,11 +525,17 @@ func (m *Manager) RenameProfile(tool store.Tool, oldName, newName string) error
 			st.SettingsSync[syncIdx].Profile = newName
 			st.SettingsSync[syncIdx].UpdatedAt = time.Now().UTC()
 		}
+		for i := range st.Aliases {
+			if st.Aliases[i].Tool == tool && st.Aliases[i].Profile == oldName {
+				st.Aliases[i].Profile = newName
+			}
+		}
 		return nil
 	})
 }
 
 func (m *Manager) RemoveProfile(tool store.Tool, name string, purge bool) error {
+	defer m.invalidateStatus(store.Profile{Tool: tool, Name: name})
 	return m.store.Update(func(st *store.State) error {
 		idx, p := store.FindProfile(st, tool, name)
 		if p == nil {
@@ -515,10 +545,27 @@ func (m *Manager) RemoveProfile(tool store.Tool, name string, purge bool) error
 		if err != nil {
 			return err
 		}
+		// Sealed credentials are the only copy of the login; dropping them
+		// would leave the kept directory logged out, and keeping them would
+		// hand them to a later profile of the same name.
+		if !purge && m.secrets.Vault().Has(tool, name) {
+			return fmt.Errorf("%s/%s has sealed credentials; run `profilex vault disable %s %s` first to keep them, or remove with --purge", tool, name, tool, name)
+		}
 		if purge {
 			if err := os.RemoveAll(dir); err != nil {
 				return err
 			}
+			if err := m.secrets.Vault().Delete(tool, name); err != nil {
+				return err
+			}
+			if err := removeIfExists(m.leasePath(*p)); err != nil {
+				return err
+			}
+		}
+		// The API key is stored under the profile's name, not in its
+		// directory; a later profile of the same name must not inherit it.
+		if err := m.secrets.Delete(tool, name); err != nil {
+			return err
 		}
 
 		st.Profiles = append(st.Profiles[:idx], st.Profiles[idx+1:]...)
@@ -534,54 +581,121 @@ func (m *Manager) RemoveProfile(tool store.Tool, name string, purge bool) error
 		if syncIdx, sync := store.FindSettingsSync(st, tool, name); sync != nil {
 			st.SettingsSync = append(st.SettingsSync[:syncIdx], st.SettingsSync[syncIdx+1:]...)
 		}
+		aliases := st.Aliases[:0]
+		for _, a := range st.Aliases {
+			if a.Tool != tool || a.Profile != name {
+				aliases = append(aliases, a)
+			}
+		}
+		st.Aliases = aliases
 		return nil
 	})
 }
 
 func (m *Manager) RunTool(ctx context.Context, profile store.Profile, args []string) error {
-	adapter, err := adapters.Get(profile.Tool)
+	adapter, err := m.adapterFor(profile)
+	if err != nil {
+		return err
+	}
+	env, err := m.LaunchEnv(profile)
 	if err != nil {
 		return err
 	}
 	cmd := adapter.RunCommand(profile.Dir, args)
-	return runInteractive(ctx, cmd)
+	if cmd.Env, err = m.isolateCommandEnv(profile, cmd.Env); err != nil {
+		return err
+	}
+	cmd.Env = append(cmd.Env, env...)
+	return m.runProfileCommand(ctx, profile, cmd)
 }
 
-func (m *Manager) StatusForProfile(ctx context.Context, profile store.Profile) (adapters.Status, error) {
-	adapter, err := adapters.Get(profile.Tool)
+// RunInProfile runs cmd interactively for profile. A vault profile's
+// credentials stay unsealed until cmd exits.
+func (m *Manager) RunInProfile(ctx context.Context, profile store.Profile, cmd *exec.Cmd) error {
+	return m.runProfileCommand(ctx, profile, cmd)
+}
+
+// Login runs the tool's login flow under the profile's environment and
+// returns the refreshed status.
+func (m *Manager) Login(ctx context.Context, profile store.Profile, opts adapters.LoginOptions) (adapters.Status, error) {
+	adapter, err := m.adapterFor(profile)
 	if err != nil {
 		return adapters.Status{}, err
 	}
-	return adapter.Status(ctx, profile.Dir)
+	if err := ensureToolBinary(adapter); err != nil {
+		return adapters.Status{}, err
+	}
+	cmd, err := adapter.LoginCommand(profile.Dir, opts)
+	if err != nil {
+		return adapters.Status{}, err
+	}
+	if cmd.Env, err = m.isolateCommandEnv(profile, cmd.Env); err != nil {
+		return adapters.Status{}, err
+	}
+	err = m.runProfileCommand(ctx, profile, cmd)
+	m.invalidateStatus(profile)
+	if err != nil {
+		return adapters.Status{}, err
+	}
+	// The refresh is best effort: a failed probe must not fail the login.
+	status, _ := m.StatusForProfile(ctx, profile)
+	return status, nil
 }
 
-func (m *Manager) StatusRows(ctx context.Context, filterTool *store.Tool) ([]StatusRow, error) {
-	st, err := m.Load()
+// Logout runs the tool's logout command under the profile's environment and
+// returns the refreshed status.
+func (m *Manager) Logout(ctx context.Context, profile store.Profile) (adapters.Status, error) {
+	adapter, err := m.adapterFor(profile)
 	if err != nil {
-		return nil, err
+		return adapters.Status{}, err
 	}
-	rows := []StatusRow{}
-	for _, p := range st.Profiles {
-		if filterTool != nil && p.Tool != *filterTool {
-			continue
-		}
-		dir, err := m.validatedManagedProfileDir(p)
-		if err != nil {
-			rows = append(rows, StatusRow{Profile: p, Error: err.Error()})
-			continue
-		}
-		p.Dir = dir
+	if err := ensureToolBinary(adapter); err != nil {
+		return adapters.Status{}, err
+	}
+	cmd := adapter.LogoutCommand(profile.Dir)
+	if cmd == nil {
+		return adapters.Status{}, fmt.Errorf("%s has no logout command", profile.Tool)
+	}
+	if cmd.Env, err = m.isolateCommandEnv(profile, cmd.Env); err != nil {
+		return adapters.Status{}, err
+	}
+	err = m.runProfileCommand(ctx, profile, cmd)
+	m.invalidateStatus(profile)
+	if err != nil {
+		return adapters.Status{}, err
+	}
+	status, _ := m.StatusForProfile(ctx, profile)
+	return status, nil
+}
 
-		ctxOne, cancel := context.WithTimeout(ctx, 8*time.Second)
-		status, sErr := m.StatusForProfile(ctxOne, p)
-		cancel()
-		row := StatusRow{Profile: p, Status: status}
-		if sErr != nil {
-			row.Error = sErr.Error()
-		}
-		rows = append(rows, row)
+func (m *Manager) StatusForProfile(ctx context.Context, profile store.Profile) (adapters.Status, error) {
+	if st, ok := m.secretStatus(profile); ok {
+		return st, nil
+	}
+	if st, ok := m.vaultStatus(profile); ok {
+		return st, nil
 	}
-	return rows, nil
+	adapter, err := m.adapterFor(profile)
+	if err != nil {
+		return adapters.Status{}, err
+	}
+	return adapter.Status(ctx, profile.Dir)
+}
+
+// OfflineStatusForProfile reads account details from credential files only,
+// without launching the tool.
+func (m *Manager) OfflineStatusForProfile(profile store.Profile) (adapters.Status, bool, error) {
+	if st, ok := m.secretStatus(profile); ok {
+		return st, true, nil
+	}
+	if st, ok := m.vaultStatus(profile); ok {
+		return st, true, nil
+	}
+	adapter, err := adapters.Get(profile.Tool)
+	if err != nil {
+		return adapters.Status{}, false, err
+	}
+	return adapter.OfflineStatus(profile.Dir)
 }
 
 func (m *Manager) expectedProfileDir(tool store.Tool, name string) (string, error) {
@@ -625,15 +739,27 @@ func samePath(a, b string) bool {
 	return a == b
 }
 
+// createEnvDirs creates the subdirectories that XDG-style adapters point
+// their variables at, so tools that expect them to exist start cleanly.
+func createEnvDirs(tool store.Tool, profileDir string) error {
+	adapter, err := adapters.Get(tool)
+	if err != nil {
+		return err
+	}
+	for _, e := range adapter.Env(profileDir) {
+		if err := os.MkdirAll(e.Value, 0o755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func sessionLeafForTool(tool store.Tool) (string, error) {
-	switch tool {
-	case store.ToolClaude:
-		return "projects", nil
-	case store.ToolCodex:
-		return "sessions", nil
-	default:
+	def, ok := adapters.Lookup(tool)
+	if !ok || def.SessionLeaf == "" {
 		return "", fmt.Errorf("unsupported tool %q for shared sessions", tool)
 	}
+	return def.SessionLeaf, nil
 }
 
 func createDirLink(target, linkPath string) error {
@@ -670,7 +796,9 @@ func removeDirLink(linkPath string) error {
 }
 
 func runInteractive(ctx context.Context, cmd *exec.Cmd) error {
-	cmd.Stdin = os.Stdin
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Start(); err != nil {
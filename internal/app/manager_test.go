@@ -572,3 +572,44 @@ func TestRemoveProfileAlsoRemovesSettingsSyncBinding(t *testing.T) {
 		t.Fatalf("sync binding should be removed with profile")
 	}
 }
+
+func TestNewManagerLoadsAdapterDefinitions(t *testing.T) {
+	root := t.TempDir()
+	defs := filepath.Join(root, "adapters.d")
+	if err := os.MkdirAll(defs, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	def := `{"name": "opencode", "binary": "opencode", "config_env": "OPENCODE_CONFIG_DIR", "native_config_dir": ["~/.config/opencode"], "session_leaf": "storage", "settings_files": ["opencode.json"]}`
+	if err := os.WriteFile(filepath.Join(defs, "opencode.json"), []byte(def), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AdapterLoadError(); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := store.Tool("opencode")
+	p, _, err := m.EnsureProfile(tool, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, err := settingsPathsForTool(tool); err != nil || len(files) != 1 || files[0] != "opencode.json" {
+		t.Fatalf("unexpected settings files: %v %v", files, err)
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	native, err := nativeConfigDirForTool(tool)
+	if err != nil || filepath.Base(native) != "opencode" {
+		t.Fatalf("unexpected native dir: %q %v", native, err)
+	}
+	shared, err := m.EnableSharedSessions(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(shared) != "storage" {
+		t.Fatalf("shared sessions should use the declared leaf, got %s", shared)
+	}
+}
@@ -0,0 +1,373 @@
+package app
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+	"github.com/derekurban/profilex-cli/internal/usage"
+)
+
+type DiskUsageEntry struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Bytes  int64  `json:"bytes"`
+	Files  int    `json:"files"`
+	Linked string `json:"linked,omitempty"`
+}
+
+type ProfileDiskUsage struct {
+	Profile   store.Profile    `json:"profile"`
+	Bytes     int64            `json:"bytes"`
+	Breakdown []DiskUsageEntry `json:"breakdown"`
+}
+
+type DiskUsageReport struct {
+	Root     string             `json:"root"`
+	Profiles []ProfileDiskUsage `json:"profiles"`
+	Shared   []DiskUsageEntry   `json:"shared"`
+	Total    int64              `json:"total"`
+}
+
+type PruneOptions struct {
+	OlderThan time.Duration
+	Archive   string
+	DryRun    bool
+}
+
+type PruneResult struct {
+	Cutoff   time.Time           `json:"cutoff"`
+	DryRun   bool                `json:"dryRun"`
+	Scanned  int                 `json:"scanned"`
+	Files    []usage.SessionFile `json:"files"`
+	Bytes    int64               `json:"bytes"`
+	Archive  string              `json:"archive,omitempty"`
+	Archived int                 `json:"archived"`
+	Skipped  []string            `json:"skipped,omitempty"`
+}
+
+// DiskUsage reports disk use per profile, per shared pool and per top-level
+// subdirectory. Shared links are reported on the profile but counted only
+// once, under the shared pool they point to.
+func (m *Manager) DiskUsage(filterTool *store.Tool) (*DiskUsageReport, error) {
+	st, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	report := &DiskUsageReport{Root: m.Root(), Profiles: []ProfileDiskUsage{}, Shared: []DiskUsageEntry{}}
+
+	for _, p := range st.Profiles {
+		if filterTool != nil && p.Tool != *filterTool {
+			continue
+		}
+		dir, err := m.validatedManagedProfileDir(p)
+		if err != nil {
+			return nil, err
+		}
+		p.Dir = dir
+		row := ProfileDiskUsage{Profile: p, Breakdown: []DiskUsageEntry{}}
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		loose := DiskUsageEntry{Name: "(files)", Path: dir}
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+			info, err := os.Lstat(path)
+			if err != nil {
+				continue
+			}
+			if info.Mode()&os.ModeSymlink != 0 || isDirLink(path, info) {
+				target, _ := filepath.EvalSymlinks(path)
+				row.Breakdown = append(row.Breakdown, DiskUsageEntry{Name: e.Name(), Path: path, Linked: target})
+				continue
+			}
+			if !info.IsDir() {
+				loose.Bytes += info.Size()
+				loose.Files++
+				continue
+			}
+			bytes, files := dirSize(path)
+			row.Breakdown = append(row.Breakdown, DiskUsageEntry{Name: e.Name(), Path: path, Bytes: bytes, Files: files})
+		}
+		if loose.Files > 0 {
+			row.Breakdown = append(row.Breakdown, loose)
+		}
+		for _, b := range row.Breakdown {
+			row.Bytes += b.Bytes
+		}
+		sortDiskEntries(row.Breakdown)
+		report.Profiles = append(report.Profiles, row)
+		report.Total += row.Bytes
+	}
+
+	pools := []DiskUsageEntry{{Name: "skills", Path: filepath.Join(m.Root(), "shared", "skills")}}
+	for _, tool := range store.SupportedTools {
+		if filterTool != nil && tool != *filterTool {
+			continue
+		}
+		leaf, err := sessionLeafForTool(tool)
+		if err != nil {
+			continue
+		}
+		pools = append(pools, DiskUsageEntry{Name: string(tool) + "/" + leaf, Path: filepath.Join(m.Root(), "shared", string(tool), leaf)})
+	}
+	for _, pool := range pools {
+		if !dirExists(pool.Path) {
+			continue
+		}
+		pool.Bytes, pool.Files = dirSize(pool.Path)
+		report.Shared = append(report.Shared, pool)
+		report.Total += pool.Bytes
+	}
+	sortDiskEntries(report.Shared)
+	return report, nil
+}
+
+// sessionPruneGrace is how recently modified a session file may be and still
+// never be pruned, whatever the requested age: a session that has not ended
+// may still be written to.
+const sessionPruneGrace = time.Hour
+
+// PruneSessions removes (or archives and removes) session JSONL files whose
+// modification time is older than opts.OlderThan across every managed
+// profile and shared pool. Files modified within sessionPruneGrace, or
+// reachable from a profile a live `profilex run` holds, are left alone.
+func (m *Manager) PruneSessions(opts PruneOptions) (*PruneResult, error) {
+	if opts.OlderThan <= 0 {
+		return nil, errors.New("prune age must be positive")
+	}
+	st, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	files, err := usage.ListSessionFiles(usage.ManagedSessionRoots(st))
+	if err != nil {
+		return nil, err
+	}
+	// Session roots of running profiles, to skip files the tool may still
+	// append to.
+	running := &store.State{}
+	for _, p := range st.Profiles {
+		if m.liveLease(p) != nil {
+			running.Profiles = append(running.Profiles, p)
+		}
+	}
+	inUse := usage.ManagedSessionRoots(running)
+
+	result := &PruneResult{
+		Files:   []usage.SessionFile{},
+		DryRun:  opts.DryRun,
+		Cutoff:  time.Now().Add(-max(opts.OlderThan, sessionPruneGrace)).UTC(),
+		Scanned: len(files),
+		Archive: opts.Archive,
+	}
+	for _, f := range files {
+		if !f.ModTime.Before(result.Cutoff) {
+			continue
+		}
+		if slices.ContainsFunc(f.Roots, func(root string) bool { return slices.Contains(inUse, root) }) {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: in use by a running profile", f.Path))
+			continue
+		}
+		result.Files = append(result.Files, f)
+		result.Bytes += f.Bytes
+	}
+	if opts.DryRun || len(result.Files) == 0 {
+		return result, nil
+	}
+
+	if strings.TrimSpace(opts.Archive) != "" {
+		if err := m.archiveSessions(opts.Archive, result.Files); err != nil {
+			return nil, fmt.Errorf("archive sessions: %w", err)
+		}
+		result.Archived = len(result.Files)
+	}
+
+	for _, f := range result.Files {
+		if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %v", f.Path, err))
+			continue
+		}
+		removeEmptyParents(filepath.Dir(f.Path), f.Roots)
+	}
+	return result, nil
+}
+
+func (m *Manager) archiveSessions(archivePath string, files []usage.SessionFile) (err error) {
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = os.Remove(archivePath)
+		}
+	}()
+
+	var (
+		w       io.Writer = out
+		closers []func() error
+	)
+	lower := strings.ToLower(archivePath)
+	switch {
+	case strings.HasSuffix(lower, ".tar.zst") || strings.HasSuffix(lower, ".tzst"):
+		zstd, lookErr := exec.LookPath("zstd")
+		if lookErr != nil {
+			return errors.New("zstd not found in PATH (use a .tar.gz archive instead)")
+		}
+		cmd := exec.Command(zstd, "-q", "-c")
+		cmd.Stdout = out
+		cmd.Stderr = os.Stderr
+		stdin, pipeErr := cmd.StdinPipe()
+		if pipeErr != nil {
+			return pipeErr
+		}
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		w = stdin
+		closers = append(closers, stdin.Close, cmd.Wait)
+	case strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz"):
+		gz := gzip.NewWriter(out)
+		w = gz
+		closers = append(closers, gz.Close)
+	case strings.HasSuffix(lower, ".tar"):
+	default:
+		return fmt.Errorf("unsupported archive extension for %s (expected .tar, .tar.gz or .tar.zst)", archivePath)
+	}
+
+	tw := tar.NewWriter(w)
+	for _, f := range files {
+		if err := addFileToTar(tw, f.Path, m.archiveName(f.Path)); err != nil {
+			_ = tw.Close()
+			for _, c := range closers {
+				_ = c()
+			}
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	for _, c := range closers {
+		if err := c(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) archiveName(path string) string {
+	root := m.Root()
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return strings.TrimLeft(filepath.ToSlash(filepath.Clean(path)), "/")
+}
+
+func addFileToTar(tw *tar.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func removeEmptyParents(dir string, roots []string) {
+	stop := map[string]bool{}
+	for _, r := range roots {
+		stop[filepath.Clean(filepath.FromSlash(r))] = true
+		if resolved, err := filepath.EvalSymlinks(r); err == nil {
+			stop[filepath.Clean(resolved)] = true
+		}
+	}
+	for i := 0; i < 8; i++ {
+		dir = filepath.Clean(dir)
+		if stop[dir] || len(stop) == 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func dirSize(root string) (int64, int) {
+	var (
+		bytes int64
+		files int
+	)
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		bytes += info.Size()
+		files++
+		return nil
+	})
+	return bytes, files
+}
+
+// isDirLink reports Windows junctions, which Lstat does not flag as symlinks.
+func isDirLink(path string, info os.FileInfo) bool {
+	if info.Mode()&os.ModeIrregular == 0 {
+		return false
+	}
+	_, err := os.Readlink(path)
+	return err == nil
+}
+
+func sortDiskEntries(entries []DiskUsageEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Bytes == entries[j].Bytes {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].Bytes > entries[j].Bytes
+	})
+}
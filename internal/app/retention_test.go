@@ -0,0 +1,167 @@
+package app
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestPruneSessionsCountsSharedSessionsOnce(t *testing.T) {
+	m := newTestManager(t)
+	for _, name := range []string{"work", "personal"} {
+		p, _, err := m.EnsureProfile(store.ToolClaude, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.EnableSharedSessions(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shared := filepath.Join(m.Root(), "shared", "claude", "projects", "demo")
+	if err := os.MkdirAll(shared, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	oldFile := filepath.Join(shared, "old.jsonl")
+	newFile := filepath.Join(shared, "new.jsonl")
+	for _, f := range []string{oldFile, newFile} {
+		if err := os.WriteFile(f, []byte(`{"type":"user"}`+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	past := time.Now().Add(-100 * 24 * time.Hour)
+	if err := os.Chtimes(oldFile, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	dry, err := m.PruneSessions(PruneOptions{OlderThan: 90 * 24 * time.Hour, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.Scanned != 2 || len(dry.Files) != 1 {
+		t.Fatalf("expected 2 scanned / 1 prunable, got %d / %d", dry.Scanned, len(dry.Files))
+	}
+	if _, err := os.Stat(oldFile); err != nil {
+		t.Fatalf("dry run must not delete: %v", err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "old.tar.gz")
+	res, err := m.PruneSessions(PruneOptions{OlderThan: 90 * 24 * time.Hour, Archive: archive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Archived != 1 {
+		t.Fatalf("expected 1 archived session, got %d", res.Archived)
+	}
+	if _, err := os.Stat(oldFile); !os.IsNotExist(err) {
+		t.Fatalf("old session should be pruned")
+	}
+	if _, err := os.Stat(newFile); err != nil {
+		t.Fatalf("recent session should remain: %v", err)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr, err := tar.NewReader(gz).Next()
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if hdr == nil || hdr.Name != "shared/claude/projects/demo/old.jsonl" {
+		t.Fatalf("unexpected archive entry: %+v", hdr)
+	}
+}
+
+func TestPruneSessionsSkipsLiveSessions(t *testing.T) {
+	m := newTestManager(t)
+	running, _, err := m.EnsureProfile(store.ToolClaude, "running")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idle, _, err := m.EnsureProfile(store.ToolClaude, "idle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(path string, age time.Duration) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(`{"type":"user"}`+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		at := time.Now().Add(-age)
+		if err := os.Chtimes(path, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	leased := filepath.Join(running.Dir, "projects", "demo", "old.jsonl")
+	recent := filepath.Join(idle.Dir, "projects", "demo", "recent.jsonl")
+	stale := filepath.Join(idle.Dir, "projects", "demo", "stale.jsonl")
+	write(leased, 48*time.Hour)
+	write(recent, 10*time.Minute)
+	write(stale, 48*time.Hour)
+	if err := m.writeLease(running, &RunLease{PIDs: []int{os.Getpid()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := m.PruneSessions(PruneOptions{OlderThan: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 1 || res.Files[0].Path != stale || len(res.Skipped) != 1 {
+		t.Fatalf("expected only the stale idle session pruned and the leased one skipped, got %+v", res)
+	}
+	for _, path := range []string{leased, recent} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("live session %s should remain: %v", path, err)
+		}
+	}
+}
+
+func TestDiskUsageCountsSharedPoolOnce(t *testing.T) {
+	m := newTestManager(t)
+	for _, name := range []string{"work", "personal"} {
+		p, _, err := m.EnsureProfile(store.ToolCodex, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.EnableSharedSessions(p); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(p.Dir, "log"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(p.Dir, "log", "codex.log"), make([]byte, 10), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(m.Root(), "shared", "codex", "sessions", "s.jsonl"), make([]byte, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := m.DiskUsage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 120 {
+		t.Fatalf("expected shared pool counted once (total 120), got %d", report.Total)
+	}
+	for _, p := range report.Profiles {
+		if p.Bytes != 10 {
+			t.Fatalf("profile %s should only count its own logs, got %d", p.Profile.Name, p.Bytes)
+		}
+	}
+}
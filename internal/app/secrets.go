@@ -0,0 +1,107 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/secrets"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// SetPassphrasePrompt installs the callback used to ask for the secret store
+// passphrase when neither a key file nor PROFILEX_PASSPHRASE is available.
+func (m *Manager) SetPassphrasePrompt(prompt secrets.PromptFunc) {
+	m.prompt = prompt
+}
+
+func (m *Manager) Secrets() *secrets.Store {
+	return m.secrets
+}
+
+// SetAPIKey encrypts value into the secret store and switches the profile to
+// API key auth.
+func (m *Manager) SetAPIKey(tool store.Tool, name, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return errors.New("API key cannot be empty")
+	}
+	st, err := m.Load()
+	if err != nil {
+		return err
+	}
+	if _, p := store.FindProfile(st, tool, name); p == nil {
+		return fmt.Errorf("profile not found: %s/%s", tool, name)
+	}
+	key, err := m.secrets.ResolveKey(m.prompt, true)
+	if err != nil {
+		return err
+	}
+	if err := m.secrets.Set(tool, name, value, key); err != nil {
+		return err
+	}
+	return m.setAuthMode(tool, name, store.AuthModeAPIKey)
+}
+
+// ClearAPIKey deletes the stored key and returns the profile to the tool's
+// own credential files.
+func (m *Manager) ClearAPIKey(tool store.Tool, name string) error {
+	if err := m.secrets.Delete(tool, name); err != nil {
+		return err
+	}
+	return m.setAuthMode(tool, name, store.AuthModeDefault)
+}
+
+func (m *Manager) setAuthMode(tool store.Tool, name string, mode store.AuthMode) error {
+	return m.store.Update(func(st *store.State) error {
+		idx, p := store.FindProfile(st, tool, name)
+		if p == nil {
+			return fmt.Errorf("profile not found: %s/%s", tool, name)
+		}
+		st.Profiles[idx].Auth = mode
+		return nil
+	})
+}
+
+// LaunchEnv returns the extra KEY=VALUE pairs for the profile's child
+// process. API keys are decrypted here and only ever passed to the child;
+// they are never written to state or exports.
+func (m *Manager) LaunchEnv(profile store.Profile) ([]string, error) {
+	if profile.Auth != store.AuthModeAPIKey {
+		return nil, nil
+	}
+	adapter, err := adapters.Get(profile.Tool)
+	if err != nil {
+		return nil, err
+	}
+	if !m.secrets.Has(profile.Tool, profile.Name) {
+		return nil, fmt.Errorf("no API key stored for %s/%s (run `profilex secret set %s %s`)", profile.Tool, profile.Name, profile.Tool, profile.Name)
+	}
+	key, err := m.secrets.UnlockKey(profile.Tool, profile.Name, m.prompt)
+	if err != nil {
+		return nil, err
+	}
+	value, _, err := m.secrets.Get(profile.Tool, profile.Name, key)
+	if err != nil {
+		return nil, err
+	}
+	return []string{adapter.APIKeyEnvVar() + "=" + value}, nil
+}
+
+// secretStatus reports API key profiles as logged in when a key is stored,
+// without decrypting it.
+func (m *Manager) secretStatus(profile store.Profile) (adapters.Status, bool) {
+	if profile.Auth != store.AuthModeAPIKey {
+		return adapters.Status{}, false
+	}
+	if !m.secrets.Has(profile.Tool, profile.Name) {
+		return adapters.Status{Source: adapters.SourceSecret}, true
+	}
+	return adapters.Status{
+		LoggedIn: true,
+		Method:   "api_key",
+		AuthKind: adapters.AuthAPIKey,
+		Source:   adapters.SourceSecret,
+	}, true
+}
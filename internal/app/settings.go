@@ -6,23 +6,22 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/derekurban/profilex-cli/internal/adapters"
 	"github.com/derekurban/profilex-cli/internal/store"
 )
 
 const nativeProfileRef = "default"
 
 func settingsPathsForTool(tool store.Tool) ([]string, error) {
-	switch tool {
-	case store.ToolCodex:
-		return []string{"config.toml"}, nil
-	case store.ToolClaude:
-		return []string{"settings.json"}, nil
-	default:
+	def, ok := adapters.Lookup(tool)
+	if !ok || len(def.SettingsFiles) == 0 {
 		return nil, fmt.Errorf("unsupported tool %q for settings presets", tool)
 	}
+	return def.SettingsFiles, nil
 }
 
 func isNativeProfileAlias(name string) bool {
@@ -40,35 +39,11 @@ func dirExists(path string) bool {
 }
 
 func nativeConfigDirForTool(tool store.Tool) (string, error) {
-	switch tool {
-	case store.ToolCodex:
-		if custom := strings.TrimSpace(os.Getenv("PROFILEX_NATIVE_CODEX_HOME")); custom != "" {
-			return filepath.Clean(custom), nil
-		}
-	case store.ToolClaude:
-		if custom := strings.TrimSpace(os.Getenv("PROFILEX_NATIVE_CLAUDE_CONFIG_DIR")); custom != "" {
-			return filepath.Clean(custom), nil
-		}
-	}
-
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-
-	switch tool {
-	case store.ToolCodex:
-		return filepath.Join(home, ".codex"), nil
-	case store.ToolClaude:
-		primary := filepath.Join(home, ".claude")
-		legacy := filepath.Join(home, ".config", "claude")
-		if dirExists(primary) || !dirExists(legacy) {
-			return primary, nil
-		}
-		return legacy, nil
-	default:
+	def, ok := adapters.Lookup(tool)
+	if !ok {
 		return "", fmt.Errorf("unsupported tool %q for native settings", tool)
 	}
+	return def.NativeConfigDir()
 }
 
 func nativeSessionDirForTool(tool store.Tool) (string, error) {
@@ -144,74 +119,115 @@ func (m *Manager) NativeSessionDir(tool store.Tool) (string, error) {
 	return nativeSessionDirForTool(tool)
 }
 
-func (m *Manager) applyPresetDirToProfile(profileDir, presetDir string, tool store.Tool) error {
-	paths, err := settingsPathsForTool(tool)
+// presetScope locates an existing preset and what applying it covers.
+func (m *Manager) presetScope(st *store.State, tool store.Tool, preset string) (string, SettingsScope, error) {
+	presetDir, err := m.expectedPresetDir(tool, preset)
 	if err != nil {
-		return err
+		return "", SettingsScope{}, err
 	}
-	for _, rel := range paths {
-		src := filepath.Join(presetDir, rel)
-		dst := filepath.Join(profileDir, rel)
-		if err := syncPath(src, dst); err != nil {
-			return fmt.Errorf("sync settings path %q: %w", rel, err)
+	if _, err := os.Stat(presetDir); err != nil {
+		if os.IsNotExist(err) {
+			return "", SettingsScope{}, fmt.Errorf("settings preset not found: %s/%s", tool, preset)
 		}
+		return "", SettingsScope{}, err
 	}
-	return nil
+	_, p := store.FindSettingsPreset(st, tool, preset)
+	scope, err := applyScope(tool, p, presetDir)
+	if err != nil {
+		return "", SettingsScope{}, err
+	}
+	return presetDir, scope, nil
 }
 
-func (m *Manager) applyPresetToDir(tool store.Tool, preset, profileDir string) error {
-	presetDir, err := m.expectedPresetDir(tool, preset)
+func (m *Manager) applyPresetToDir(st *store.State, tool store.Tool, preset, profileDir string) error {
+	presetDir, scope, cleanup, err := m.effectivePreset(st, tool, preset)
+	defer cleanup()
 	if err != nil {
 		return err
 	}
-	if _, err := os.Stat(presetDir); err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("settings preset not found: %s/%s", tool, preset)
+	for _, rel := range scope.Paths {
+		if err := scope.applyPath(presetDir, profileDir, rel); err != nil {
+			return fmt.Errorf("sync settings path %q: %w", rel, err)
 		}
-		return err
 	}
-	return m.applyPresetDirToProfile(profileDir, presetDir, tool)
+	return nil
 }
 
 // SnapshotSettings stores tool-native settings from a source profile into a
-// named preset.
+// named preset and records it as a new revision, unless nothing changed
+// since the latest one.
 func (m *Manager) SnapshotSettings(tool store.Tool, sourceProfile, preset string) (int, error) {
 	if err := store.ValidatePresetName(preset); err != nil {
 		return 0, err
 	}
-	st, err := m.Load()
-	if err != nil {
-		return 0, err
-	}
-	sourceDir, _, err := m.resolveSettingsProfileDir(st, tool, sourceProfile)
-	if err != nil {
-		return 0, err
-	}
-	presetDir, err := m.expectedPresetDir(tool, preset)
-	if err != nil {
-		return 0, err
-	}
-	if err := os.MkdirAll(presetDir, 0o755); err != nil {
-		return 0, err
-	}
+	// The snapshot and its revision are taken under the state lock, so
+	// concurrent snapshots cannot claim the same revision number.
+	return 0, m.store.Update(func(st *store.State) error {
+		sourceDir, canonicalSource, err := m.resolveSettingsProfileDir(st, tool, sourceProfile)
+		if err != nil {
+			return err
+		}
+		presetDir, err := m.expectedPresetDir(tool, preset)
+		if err != nil {
+			return err
+		}
+		existed := dirExists(presetDir)
+		if err := os.MkdirAll(presetDir, 0o755); err != nil {
+			return err
+		}
 
-	paths, err := settingsPathsForTool(tool)
-	if err != nil {
-		return 0, err
-	}
-	for _, rel := range paths {
-		src := filepath.Join(sourceDir, rel)
-		dst := filepath.Join(presetDir, rel)
-		if err := syncPath(src, dst); err != nil {
-			return 0, fmt.Errorf("snapshot settings path %q: %w", rel, err)
+		now := time.Now().UTC()
+		idx, p := store.FindSettingsPreset(st, tool, preset)
+		if p == nil {
+			st.SettingsPresets = append(st.SettingsPresets, store.SettingsPreset{
+				Tool:      tool,
+				Name:      preset,
+				CreatedAt: now,
+			})
+			p = &st.SettingsPresets[len(st.SettingsPresets)-1]
+		}
+		// Presets snapshotted before revisions were kept get their current
+		// content saved first, so this snapshot cannot lose it.
+		if existed && len(p.Revisions) == 0 {
+			prior, err := applyScope(tool, p, presetDir)
+			if err != nil {
+				return err
+			}
+			if len(prior.Paths) > 0 {
+				if _, err := m.recordPresetRevision(p, tool, preset, presetDir, prior, "", now); err != nil {
+					return err
+				}
+			}
 		}
-	}
 
-	now := time.Now().UTC()
-	if err := m.touchPreset(tool, preset, now); err != nil {
-		return 0, err
-	}
-	return 0, nil
+		scope, err := captureScope(tool, p)
+		if err != nil {
+			return err
+		}
+		for _, rel := range scope.Paths {
+			if err := scope.copyPath(sourceDir, presetDir, rel); err != nil {
+				return fmt.Errorf("snapshot settings path %q: %w", rel, err)
+			}
+		}
+		// Drop what an earlier snapshot captured but the allowlist no longer
+		// covers, so applying the preset cannot resurrect it.
+		if idx >= 0 {
+			for _, rel := range p.Paths {
+				if !slices.Contains(scope.Paths, rel) {
+					if err := os.RemoveAll(filepath.Join(presetDir, filepath.FromSlash(rel))); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if _, err := m.recordPresetRevision(p, tool, preset, presetDir, scope, canonicalSource, now); err != nil {
+			return err
+		}
+		p.Paths = scope.Paths
+		p.UpdatedAt = now
+		return nil
+	})
 }
 
 func (m *Manager) ApplySettingsPreset(tool store.Tool, preset, profileName string) error {
@@ -223,18 +239,21 @@ func (m *Manager) ApplySettingsPreset(tool store.Tool, preset, profileName strin
 	if err != nil {
 		return err
 	}
-	if err := m.applyPresetToDir(tool, preset, profileDir); err != nil {
+	if err := m.applyPresetToDir(st, tool, preset, profileDir); err != nil {
 		return err
 	}
 	return m.touchPreset(tool, preset, time.Now().UTC())
 }
 
+// SetSettingsSync binds a profile to a preset, applying it now, or unbinds
+// it. Bound profiles are brought back in line before each launch; see
+// ApplySyncedSettings.
 func (m *Manager) SetSettingsSync(tool store.Tool, profileName, preset string, enabled bool) error {
 	st, err := m.Load()
 	if err != nil {
 		return err
 	}
-	_, canonicalProfile, err := m.resolveSettingsProfileDir(st, tool, profileName)
+	profileDir, canonicalProfile, err := m.resolveSettingsProfileDir(st, tool, profileName)
 	if err != nil {
 		return err
 	}
@@ -242,16 +261,6 @@ func (m *Manager) SetSettingsSync(tool store.Tool, profileName, preset string, e
 		if err := store.ValidatePresetName(preset); err != nil {
 			return err
 		}
-		presetDir, err := m.expectedPresetDir(tool, preset)
-		if err != nil {
-			return err
-		}
-		if _, err := os.Stat(presetDir); err != nil {
-			if os.IsNotExist(err) {
-				return fmt.Errorf("settings preset not found: %s/%s", tool, preset)
-			}
-			return err
-		}
 		if err := m.ApplySettingsPreset(tool, preset, canonicalProfile); err != nil {
 			return err
 		}
@@ -270,22 +279,18 @@ func (m *Manager) SetSettingsSync(tool store.Tool, profileName, preset string, e
 		if existing != nil {
 			st.SettingsSync[idx].Preset = preset
 			st.SettingsSync[idx].UpdatedAt = now
-			return nil
+		} else {
+			st.SettingsSync = append(st.SettingsSync, store.SettingsSync{
+				Tool:      tool,
+				Profile:   canonicalProfile,
+				Preset:    preset,
+				UpdatedAt: now,
+			})
 		}
-		st.SettingsSync = append(st.SettingsSync, store.SettingsSync{
-			Tool:      tool,
-			Profile:   canonicalProfile,
-			Preset:    preset,
-			UpdatedAt: now,
-		})
-		return nil
+		return m.recordSettingsBaseline(st, tool, preset, profileDir, canonicalProfile)
 	})
 }
 
-func (m *Manager) ApplySyncedSettings(profile store.Profile) (bool, string, error) {
-	return false, "", nil
-}
-
 func (m *Manager) ListSettings(tool *store.Tool) ([]store.SettingsPreset, []store.SettingsSync, error) {
 	st, err := m.Load()
 	if err != nil {
@@ -341,6 +346,22 @@ func (m *Manager) RenameSettingsPreset(tool store.Tool, oldName, newName string)
 	if err := os.Rename(oldDir, newDir); err != nil {
 		return err
 	}
+	oldHistory, err := m.presetHistoryDir(tool, oldName)
+	if err != nil {
+		return err
+	}
+	newHistory, err := m.presetHistoryDir(tool, newName)
+	if err != nil {
+		return err
+	}
+	if dirExists(oldHistory) {
+		if err := os.RemoveAll(newHistory); err != nil {
+			return err
+		}
+		if err := os.Rename(oldHistory, newHistory); err != nil {
+			return err
+		}
+	}
 
 	return m.store.Update(func(st *store.State) error {
 		idx, p := store.FindSettingsPreset(st, tool, oldName)
@@ -349,6 +370,11 @@ func (m *Manager) RenameSettingsPreset(tool store.Tool, oldName, newName string)
 		}
 		st.SettingsPresets[idx].Name = newName
 		st.SettingsPresets[idx].UpdatedAt = time.Now().UTC()
+		for i := range st.SettingsPresets {
+			if st.SettingsPresets[i].Tool == tool && st.SettingsPresets[i].Parent == oldName {
+				st.SettingsPresets[i].Parent = newName
+			}
+		}
 		for i := range st.SettingsSync {
 			if st.SettingsSync[i].Tool == tool && st.SettingsSync[i].Preset == oldName {
 				st.SettingsSync[i].Preset = newName
@@ -364,6 +390,15 @@ func (m *Manager) DeleteSettingsPreset(tool store.Tool, name string) error {
 	if err != nil {
 		return err
 	}
+	st, err := m.Load()
+	if err != nil {
+		return err
+	}
+	for _, p := range st.SettingsPresets {
+		if p.Tool == tool && p.Parent == name {
+			return fmt.Errorf("settings preset %s/%s is extended by %s; change its parent first", tool, name, p.Name)
+		}
+	}
 	if _, err := os.Stat(dir); err == nil {
 		if rmErr := os.RemoveAll(dir); rmErr != nil {
 			return rmErr
@@ -371,6 +406,13 @@ func (m *Manager) DeleteSettingsPreset(tool store.Tool, name string) error {
 	} else if !os.IsNotExist(err) {
 		return err
 	}
+	history, err := m.presetHistoryDir(tool, name)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(history); err != nil {
+		return err
+	}
 
 	return m.store.Update(func(st *store.State) error {
 		if idx, p := store.FindSettingsPreset(st, tool, name); p != nil {
@@ -388,25 +430,79 @@ func (m *Manager) DeleteSettingsPreset(tool store.Tool, name string) error {
 	})
 }
 
-func syncPath(src, dst string) error {
+// syncPath replaces dst with src, or removes dst when src is missing. skip
+// is given paths relative to src and dst; skipped files are neither copied
+// nor removed.
+func syncPath(src, dst string, skip func(rel string) bool) error {
 	info, err := os.Stat(src)
 	if err != nil {
 		if os.IsNotExist(err) {
-			if rmErr := os.RemoveAll(dst); rmErr != nil && !os.IsNotExist(rmErr) {
-				return rmErr
-			}
-			return nil
+			return clearPath(dst, skip)
 		}
 		return err
 	}
 	if info.IsDir() {
-		return copyDirReplace(src, dst)
+		return copyDirReplace(src, dst, skip)
 	}
 	return copyFileReplace(src, dst, info.Mode())
 }
 
-func copyDirReplace(src, dst string) error {
-	if err := os.RemoveAll(dst); err != nil {
+// clearPath removes dst except for what skip keeps, then dst itself if that
+// left it empty.
+func clearPath(dst string, skip func(rel string) bool) error {
+	info, err := os.Lstat(dst)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return os.Remove(dst)
+	}
+	err = filepath.WalkDir(dst, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if skip(filepath.ToSlash(rel)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return err
+	}
+	removeEmptyDirs(dst)
+	return nil
+}
+
+// removeEmptyDirs removes dir and its subdirectories, deepest first, where
+// they are empty.
+func removeEmptyDirs(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			removeEmptyDirs(filepath.Join(dir, e.Name()))
+		}
+	}
+	_ = os.Remove(dir)
+}
+
+func copyDirReplace(src, dst string, skip func(rel string) bool) error {
+	if err := clearPath(dst, skip); err != nil {
 		return err
 	}
 	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
@@ -417,6 +513,12 @@ func copyDirReplace(src, dst string) error {
 		if err != nil {
 			return err
 		}
+		if rel != "." && skip(filepath.ToSlash(rel)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		target := filepath.Join(dst, rel)
 		if d.IsDir() {
 			return os.MkdirAll(target, 0o755)
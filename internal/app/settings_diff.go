@@ -0,0 +1,388 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// SettingsDiff lists how two sets of tool settings differ, file by file.
+type SettingsDiff struct {
+	Tool  store.Tool         `json:"tool"`
+	A     string             `json:"a"`
+	B     string             `json:"b"`
+	Files []SettingsFileDiff `json:"files"`
+}
+
+// SettingsFileDiff is one file that differs. JSON and TOML files are
+// compared key by key; other files, and settings that do not parse, line
+// by line.
+type SettingsFileDiff struct {
+	Path string `json:"path"`
+	// Status is "added" (only in B), "removed" (only in A) or "changed".
+	Status string            `json:"status"`
+	Keys   []SettingsKeyDiff `json:"keys,omitempty"`
+	// Lines are the removed ("- ") and added ("+ ") lines, in file order.
+	Lines []string `json:"lines,omitempty"`
+}
+
+// SettingsKeyDiff is one differing key, dotted from the top of the file.
+// Values are compact JSON, or the TOML value as written.
+type SettingsKeyDiff struct {
+	Key string `json:"key"`
+	A   string `json:"a,omitempty"`
+	B   string `json:"b,omitempty"`
+	// Op is "added", "removed" or "changed".
+	Op string `json:"op"`
+}
+
+// settingsSide is one side of a diff: a directory read through a scope.
+type settingsSide struct {
+	label string
+	dir   string
+	scope SettingsScope
+}
+
+// DiffSettings compares two presets, profiles or native configs of a tool.
+// A preset is compared as layered over its parents, and "<preset>@<rev>"
+// names one of its revisions. A bare name is looked up as a preset, then as
+// a profile; "preset:" and "profile:" prefixes settle names used by both. An
+// empty ref stands for no settings at all.
+func (m *Manager) DiffSettings(tool store.Tool, a, b string) (SettingsDiff, error) {
+	st, err := m.Load()
+	if err != nil {
+		return SettingsDiff{}, err
+	}
+	sa, cleanupA, err := m.resolveSettingsSide(st, tool, a)
+	defer cleanupA()
+	if err != nil {
+		return SettingsDiff{}, err
+	}
+	sb, cleanupB, err := m.resolveSettingsSide(st, tool, b)
+	defer cleanupB()
+	if err != nil {
+		return SettingsDiff{}, err
+	}
+
+	diff := SettingsDiff{Tool: tool, A: sa.label, B: sb.label, Files: []SettingsFileDiff{}}
+	filesA, err := sa.files()
+	if err != nil {
+		return diff, err
+	}
+	filesB, err := sb.files()
+	if err != nil {
+		return diff, err
+	}
+	names := []string{}
+	for rel := range filesA {
+		names = append(names, rel)
+	}
+	for rel := range filesB {
+		if _, ok := filesA[rel]; !ok {
+			names = append(names, rel)
+		}
+	}
+	slices.Sort(names)
+	for _, rel := range names {
+		ca, inA := filesA[rel]
+		cb, inB := filesB[rel]
+		if inA && inB && bytes.Equal(ca, cb) {
+			continue
+		}
+		fd := SettingsFileDiff{Path: rel, Status: "changed"}
+		switch {
+		case !inA:
+			fd.Status = "added"
+		case !inB:
+			fd.Status = "removed"
+		}
+		keysA, okA := flattenSettings(rel, ca)
+		keysB, okB := flattenSettings(rel, cb)
+		if okA && okB {
+			fd.Keys = diffKeys(keysA, keysB)
+			// Formatting-only changes are not differences.
+			if len(fd.Keys) == 0 && fd.Status == "changed" {
+				continue
+			}
+		} else if bytes.IndexByte(ca, 0) >= 0 || bytes.IndexByte(cb, 0) >= 0 {
+			fd.Lines = []string{"(binary files differ)"}
+		} else {
+			fd.Lines = diffLines(string(ca), string(cb))
+		}
+		diff.Files = append(diff.Files, fd)
+	}
+	return diff, nil
+}
+
+func (m *Manager) resolveSettingsSide(st *store.State, tool store.Tool, ref string) (settingsSide, func(), error) {
+	noop := func() {}
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		scope, err := captureScope(tool, nil)
+		scope.Paths = nil
+		return settingsSide{label: "(none)", scope: scope}, noop, err
+	}
+	kind, name, found := strings.Cut(ref, ":")
+	if !found || (kind != "preset" && kind != "profile") {
+		kind, name = "", ref
+	}
+	if name, rev, err := parsePresetRevisionRef(name); err != nil || rev > 0 {
+		if err != nil {
+			return settingsSide{}, noop, err
+		}
+		side, err := m.revisionSide(st, tool, name, rev)
+		return side, noop, err
+	}
+	if kind == "" && !isNativeProfileAlias(name) {
+		presetDir, err := m.expectedPresetDir(tool, name)
+		isPreset := err == nil && dirExists(presetDir)
+		_, profileErr := m.GetProfile(st, tool, name)
+		switch {
+		case isPreset && profileErr == nil:
+			return settingsSide{}, noop, fmt.Errorf("%q is both a preset and a profile of %s; use preset:%s or profile:%s", name, tool, name, name)
+		case isPreset:
+			kind = "preset"
+		default:
+			kind = "profile"
+		}
+	}
+
+	if kind == "preset" {
+		dir, scope, cleanup, err := m.effectivePreset(st, tool, name)
+		return settingsSide{label: "preset " + name, dir: dir, scope: scope}, cleanup, err
+	}
+	dir, canonical, err := m.resolveSettingsProfileDir(st, tool, name)
+	if err != nil {
+		return settingsSide{}, noop, err
+	}
+	// A profile is read through the whole default allowlist and anything a
+	// preset includes, so both sides cover the same paths.
+	scope, err := captureScope(tool, nil)
+	if err != nil {
+		return settingsSide{}, noop, err
+	}
+	for _, p := range st.SettingsPresets {
+		if p.Tool != tool {
+			continue
+		}
+		for _, rel := range p.Include {
+			if !slices.Contains(scope.Paths, rel) {
+				scope.Paths = append(scope.Paths, rel)
+			}
+		}
+	}
+	label := "profile " + canonical
+	if isNativeProfileAlias(name) {
+		label = "native " + string(tool) + " config"
+	}
+	return settingsSide{label: label, dir: dir, scope: scope}, noop, nil
+}
+
+// files reads every file the side's scope covers, keyed by its slash path
+// relative to the side's directory.
+func (s settingsSide) files() (map[string][]byte, error) {
+	out := map[string][]byte{}
+	if s.dir == "" {
+		return out, nil
+	}
+	for _, rel := range s.scope.Paths {
+		if s.scope.skip(rel) {
+			continue
+		}
+		root := filepath.Join(s.dir, filepath.FromSlash(rel))
+		info, err := os.Stat(root)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			b, err := os.ReadFile(root)
+			if err != nil {
+				return nil, err
+			}
+			out[rel] = b
+			continue
+		}
+		err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			sub, err := filepath.Rel(s.dir, p)
+			if err != nil {
+				return err
+			}
+			sub = filepath.ToSlash(sub)
+			if s.scope.skip(sub) {
+				return nil
+			}
+			b, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			out[sub] = b
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// flattenSettings maps each leaf key of a JSON or TOML file to its value.
+// A missing file (nil) flattens to no keys. ok is false for other files and
+// for settings that do not parse.
+func flattenSettings(rel string, b []byte) (map[string]string, bool) {
+	out := map[string]string{}
+	switch strings.ToLower(path.Ext(rel)) {
+	case ".json":
+		v, err := decodeSettingsJSON(b)
+		if err != nil {
+			return nil, false
+		}
+		flattenJSON("", v, out)
+		return out, true
+	case ".toml":
+		arrays := map[string]int{}
+		for _, s := range parseTOML(b).sections {
+			prefix := s.name
+			if s.array {
+				prefix = fmt.Sprintf("%s[%d]", s.name, arrays[s.name])
+				arrays[s.name]++
+			}
+			keys := 0
+			for _, e := range s.entries {
+				if e.key == "" {
+					continue
+				}
+				keys++
+				out[joinKey(prefix, e.key)] = tomlValue(e)
+			}
+			if keys == 0 && s.header != "" {
+				out[prefix] = "{}"
+			}
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func flattenJSON(prefix string, v any, out map[string]string) {
+	if m, ok := v.(map[string]any); ok && (len(m) > 0 || prefix == "") {
+		for k, child := range m {
+			flattenJSON(joinKey(prefix, k), child, out)
+		}
+		return
+	}
+	if prefix == "" {
+		prefix = "(root)"
+	}
+	out[prefix] = jsonKey(v)
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// tomlValue is an entry's value with comments and line breaks dropped.
+func tomlValue(e *tomlEntry) string {
+	parts := []string{}
+	for i, line := range e.lines {
+		if i == 0 {
+			_, line, _ = cutTOMLKey(line)
+		}
+		if line = strings.TrimSpace(stripTOMLComment(line)); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func diffKeys(a, b map[string]string) []SettingsKeyDiff {
+	out := []SettingsKeyDiff{}
+	for k, va := range a {
+		vb, ok := b[k]
+		switch {
+		case !ok:
+			out = append(out, SettingsKeyDiff{Key: k, A: va, Op: "removed"})
+		case va != vb:
+			out = append(out, SettingsKeyDiff{Key: k, A: va, B: vb, Op: "changed"})
+		}
+	}
+	for k, vb := range b {
+		if _, ok := a[k]; !ok {
+			out = append(out, SettingsKeyDiff{Key: k, B: vb, Op: "added"})
+		}
+	}
+	slices.SortFunc(out, func(x, y SettingsKeyDiff) int { return strings.Compare(x.Key, y.Key) })
+	return out
+}
+
+// maxDiffCells bounds the line diff table; larger files list every line
+// of each side as removed and added.
+const maxDiffCells = 4_000_000
+
+// diffLines returns the lines removed from a and added in b, by longest
+// common subsequence.
+func diffLines(a, b string) []string {
+	la, lb := splitDiffLines(a), splitDiffLines(b)
+	out := []string{}
+	if len(la)*len(lb) > maxDiffCells {
+		for _, l := range la {
+			out = append(out, "- "+l)
+		}
+		for _, l := range lb {
+			out = append(out, "+ "+l)
+		}
+		return out
+	}
+	// lcs[i][j] is the common subsequence length of la[i:] and lb[j:].
+	lcs := make([][]int, len(la)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(lb)+1)
+	}
+	for i := len(la) - 1; i >= 0; i-- {
+		for j := len(lb) - 1; j >= 0; j-- {
+			if la[i] == lb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(la) || j < len(lb) {
+		switch {
+		case i < len(la) && j < len(lb) && la[i] == lb[j]:
+			i++
+			j++
+		case i < len(la) && (j == len(lb) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+la[i])
+			i++
+		default:
+			out = append(out, "+ "+lb[j])
+			j++
+		}
+	}
+	return out
+}
+
+func splitDiffLines(s string) []string {
+	s = strings.TrimRight(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestDiffSettingsComparesKeysAndFallsBackToLines(t *testing.T) {
+	m := newTestManager(t)
+	source, _, err := m.EnsureProfile(store.ToolCodex, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, _, err := m.EnsureProfile(store.ToolCodex, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(dir, rel, body string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(source.Dir, "config.toml", "model = \"o3\"\n\n[tui]\nnotifications = true\n")
+	write(source.Dir, "AGENTS.md", "be brief\nuse tabs\n")
+	write(source.Dir, "prompts/review.md", "review")
+	if _, err := m.SnapshotSettings(store.ToolCodex, "source", "team"); err != nil {
+		t.Fatal(err)
+	}
+	write(work.Dir, "config.toml", "# mine\nmodel = \"gpt-5\"   # faster\n\n[tui]\nnotifications = true\n\n[sandbox_workspace_write]\nnetwork_access = true\n")
+	write(work.Dir, "AGENTS.md", "be brief\nuse spaces\n")
+	write(work.Dir, "auth.json", `{"token":"secret"}`)
+
+	diff, err := m.DiffSettings(store.ToolCodex, "team", "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.A != "preset team" || diff.B != "profile work" {
+		t.Fatalf("unexpected labels %q, %q", diff.A, diff.B)
+	}
+	files := map[string]SettingsFileDiff{}
+	for _, f := range diff.Files {
+		files[f.Path] = f
+	}
+	if _, ok := files["auth.json"]; ok {
+		t.Fatalf("credential files must never be diffed")
+	}
+	wantKeys := []SettingsKeyDiff{
+		{Key: "model", A: `"o3"`, B: `"gpt-5"`, Op: "changed"},
+		{Key: "sandbox_workspace_write.network_access", B: "true", Op: "added"},
+	}
+	if got := files["config.toml"].Keys; !reflect.DeepEqual(got, wantKeys) {
+		t.Fatalf("config.toml keys: got %+v", got)
+	}
+	if got := files["AGENTS.md"].Lines; !reflect.DeepEqual(got, []string{"- use tabs", "+ use spaces"}) {
+		t.Fatalf("AGENTS.md lines: got %q", got)
+	}
+	if f := files["prompts/review.md"]; f.Status != "removed" {
+		t.Fatalf("expected prompts/review.md to be reported removed, got %+v", f)
+	}
+
+	if _, _, err := m.EnsureProfile(store.ToolCodex, "team"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.DiffSettings(store.ToolCodex, "team", "work"); err == nil || !strings.Contains(err.Error(), "preset:team") {
+		t.Fatalf("expected an ambiguous name to be refused, got %v", err)
+	}
+	same, err := m.DiffSettings(store.ToolCodex, "preset:team", "profile:source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(same.Files) != 0 {
+		t.Fatalf("expected a fresh snapshot to match its source, got %+v", same.Files)
+	}
+}
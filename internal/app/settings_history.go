@@ -0,0 +1,212 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// presetHistoryDir is where a preset's revisions are kept, one numbered
+// directory each. It sits outside presets/ so no revision is ever applied.
+func (m *Manager) presetHistoryDir(tool store.Tool, preset string) (string, error) {
+	if err := store.ValidatePresetName(preset); err != nil {
+		return "", err
+	}
+	return filepath.Join(m.Root(), "preset-history", string(tool), preset), nil
+}
+
+func (m *Manager) presetRevisionDir(tool store.Tool, preset string, rev int) (string, error) {
+	dir, err := m.presetHistoryDir(tool, preset)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strconv.Itoa(rev)), nil
+}
+
+// presetContentHash fingerprints the files scope covers in dir, as they are
+// on disk.
+func presetContentHash(dir string, scope SettingsScope) (string, error) {
+	scope.Merge = ""
+	paths := slices.Clone(scope.Paths)
+	slices.Sort(paths)
+	h := sha256.New()
+	for _, rel := range paths {
+		sum, err := scope.digest(dir, rel)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", rel, sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// maxPresetRevisions is how many revisions a preset keeps. Older ones are
+// dropped, files included, as new ones are recorded.
+const maxPresetRevisions = 50
+
+// recordPresetRevision copies what scope covers in presetDir into p's next
+// revision and appends it, dropping revisions beyond maxPresetRevisions.
+// Content matching the latest revision returns that one instead. p must come from state held under the lock, so no two callers can
+// take the same revision number.
+func (m *Manager) recordPresetRevision(p *store.SettingsPreset, tool store.Tool, preset, presetDir string, scope SettingsScope, source string, now time.Time) (store.PresetRevision, error) {
+	hash, err := presetContentHash(presetDir, scope)
+	if err != nil {
+		return store.PresetRevision{}, err
+	}
+	next := 1
+	if latest := p.LatestRevision(); latest != nil {
+		if latest.Hash == hash {
+			return *latest, nil
+		}
+		next = latest.Rev + 1
+	}
+	revDir, err := m.presetRevisionDir(tool, preset, next)
+	if err != nil {
+		return store.PresetRevision{}, err
+	}
+	// A directory left behind by an interrupted snapshot is stale.
+	if err := os.RemoveAll(revDir); err != nil {
+		return store.PresetRevision{}, err
+	}
+	if err := os.MkdirAll(revDir, 0o755); err != nil {
+		return store.PresetRevision{}, err
+	}
+	for _, rel := range scope.Paths {
+		if err := scope.copyPath(presetDir, revDir, rel); err != nil {
+			return store.PresetRevision{}, fmt.Errorf("save revision %d of %s/%s: %w", next, tool, preset, err)
+		}
+	}
+	rev := store.PresetRevision{
+		Rev:       next,
+		Source:    source,
+		Hash:      hash,
+		Paths:     slices.Clone(scope.Paths),
+		CreatedAt: now,
+	}
+	p.Revisions = append(p.Revisions, rev)
+	if drop := len(p.Revisions) - maxPresetRevisions; drop > 0 {
+		for _, old := range p.Revisions[:drop] {
+			oldDir, err := m.presetRevisionDir(tool, preset, old.Rev)
+			if err != nil {
+				return store.PresetRevision{}, err
+			}
+			if err := os.RemoveAll(oldDir); err != nil {
+				return store.PresetRevision{}, err
+			}
+		}
+		p.Revisions = slices.Delete(p.Revisions, 0, drop)
+	}
+	return rev, nil
+}
+
+// SettingsPresetLog returns a preset's revisions, oldest first.
+func (m *Manager) SettingsPresetLog(tool store.Tool, preset string) ([]store.PresetRevision, error) {
+	st, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	_, p := store.FindSettingsPreset(st, tool, preset)
+	if p == nil {
+		return nil, fmt.Errorf("settings preset not found: %s/%s", tool, preset)
+	}
+	return p.Revisions, nil
+}
+
+// RollbackSettingsPreset restores a preset's files to revision rev. The
+// restore is itself recorded as a new revision, so it can be undone too.
+// Profiles synced to the preset pick it up on their next launch.
+func (m *Manager) RollbackSettingsPreset(tool store.Tool, preset string, rev int) (store.PresetRevision, error) {
+	var saved store.PresetRevision
+	err := m.store.Update(func(st *store.State) error {
+		_, p := store.FindSettingsPreset(st, tool, preset)
+		if p == nil {
+			return fmt.Errorf("settings preset not found: %s/%s", tool, preset)
+		}
+		target := p.FindRevision(rev)
+		if target == nil {
+			return fmt.Errorf("settings preset %s/%s has no revision %d", tool, preset, rev)
+		}
+		revDir, err := m.presetRevisionDir(tool, preset, rev)
+		if err != nil {
+			return err
+		}
+		if !dirExists(revDir) {
+			return fmt.Errorf("files for revision %d of %s/%s are missing", rev, tool, preset)
+		}
+		presetDir, err := m.expectedPresetDir(tool, preset)
+		if err != nil {
+			return err
+		}
+		scope, err := captureScope(tool, p)
+		if err != nil {
+			return err
+		}
+
+		for _, rel := range p.Paths {
+			if !slices.Contains(target.Paths, rel) {
+				if err := os.RemoveAll(filepath.Join(presetDir, filepath.FromSlash(rel))); err != nil {
+					return err
+				}
+			}
+		}
+		scope.Paths = slices.Clone(target.Paths)
+		for _, rel := range scope.Paths {
+			if err := scope.copyPath(revDir, presetDir, rel); err != nil {
+				return fmt.Errorf("restore settings path %q: %w", rel, err)
+			}
+		}
+
+		now := time.Now().UTC()
+		if saved, err = m.recordPresetRevision(p, tool, preset, presetDir, scope, fmt.Sprintf("rollback to %d", rev), now); err != nil {
+			return err
+		}
+		p.Paths = scope.Paths
+		p.UpdatedAt = now
+		return nil
+	})
+	return saved, err
+}
+
+// parsePresetRevisionRef splits "name@rev". rev is 0 without a suffix.
+func parsePresetRevisionRef(ref string) (string, int, error) {
+	name, raw, found := strings.Cut(ref, "@")
+	if !found {
+		return ref, 0, nil
+	}
+	rev, err := strconv.Atoi(raw)
+	if err != nil || rev < 1 {
+		return "", 0, fmt.Errorf("invalid preset revision %q (expected <preset>@<number>)", ref)
+	}
+	return name, rev, nil
+}
+
+// revisionSide reads revision rev of a preset for DiffSettings. Unlike the
+// live preset, a revision is compared on its own, not layered over parents.
+func (m *Manager) revisionSide(st *store.State, tool store.Tool, preset string, rev int) (settingsSide, error) {
+	_, p := store.FindSettingsPreset(st, tool, preset)
+	if p == nil {
+		return settingsSide{}, fmt.Errorf("settings preset not found: %s/%s", tool, preset)
+	}
+	r := p.FindRevision(rev)
+	if r == nil {
+		return settingsSide{}, fmt.Errorf("settings preset %s/%s has no revision %d", tool, preset, rev)
+	}
+	dir, err := m.presetRevisionDir(tool, preset, rev)
+	if err != nil {
+		return settingsSide{}, err
+	}
+	scope, err := captureScope(tool, nil)
+	if err != nil {
+		return settingsSide{}, err
+	}
+	scope.Paths = slices.Clone(r.Paths)
+	return settingsSide{label: fmt.Sprintf("preset %s@%d", preset, rev), dir: dir, scope: scope}, nil
+}
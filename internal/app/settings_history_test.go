@@ -0,0 +1,133 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestSettingsPresetRevisionsAndRollback(t *testing.T) {
+	m := newTestManager(t)
+	good, _, err := m.EnsureProfile(store.ToolCodex, "good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong, _, err := m.EnsureProfile(store.ToolCodex, "wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(dir, rel, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	presetDir := filepath.Join(m.Root(), "presets", "codex", "team")
+
+	// A preset written before history existed keeps its content as rev 1.
+	if err := os.MkdirAll(presetDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write(presetDir, "config.toml", "model = \"legacy\"\n")
+	write(good.Dir, "config.toml", "model = \"o3\"\n")
+	write(good.Dir, "AGENTS.md", "team rules")
+	if _, err := m.SnapshotSettings(store.ToolCodex, "good", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SnapshotSettings(store.ToolCodex, "good", "team"); err != nil {
+		t.Fatal(err)
+	}
+	write(wrong.Dir, "config.toml", "model = \"oops\"\n")
+	if _, err := m.SnapshotSettings(store.ToolCodex, "wrong", "team"); err != nil {
+		t.Fatal(err)
+	}
+
+	revs, err := m.SettingsPresetLog(store.ToolCodex, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 3 {
+		t.Fatalf("expected legacy, good and wrong revisions with the repeat skipped, got %+v", revs)
+	}
+	if revs[0].Source != "" || revs[1].Source != "good" || revs[2].Source != "wrong" || revs[1].Hash == revs[2].Hash {
+		t.Fatalf("unexpected revisions %+v", revs)
+	}
+	if _, err := os.Stat(filepath.Join(presetDir, "AGENTS.md")); !os.IsNotExist(err) {
+		t.Fatalf("the bad snapshot should have dropped AGENTS.md, stat err=%v", err)
+	}
+
+	diff, err := m.DiffSettings(store.ToolCodex, "team@2", "team@3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.A != "preset team@2" || len(diff.Files) != 2 {
+		t.Fatalf("expected config.toml and AGENTS.md to differ between revisions, got %+v", diff)
+	}
+
+	saved, err := m.RollbackSettingsPreset(store.ToolCodex, "team", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Rev != 4 || saved.Source != "rollback to 2" || saved.Hash != revs[1].Hash {
+		t.Fatalf("unexpected rollback revision %+v", saved)
+	}
+	for rel, want := range map[string]string{"config.toml": "model = \"o3\"\n", "AGENTS.md": "team rules"} {
+		if got, err := os.ReadFile(filepath.Join(presetDir, rel)); err != nil || string(got) != want {
+			t.Fatalf("%s: expected %q after rollback, got %q (%v)", rel, want, got, err)
+		}
+	}
+	if _, err := m.RollbackSettingsPreset(store.ToolCodex, "team", 9); err == nil {
+		t.Fatalf("expected an unknown revision to be refused")
+	}
+
+	if err := m.RenameSettingsPreset(store.ToolCodex, "team", "base"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.DiffSettings(store.ToolCodex, "base@1", "base"); err != nil {
+		t.Fatalf("expected revisions to follow a rename: %v", err)
+	}
+	if err := m.DeleteSettingsPreset(store.ToolCodex, "base"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(m.Root(), "preset-history", "codex", "base")); !os.IsNotExist(err) {
+		t.Fatalf("expected deleting a preset to drop its history, stat err=%v", err)
+	}
+}
+
+func TestSettingsPresetRevisionsAreCapped(t *testing.T) {
+	m := newTestManager(t)
+	source, _, err := m.EnsureProfile(store.ToolCodex, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range maxPresetRevisions + 2 {
+		body := fmt.Sprintf("model = \"v%d\"\n", i)
+		if err := os.WriteFile(filepath.Join(source.Dir, "config.toml"), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.SnapshotSettings(store.ToolCodex, "source", "team"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revs, err := m.SettingsPresetLog(store.ToolCodex, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != maxPresetRevisions || revs[0].Rev != 3 || revs[len(revs)-1].Rev != maxPresetRevisions+2 {
+		t.Fatalf("expected revisions 3 through %d, got %d starting at %d", maxPresetRevisions+2, len(revs), revs[0].Rev)
+	}
+	historyDir := filepath.Join(m.Root(), "preset-history", "codex", "team")
+	for rev, want := range map[int]bool{1: false, 2: false, 3: true} {
+		if _, err := os.Stat(filepath.Join(historyDir, strconv.Itoa(rev))); (err == nil) != want {
+			t.Fatalf("revision %d files: want kept=%v, stat err=%v", rev, want, err)
+		}
+	}
+	if _, err := m.RollbackSettingsPreset(store.ToolCodex, "team", 1); err == nil {
+		t.Fatal("expected rollback to a dropped revision to fail")
+	}
+}
@@ -0,0 +1,219 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// presetChain returns preset and its ancestors, root first. It fails when
+// the parent links loop.
+func presetChain(st *store.State, tool store.Tool, preset string) ([]string, error) {
+	chain := []string{preset}
+	for name := preset; ; {
+		_, p := store.FindSettingsPreset(st, tool, name)
+		if p == nil || p.Parent == "" {
+			break
+		}
+		if slices.Contains(chain, p.Parent) {
+			return nil, fmt.Errorf("settings preset inheritance cycle: %s -> %s", strings.Join(chain, " -> "), p.Parent)
+		}
+		chain = append(chain, p.Parent)
+		name = p.Parent
+	}
+	slices.Reverse(chain)
+	return chain, nil
+}
+
+// effectivePreset resolves what applying preset writes. A preset without a
+// parent is read in place. Otherwise its chain is layered root first into a
+// temporary directory, each preset applied onto its parents' result per its
+// own strategy, and cleanup removes it. A preset that lacks a path inherits
+// its parent's copy.
+func (m *Manager) effectivePreset(st *store.State, tool store.Tool, preset string) (dir string, scope SettingsScope, cleanup func(), err error) {
+	cleanup = func() {}
+	chain, err := presetChain(st, tool, preset)
+	if err != nil {
+		return "", SettingsScope{}, cleanup, err
+	}
+	if len(chain) == 1 {
+		dir, scope, err = m.presetScope(st, tool, preset)
+		return dir, scope, cleanup, err
+	}
+
+	tmp, err := os.MkdirTemp("", "profilex-preset-")
+	if err != nil {
+		return "", SettingsScope{}, cleanup, err
+	}
+	cleanup = func() { _ = os.RemoveAll(tmp) }
+	paths := []string{}
+	for i, name := range chain {
+		layerDir, err := m.expectedPresetDir(tool, name)
+		if err != nil {
+			cleanup()
+			return "", SettingsScope{}, func() {}, err
+		}
+		// A child may extend its parent before it has files of its own.
+		if !dirExists(layerDir) && i < len(chain)-1 {
+			cleanup()
+			return "", SettingsScope{}, func() {}, fmt.Errorf("settings preset not found: %s/%s (parent of %s)", tool, name, chain[i+1])
+		}
+		_, p := store.FindSettingsPreset(st, tool, name)
+		layer, err := applyScope(tool, p, layerDir)
+		if err != nil {
+			cleanup()
+			return "", SettingsScope{}, func() {}, err
+		}
+		for _, rel := range layer.Paths {
+			if !slices.Contains(paths, rel) {
+				paths = append(paths, rel)
+			}
+			if _, err := os.Lstat(filepath.Join(layerDir, filepath.FromSlash(rel))); err != nil {
+				continue
+			}
+			if err := layer.applyPath(layerDir, tmp, rel); err != nil {
+				cleanup()
+				return "", SettingsScope{}, func() {}, fmt.Errorf("layer settings preset %s/%s: %w", tool, name, err)
+			}
+		}
+		scope = layer
+	}
+	scope.Paths = slices.DeleteFunc(paths, scope.skip)
+	return tmp, scope, cleanup, nil
+}
+
+// SettingsPresetChain returns a preset and its ancestors, root first.
+func (m *Manager) SettingsPresetChain(tool store.Tool, preset string) ([]string, error) {
+	st, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	return presetChain(st, tool, preset)
+}
+
+// SetSettingsPresetParent makes preset extend parent, or stand alone when
+// parent is empty, creating its state entry if needed. Bound profiles pick
+// the change up on their next sync.
+func (m *Manager) SetSettingsPresetParent(tool store.Tool, preset, parent string) error {
+	if err := store.ValidatePresetName(preset); err != nil {
+		return err
+	}
+	if _, err := settingsPathsForTool(tool); err != nil {
+		return err
+	}
+	if parent != "" {
+		if err := store.ValidatePresetName(parent); err != nil {
+			return err
+		}
+		if parent == preset {
+			return fmt.Errorf("settings preset %s/%s cannot extend itself", tool, preset)
+		}
+		parentDir, err := m.expectedPresetDir(tool, parent)
+		if err != nil {
+			return err
+		}
+		if !dirExists(parentDir) {
+			return fmt.Errorf("settings preset not found: %s/%s", tool, parent)
+		}
+	}
+	now := time.Now().UTC()
+	return m.store.Update(func(st *store.State) error {
+		_, p := store.FindSettingsPreset(st, tool, preset)
+		if p == nil {
+			st.SettingsPresets = append(st.SettingsPresets, store.SettingsPreset{Tool: tool, Name: preset, CreatedAt: now, UpdatedAt: now})
+			p = &st.SettingsPresets[len(st.SettingsPresets)-1]
+		}
+		p.Parent = parent
+		p.UpdatedAt = now
+		_, err := presetChain(st, tool, preset)
+		return err
+	})
+}
+
+// SettingsPresetView describes a preset's files: its own, or the effective
+// result of layering its parent chain.
+type SettingsPresetView struct {
+	Tool      store.Tool           `json:"tool"`
+	Name      string               `json:"name"`
+	Chain     []string             `json:"chain"`
+	Merge     store.MergeStrategy  `json:"merge"`
+	Effective bool                 `json:"effective"`
+	Files     []SettingsPresetFile `json:"files"`
+}
+
+// SettingsPresetFile is one path of a SettingsPresetView.
+type SettingsPresetFile struct {
+	Path string `json:"path"`
+	Dir  bool   `json:"dir,omitempty"`
+	// From lists the presets in the chain that provide the path.
+	From    []string `json:"from"`
+	Content string   `json:"content,omitempty"`
+}
+
+// ShowSettingsPreset reads a preset's files, or with effective set, what
+// its chain resolves to before being applied to a profile.
+func (m *Manager) ShowSettingsPreset(tool store.Tool, preset string, effective bool) (SettingsPresetView, error) {
+	st, err := m.Load()
+	if err != nil {
+		return SettingsPresetView{}, err
+	}
+	chain, err := presetChain(st, tool, preset)
+	if err != nil {
+		return SettingsPresetView{}, err
+	}
+	view := SettingsPresetView{Tool: tool, Name: preset, Chain: chain, Merge: store.MergeReplace, Effective: effective, Files: []SettingsPresetFile{}}
+	if _, p := store.FindSettingsPreset(st, tool, preset); p != nil && p.Merge != "" {
+		view.Merge = p.Merge
+	}
+
+	var dir string
+	var scope SettingsScope
+	sources := []string{preset}
+	if effective {
+		var cleanup func()
+		dir, scope, cleanup, err = m.effectivePreset(st, tool, preset)
+		defer cleanup()
+		sources = chain
+	} else {
+		ownDir, _ := m.expectedPresetDir(tool, preset)
+		if len(chain) > 1 && !dirExists(ownDir) {
+			return view, nil
+		}
+		dir, scope, err = m.presetScope(st, tool, preset)
+	}
+	if err != nil {
+		return view, err
+	}
+
+	for _, rel := range scope.Paths {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(rel)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return view, err
+		}
+		f := SettingsPresetFile{Path: rel, Dir: info.IsDir(), From: []string{}}
+		for _, name := range sources {
+			if srcDir, err := m.expectedPresetDir(tool, name); err == nil {
+				if _, err := os.Lstat(filepath.Join(srcDir, filepath.FromSlash(rel))); err == nil {
+					f.From = append(f.From, name)
+				}
+			}
+		}
+		if !f.Dir {
+			b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+			if err != nil {
+				return view, err
+			}
+			f.Content = string(b)
+		}
+		view.Files = append(view.Files, f)
+	}
+	return view, nil
+}
@@ -0,0 +1,448 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// mergeable reports whether rel is a settings file merge strategies apply
+// to. Everything else, directories included, is replaced.
+func mergeable(rel string) bool {
+	switch strings.ToLower(path.Ext(rel)) {
+	case ".json", ".toml":
+		return true
+	}
+	return false
+}
+
+// merges reports whether applying the scope merges rel instead of replacing
+// it.
+func (s SettingsScope) merges(rel string) bool {
+	return s.Merge != "" && s.Merge != store.MergeReplace && mergeable(rel)
+}
+
+// strategy is how rel is applied under the scope.
+func (s SettingsScope) strategy(rel string) store.MergeStrategy {
+	if s.merges(rel) {
+		return s.Merge
+	}
+	return store.MergeReplace
+}
+
+// render returns what applying the scope leaves at profileDir/rel when that
+// is a merge. ok is false when rel is copied as is instead. A preset that
+// lacks the file leaves the profile's copy unchanged.
+func (s SettingsScope) render(presetDir, profileDir, rel string) (out []byte, ok bool, err error) {
+	if s.skip(rel) || !s.merges(rel) {
+		return nil, false, nil
+	}
+	preset, hasPreset, err := readSettingsFile(filepath.Join(presetDir, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, false, err
+	}
+	local, hasLocal, err := readSettingsFile(filepath.Join(profileDir, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, false, err
+	}
+	switch {
+	case !hasPreset && !hasLocal:
+		return nil, false, nil
+	case !hasPreset:
+		return local, true, nil
+	}
+	out, err = mergeSettings(rel, local, preset, s.Merge)
+	if err != nil {
+		return nil, false, fmt.Errorf("merge %s: %w", rel, err)
+	}
+	return out, true, nil
+}
+
+// applyPath brings profileDir/rel in line with the preset, merging or
+// replacing per the scope.
+func (s SettingsScope) applyPath(presetDir, profileDir, rel string) error {
+	out, ok, err := s.render(presetDir, profileDir, rel)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return s.copyPath(presetDir, profileDir, rel)
+	}
+	dst := filepath.Join(profileDir, filepath.FromSlash(rel))
+	if cur, err := os.ReadFile(dst); err == nil && bytes.Equal(cur, out) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, out, 0o644)
+}
+
+// promotePath copies a profile's edits to rel into the preset. Files that
+// merge only carry over the keys the preset already sets, so the rest of
+// the profile's file stays out of the preset.
+func (s SettingsScope) promotePath(profileDir, presetDir, rel string) error {
+	if s.skip(rel) {
+		return nil
+	}
+	if s.merges(rel) {
+		dst := filepath.Join(presetDir, filepath.FromSlash(rel))
+		preset, hasPreset, err := readSettingsFile(dst)
+		if err != nil {
+			return err
+		}
+		local, hasLocal, err := readSettingsFile(filepath.Join(profileDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return err
+		}
+		if hasPreset && hasLocal {
+			out, err := projectSettings(rel, local, preset)
+			if err != nil {
+				return fmt.Errorf("merge %s: %w", rel, err)
+			}
+			return os.WriteFile(dst, out, 0o644)
+		}
+	}
+	return s.copyPath(profileDir, presetDir, rel)
+}
+
+// desiredDigest fingerprints what applyPath would leave at profileDir/rel.
+func (s SettingsScope) desiredDigest(presetDir, profileDir, rel string) (string, error) {
+	out, ok, err := s.render(presetDir, profileDir, rel)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return s.digest(presetDir, rel)
+	}
+	return digestBytes(canonicalSettings(rel, out)), nil
+}
+
+// readSettingsFile reads a regular file. ok is false when path is missing
+// or a directory.
+func readSettingsFile(path string) (b []byte, ok bool, err error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil || info.IsDir() {
+		return nil, false, err
+	}
+	b, err = os.ReadFile(path)
+	return b, err == nil, err
+}
+
+// SettingsPreview is what applying a preset would leave at one path.
+type SettingsPreview struct {
+	Path     string              `json:"path"`
+	Strategy store.MergeStrategy `json:"strategy"`
+	Dir      bool                `json:"dir,omitempty"`
+	// Removed is set when the preset lacks the path and applying it would
+	// delete the profile's copy.
+	Removed bool `json:"removed,omitempty"`
+	Changed bool `json:"changed"`
+	// Content is the resulting file, empty for directories and removals.
+	Content string `json:"content,omitempty"`
+}
+
+// PreviewSettingsPreset reports what ApplySettingsPreset would do to a
+// profile without writing anything.
+func (m *Manager) PreviewSettingsPreset(tool store.Tool, preset, profileName string) ([]SettingsPreview, error) {
+	st, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	profileDir, _, err := m.resolveSettingsProfileDir(st, tool, profileName)
+	if err != nil {
+		return nil, err
+	}
+	presetDir, scope, cleanup, err := m.effectivePreset(st, tool, preset)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+	out := []SettingsPreview{}
+	for _, rel := range scope.Paths {
+		want, err := scope.desiredDigest(presetDir, profileDir, rel)
+		if err != nil {
+			return nil, err
+		}
+		have, err := scope.digest(profileDir, rel)
+		if err != nil {
+			return nil, err
+		}
+		p := SettingsPreview{Path: rel, Strategy: scope.strategy(rel), Changed: want != have}
+		content, merged, err := scope.render(presetDir, profileDir, rel)
+		if err != nil {
+			return nil, err
+		}
+		if !merged {
+			info, err := os.Stat(filepath.Join(presetDir, filepath.FromSlash(rel)))
+			switch {
+			case os.IsNotExist(err):
+				if have == "" {
+					continue
+				}
+				p.Removed = true
+			case err != nil:
+				return nil, err
+			case info.IsDir():
+				p.Dir = true
+			default:
+				if content, err = os.ReadFile(filepath.Join(presetDir, filepath.FromSlash(rel))); err != nil {
+					return nil, err
+				}
+			}
+		}
+		p.Content = string(content)
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// SetSettingsPresetMerge sets how applying a preset combines its JSON and
+// TOML files with a profile's, creating its state entry if needed.
+func (m *Manager) SetSettingsPresetMerge(tool store.Tool, preset string, strategy store.MergeStrategy) error {
+	if err := store.ValidatePresetName(preset); err != nil {
+		return err
+	}
+	if _, err := settingsPathsForTool(tool); err != nil {
+		return err
+	}
+	if strategy == store.MergeReplace {
+		strategy = ""
+	}
+	now := time.Now().UTC()
+	return m.store.Update(func(st *store.State) error {
+		_, p := store.FindSettingsPreset(st, tool, preset)
+		if p == nil {
+			st.SettingsPresets = append(st.SettingsPresets, store.SettingsPreset{Tool: tool, Name: preset, CreatedAt: now, UpdatedAt: now})
+			p = &st.SettingsPresets[len(st.SettingsPresets)-1]
+		}
+		p.Merge = strategy
+		return nil
+	})
+}
+
+// mergeSettings combines a profile's copy of a settings file with the
+// preset's under strategy, the preset winning on conflicting values.
+func mergeSettings(rel string, local, preset []byte, strategy store.MergeStrategy) ([]byte, error) {
+	union := strategy == store.MergeUnion
+	switch strings.ToLower(path.Ext(rel)) {
+	case ".json":
+		lv, err := decodeSettingsJSON(local)
+		if err != nil {
+			return nil, fmt.Errorf("profile copy of %s: %w", rel, err)
+		}
+		pv, err := decodeSettingsJSON(preset)
+		if err != nil {
+			return nil, fmt.Errorf("preset copy of %s: %w", rel, err)
+		}
+		return encodeSettingsJSON(mergeJSON(lv, pv, union))
+	case ".toml":
+		lv, err := decodeTOML(local)
+		if err != nil {
+			return nil, fmt.Errorf("profile copy of %s: %w", rel, err)
+		}
+		pv, err := decodeTOML(preset)
+		if err != nil {
+			return nil, fmt.Errorf("preset copy of %s: %w", rel, err)
+		}
+		out := mergeTOML(parseTOML(local), parseTOML(preset), union).bytes()
+		return checkTOMLMerge(rel, out, mergeJSON(lv, pv, union).(map[string]any))
+	}
+	return preset, nil
+}
+
+// projectSettings takes local's values for the keys the preset sets, so
+// promoting edits into a merging preset does not pull in the rest of the
+// profile's file.
+func projectSettings(rel string, local, preset []byte) ([]byte, error) {
+	switch strings.ToLower(path.Ext(rel)) {
+	case ".json":
+		lv, err := decodeSettingsJSON(local)
+		if err != nil {
+			return nil, fmt.Errorf("profile copy of %s: %w", rel, err)
+		}
+		pv, err := decodeSettingsJSON(preset)
+		if err != nil {
+			return nil, fmt.Errorf("preset copy of %s: %w", rel, err)
+		}
+		return encodeSettingsJSON(projectJSON(lv, pv))
+	case ".toml":
+		lv, err := decodeTOML(local)
+		if err != nil {
+			return nil, fmt.Errorf("profile copy of %s: %w", rel, err)
+		}
+		pv, err := decodeTOML(preset)
+		if err != nil {
+			return nil, fmt.Errorf("preset copy of %s: %w", rel, err)
+		}
+		out := projectTOML(parseTOML(local), parseTOML(preset)).bytes()
+		return checkTOMLMerge(rel, out, projectJSON(lv, pv).(map[string]any))
+	}
+	return local, nil
+}
+
+// checkTOMLMerge makes sure a line-level TOML merge decodes to the settings
+// it should. When the profile and the preset write a table in different
+// forms, such as inline on one side and under a [header] on the other, the
+// lines cannot be spliced; that is an error rather than a rewrite that
+// would drop the profile's comments.
+func checkTOMLMerge(rel string, out []byte, want map[string]any) ([]byte, error) {
+	got, err := decodeTOML(out)
+	if err == nil && reflect.DeepEqual(got, want) {
+		return out, nil
+	}
+	key := parseTOML(out).redefined()
+	if err == nil {
+		key = firstDifferentKey("", got, want)
+	}
+	if key == "" {
+		return nil, fmt.Errorf("cannot merge %s: the profile and the preset write a table in different forms; write it the same way in both, or use the replace strategy", rel)
+	}
+	return nil, fmt.Errorf("cannot merge %s: the profile and the preset write %s in different forms; write it the same way in both, or use the replace strategy", rel, key)
+}
+
+// firstDifferentKey names the first key, in sorted order, where got and
+// want differ.
+func firstDifferentKey(prefix string, got, want map[string]any) string {
+	keys := slices.Sorted(maps.Keys(want))
+	for k := range got {
+		if _, ok := want[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range keys {
+		if reflect.DeepEqual(got[k], want[k]) {
+			continue
+		}
+		name := joinKey(prefix, joinTOMLKey([]string{k}))
+		gm, gok := got[k].(map[string]any)
+		wm, wok := want[k].(map[string]any)
+		if gok && wok {
+			return firstDifferentKey(name, gm, wm)
+		}
+		return name
+	}
+	return ""
+}
+
+// decodeTOML reads a TOML settings file into the shape decodeSettingsJSON
+// produces, so both merge the same way.
+func decodeTOML(b []byte) (map[string]any, error) {
+	doc := map[string]any{}
+	if err := toml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// canonicalSettings normalizes JSON formatting and key order, which tools
+// are free to change when they rewrite their settings, so such rewrites do
+// not read as edits. Other content is returned as is.
+func canonicalSettings(rel string, b []byte) []byte {
+	if strings.ToLower(path.Ext(rel)) != ".json" {
+		return b
+	}
+	v, err := decodeSettingsJSON(b)
+	if err != nil {
+		return b
+	}
+	out, err := encodeSettingsJSON(v)
+	if err != nil {
+		return b
+	}
+	return out
+}
+
+func decodeSettingsJSON(b []byte) (any, error) {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return map[string]any{}, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return v, nil
+}
+
+func encodeSettingsJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func mergeJSON(local, preset any, union bool) any {
+	lm, lok := local.(map[string]any)
+	pm, pok := preset.(map[string]any)
+	if lok && pok {
+		out := make(map[string]any, len(lm)+len(pm))
+		for k, v := range lm {
+			out[k] = v
+		}
+		for k, pv := range pm {
+			if lv, ok := lm[k]; ok {
+				out[k] = mergeJSON(lv, pv, union)
+			} else {
+				out[k] = pv
+			}
+		}
+		return out
+	}
+	la, lok := local.([]any)
+	pa, pok := preset.([]any)
+	if union && lok && pok {
+		out := append([]any{}, la...)
+		seen := map[string]bool{}
+		for _, v := range la {
+			seen[jsonKey(v)] = true
+		}
+		for _, v := range pa {
+			if k := jsonKey(v); !seen[k] {
+				seen[k] = true
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	return preset
+}
+
+func projectJSON(local, preset any) any {
+	lm, lok := local.(map[string]any)
+	pm, pok := preset.(map[string]any)
+	if !lok || !pok {
+		return local
+	}
+	out := map[string]any{}
+	for k, pv := range pm {
+		if lv, ok := lm[k]; ok {
+			out[k] = projectJSON(lv, pv)
+		}
+	}
+	return out
+}
+
+func jsonKey(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,378 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestMergeSettingsJSON(t *testing.T) {
+	local := []byte(`{"model":"opus","env":{"A":"1"},"permissions":{"allow":["Read","Bash(ls)"],"deny":["WebFetch"]}}`)
+	preset := []byte(`{"env":{"B":"2"},"permissions":{"allow":["Read","Edit"]},"theme":"dark"}`)
+
+	decode := func(b []byte) map[string]any {
+		t.Helper()
+		var v map[string]any
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Fatalf("invalid JSON %s: %v", b, err)
+		}
+		return v
+	}
+
+	out, err := mergeSettings("settings.json", local, preset, store.MergeDeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decode(out)
+	want := decode([]byte(`{"model":"opus","env":{"A":"1","B":"2"},"permissions":{"allow":["Read","Edit"],"deny":["WebFetch"]},"theme":"dark"}`))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("merge: got %s", out)
+	}
+
+	out, err = mergeSettings("settings.json", local, preset, store.MergeUnion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allow := decode(out)["permissions"].(map[string]any)["allow"]
+	if !reflect.DeepEqual(allow, []any{"Read", "Bash(ls)", "Edit"}) {
+		t.Fatalf("union: expected local order plus preset additions, got %v", allow)
+	}
+
+	if _, err := mergeSettings("settings.json", []byte("{not json"), preset, store.MergeDeep); err == nil {
+		t.Fatalf("expected invalid profile JSON to be reported")
+	}
+}
+
+func TestMergeSettingsTOML(t *testing.T) {
+	local := strings.Join([]string{
+		`# my codex config`,
+		`model = "o3"`,
+		`approval_policy = "on-request"`,
+		``,
+		`[sandbox_workspace_write]`,
+		`writable_roots = ["/tmp"]`,
+		``,
+		`[mcp_servers.docs]`,
+		`command = "docs-mcp"`,
+		`args = [`,
+		`  "--port", # listen port`,
+		`  "9000",`,
+		`]`,
+		``,
+	}, "\n")
+	preset := strings.Join([]string{
+		`approval_policy = "never"`,
+		`sandbox_mode = "workspace-write"`,
+		``,
+		`[sandbox_workspace_write]`,
+		`writable_roots = ["/work"]`,
+		`network_access = true`,
+		``,
+		`[tui]`,
+		`notifications = true`,
+		``,
+	}, "\n")
+
+	out, err := mergeSettings("config.toml", []byte(local), []byte(preset), store.MergeDeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		`# my codex config`,
+		`model = "o3"`,
+		`approval_policy = "never"`,
+		`sandbox_mode = "workspace-write"`,
+		``,
+		`[sandbox_workspace_write]`,
+		`writable_roots = ["/work"]`,
+		`network_access = true`,
+		``,
+		`[mcp_servers.docs]`,
+		`command = "docs-mcp"`,
+		`args = [`,
+		`  "--port", # listen port`,
+		`  "9000",`,
+		`]`,
+		``,
+		`[tui]`,
+		`notifications = true`,
+		``,
+	}, "\n")
+	if string(out) != want {
+		t.Fatalf("merge:\n%s\nwant:\n%s", out, want)
+	}
+
+	out, err = mergeSettings("config.toml", []byte(local), []byte(preset), store.MergeUnion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `writable_roots = ["/tmp", "/work"]`) {
+		t.Fatalf("union: expected arrays to be unioned, got\n%s", out)
+	}
+}
+
+func TestMergeSettingsTOMLAcrossSpellings(t *testing.T) {
+	cases := []struct {
+		name, local, preset, want string
+	}{
+		{
+			name:   "dotted root key onto a table",
+			local:  "# mine\n[tui]\ntheme = \"dark\"\n",
+			preset: "tui.notifications = true\n",
+			want:   "# mine\n[tui]\ntheme = \"dark\"\nnotifications = true\n",
+		},
+		{
+			name:   "dotted root key replacing a table's key",
+			local:  "# mine\n[tui]\ntheme = \"dark\"\n",
+			preset: "tui.theme = \"light\"\n",
+			want:   "# mine\n[tui]\ntheme = \"light\"\n",
+		},
+		{
+			name:   "dotted key into a parent table",
+			local:  "model = \"o3\"\n\n# servers\n[mcp_servers]\n\n[mcp_servers.docs]\ncommand = \"docs-mcp\"\n",
+			preset: "mcp_servers.search.command = \"search-mcp\"\n",
+			want:   "model = \"o3\"\n\n# servers\n[mcp_servers]\nsearch.command = \"search-mcp\"\n\n[mcp_servers.docs]\ncommand = \"docs-mcp\"\n",
+		},
+		{
+			name:   "table onto dotted keys",
+			local:  "# mine\nmcp_servers.docs.command = \"docs-mcp\"\nmodel = \"o3\"\n",
+			preset: "[mcp_servers.docs]\nargs = [\"--quiet\"]\n",
+			want:   "# mine\nmcp_servers.docs.command = \"docs-mcp\"\nmcp_servers.docs.args = [\"--quiet\"]\nmodel = \"o3\"\n",
+		},
+		{
+			name:   "quoted keys spelled differently",
+			local:  "[profiles.\"a.b\"]\nmodel = \"o3\"\n",
+			preset: "[profiles.'a.b']\nmodel = \"gpt-5\"\n",
+			want:   "[profiles.\"a.b\"]\nmodel = \"gpt-5\"\n",
+		},
+		{
+			name:   "quoted dotted key is not a table",
+			local:  "[profiles]\n\"a.b\" = 1\n",
+			preset: "[profiles.a]\nb = 2\n",
+			want:   "[profiles]\n\"a.b\" = 1\n\n[profiles.a]\nb = 2\n",
+		},
+	}
+	for _, tc := range cases {
+		out, err := mergeSettings("config.toml", []byte(tc.local), []byte(tc.preset), store.MergeDeep)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if string(out) != tc.want {
+			t.Fatalf("%s: got\n%s\nwant:\n%s", tc.name, out, tc.want)
+		}
+	}
+
+	// A table written inline on one side and under a header on the other
+	// cannot be spliced without losing the profile's layout.
+	for _, tc := range []struct{ local, preset string }{
+		{"tui = { theme = \"dark\" }\n", "[tui]\nnotifications = true\n"},
+		{"# mine\n[tui]\ntheme = \"dark\"\n", "tui = { notifications = true }\n"},
+	} {
+		_, err := mergeSettings("config.toml", []byte(tc.local), []byte(tc.preset), store.MergeDeep)
+		if err == nil || !strings.Contains(err.Error(), "tui") {
+			t.Fatalf("expected a merge error naming tui for %q onto %q, got %v", tc.preset, tc.local, err)
+		}
+	}
+
+	out, err := projectSettings("config.toml", []byte("tui.theme = \"dark\"\nanimations = false\n"), []byte("[tui]\ntheme = \"light\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "[tui]\ntheme = \"dark\"\n" {
+		t.Fatalf("project: got\n%s", out)
+	}
+
+	if _, err := mergeSettings("config.toml", []byte("[tui]\n[tui]\n"), []byte("model = \"o3\"\n"), store.MergeDeep); err == nil {
+		t.Fatalf("expected invalid profile TOML to be reported")
+	}
+}
+
+func TestMergeStrategyPreviewApplyAndSync(t *testing.T) {
+	m := newTestManager(t)
+	source, _, err := m.EnsureProfile(store.ToolClaude, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _, err := m.EnsureProfile(store.ToolClaude, "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settingsPath := filepath.Join(target.Dir, "settings.json")
+	if err := os.WriteFile(filepath.Join(source.Dir, "settings.json"), []byte(`{"permissions":{"allow":["Edit"]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source.Dir, "CLAUDE.md"), []byte("team notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	original := `{"model":"opus","env":{"A":"1"}}`
+	if err := os.WriteFile(settingsPath, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SnapshotSettings(store.ToolClaude, "source", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSettingsPresetMerge(store.ToolClaude, "team", store.MergeDeep); err != nil {
+		t.Fatal(err)
+	}
+
+	previews, err := m.PreviewSettingsPreset(store.ToolClaude, "team", "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := map[string]SettingsPreview{}
+	for _, p := range previews {
+		byPath[p.Path] = p
+	}
+	if p := byPath["settings.json"]; p.Strategy != store.MergeDeep || !p.Changed || !strings.Contains(p.Content, `"model": "opus"`) || !strings.Contains(p.Content, `"Edit"`) {
+		t.Fatalf("unexpected settings.json preview: %+v", p)
+	}
+	if p := byPath["CLAUDE.md"]; p.Strategy != store.MergeReplace || p.Content != "team notes" {
+		t.Fatalf("unexpected CLAUDE.md preview: %+v", p)
+	}
+	if got, _ := os.ReadFile(settingsPath); string(got) != original {
+		t.Fatalf("preview must not write, got %s", got)
+	}
+
+	if err := m.SetSettingsSync(store.ToolClaude, "target", "team", true); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(settingsPath)
+	if !strings.Contains(string(got), `"model": "opus"`) || !strings.Contains(string(got), `"Edit"`) {
+		t.Fatalf("expected a merged settings.json, got %s", got)
+	}
+
+	// The tool adding its own key is not an edit to what the preset sets.
+	var v map[string]any
+	_ = json.Unmarshal(got, &v)
+	v["theme"] = "light"
+	b, _ := json.Marshal(v)
+	if err := os.WriteFile(settingsPath, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	profile := store.Profile{Tool: store.ToolClaude, Name: "target", Dir: target.Dir}
+	res, err := m.ApplySyncedSettings(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Changed() || len(res.Kept)+len(res.Conflicts) > 0 {
+		t.Fatalf("expected a profile-only key to stay in sync, got %+v", res)
+	}
+
+	// Editing a key the preset sets is promoted as that key alone.
+	v["permissions"] = map[string]any{"allow": []any{"Edit", "Read"}}
+	b, _ = json.Marshal(v)
+	if err := os.WriteFile(settingsPath, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSettingsSyncPolicy(store.ToolClaude, "target", store.SyncPromote); err != nil {
+		t.Fatal(err)
+	}
+	if res, err = m.ApplySyncedSettings(profile); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Promoted, []string{"settings.json"}) {
+		t.Fatalf("expected settings.json to be promoted, got %+v", res)
+	}
+	presetCopy, _ := os.ReadFile(filepath.Join(m.Root(), "presets", "claude", "team", "settings.json"))
+	var pv map[string]any
+	_ = json.Unmarshal(presetCopy, &pv)
+	if !reflect.DeepEqual(pv, map[string]any{"permissions": map[string]any{"allow": []any{"Edit", "Read"}}}) {
+		t.Fatalf("expected only preset keys to be promoted, got %s", presetCopy)
+	}
+}
+
+func TestSettingsPresetInheritanceLayersChainAndSyncsParentChanges(t *testing.T) {
+	m := newTestManager(t)
+	source, _, err := m.EnsureProfile(store.ToolClaude, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, _, err := m.EnsureProfile(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(dir, rel, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshot := func(name string) {
+		t.Helper()
+		if _, err := m.SnapshotSettings(store.ToolClaude, "source", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(source.Dir, "settings.json", `{"model":"sonnet","permissions":{"allow":["Read"]}}`)
+	write(source.Dir, "CLAUDE.md", "team notes")
+	snapshot("base")
+	if err := os.Remove(filepath.Join(source.Dir, "CLAUDE.md")); err != nil {
+		t.Fatal(err)
+	}
+	write(source.Dir, "settings.json", `{"permissions":{"allow":["Bash"]}}`)
+	snapshot("yolo")
+	if err := m.SetSettingsPresetMerge(store.ToolClaude, "yolo", store.MergeUnion); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSettingsPresetParent(store.ToolClaude, "yolo", "base"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSettingsPresetParent(store.ToolClaude, "base", "yolo"); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected a cycle to be refused, got %v", err)
+	}
+	if chain, _ := m.SettingsPresetChain(store.ToolClaude, "yolo"); !reflect.DeepEqual(chain, []string{"base", "yolo"}) {
+		t.Fatalf("unexpected chain %v", chain)
+	}
+
+	view, err := m.ShowSettingsPreset(store.ToolClaude, "yolo", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]SettingsPresetFile{}
+	for _, f := range view.Files {
+		files[f.Path] = f
+	}
+	if f := files["CLAUDE.md"]; f.Content != "team notes" || !reflect.DeepEqual(f.From, []string{"base"}) {
+		t.Fatalf("expected CLAUDE.md inherited from base, got %+v", f)
+	}
+	if f := files["settings.json"]; !strings.Contains(f.Content, `"model": "sonnet"`) || !strings.Contains(f.Content, `"Read",`) || !strings.Contains(f.Content, `"Bash"`) {
+		t.Fatalf("expected settings.json layered over base, got %+v", f)
+	}
+
+	if err := m.SetSettingsSync(store.ToolClaude, "work", "yolo", true); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(work.Dir, "CLAUDE.md")); string(got) != "team notes" {
+		t.Fatalf("expected the inherited file to be applied, got %q", got)
+	}
+
+	// Changing the parent reaches the profile bound to the child.
+	write(source.Dir, "settings.json", `{"model":"opus","permissions":{"allow":["Read"]}}`)
+	write(source.Dir, "CLAUDE.md", "team notes")
+	snapshot("base")
+	res, err := m.ApplySyncedSettings(store.Profile{Tool: store.ToolClaude, Name: "work", Dir: work.Dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Applied, []string{"settings.json"}) {
+		t.Fatalf("expected the parent change to be applied, got %+v", res)
+	}
+	if got, _ := os.ReadFile(filepath.Join(work.Dir, "settings.json")); !strings.Contains(string(got), `"model": "opus"`) || !strings.Contains(string(got), `"Bash"`) {
+		t.Fatalf("expected base's new model with yolo's permissions, got %s", got)
+	}
+
+	if err := m.DeleteSettingsPreset(store.ToolClaude, "base"); err == nil {
+		t.Fatalf("expected deleting a parent preset to be refused")
+	}
+	if err := m.RenameSettingsPreset(store.ToolClaude, "base", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if chain, _ := m.SettingsPresetChain(store.ToolClaude, "yolo"); !reflect.DeepEqual(chain, []string{"team", "yolo"}) {
+		t.Fatalf("expected rename to follow the parent link, got %v", chain)
+	}
+}
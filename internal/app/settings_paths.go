@@ -0,0 +1,213 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// SettingsScope is what a settings preset copies between a profile and the
+// preset directory. Paths are relative to the profile directory and use
+// forward slashes.
+type SettingsScope struct {
+	Paths []string `json:"paths"`
+	// Exclude is skipped inside Paths.
+	Exclude []string `json:"exclude,omitempty"`
+	// Blocked are the tool's credential files, which are never copied even
+	// when they sit inside an allowlisted directory.
+	Blocked []string `json:"blocked,omitempty"`
+	// Merge is how JSON and TOML files are combined on apply; see
+	// store.MergeStrategy.
+	Merge store.MergeStrategy `json:"merge,omitempty"`
+}
+
+// skip reports whether rel is excluded or a credential file.
+func (s SettingsScope) skip(rel string) bool {
+	rel = cleanSettingsPath(rel)
+	for _, ex := range s.Exclude {
+		if rel == ex || strings.HasPrefix(rel, ex+"/") {
+			return true
+		}
+		if ok, _ := path.Match(ex, rel); ok {
+			return true
+		}
+	}
+	for _, c := range s.Blocked {
+		if rel == c || path.Base(rel) == path.Base(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyPath replaces dstRoot/rel with srcRoot/rel, leaving skipped files on
+// both sides alone.
+func (s SettingsScope) copyPath(srcRoot, dstRoot, rel string) error {
+	if s.skip(rel) {
+		return nil
+	}
+	skip := func(sub string) bool { return s.skip(path.Join(rel, sub)) }
+	return syncPath(filepath.Join(srcRoot, filepath.FromSlash(rel)), filepath.Join(dstRoot, filepath.FromSlash(rel)), skip)
+}
+
+// digest fingerprints root/rel as copyPath would see it. Files that merge
+// are fingerprinted in canonical form.
+func (s SettingsScope) digest(root, rel string) (string, error) {
+	if s.skip(rel) {
+		return "", nil
+	}
+	if s.merges(rel) {
+		b, ok, err := readSettingsFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return digestBytes(canonicalSettings(rel, b)), nil
+		}
+	}
+	return settingsDigest(filepath.Join(root, filepath.FromSlash(rel)), func(sub string) bool { return s.skip(path.Join(rel, sub)) })
+}
+
+func cleanSettingsPath(rel string) string {
+	return path.Clean(filepath.ToSlash(strings.TrimSpace(rel)))
+}
+
+// validateSettingsPath refuses paths outside the profile directory and paths
+// that are, or contain, one of the tool's credential files.
+func validateSettingsPath(tool store.Tool, rel string) (string, error) {
+	clean := cleanSettingsPath(rel)
+	if clean == "." || clean == "" || !filepath.IsLocal(filepath.FromSlash(clean)) {
+		return "", fmt.Errorf("settings path %q must be a relative path inside the profile", rel)
+	}
+	for _, c := range credentialPaths(tool) {
+		if clean == c || strings.HasPrefix(c, clean+"/") {
+			return "", fmt.Errorf("settings path %q would capture credentials (%s)", rel, c)
+		}
+	}
+	return clean, nil
+}
+
+func credentialPaths(tool store.Tool) []string {
+	adapter, err := adapters.Get(tool)
+	if err != nil {
+		return nil
+	}
+	out := []string{}
+	for _, c := range adapter.CredentialFiles() {
+		out = append(out, cleanSettingsPath(c))
+	}
+	return out
+}
+
+// captureScope is what snapshotting into preset captures: the tool's default
+// allowlist plus the preset's includes, minus whole paths it excludes. A nil
+// preset gets the default.
+func captureScope(tool store.Tool, preset *store.SettingsPreset) (SettingsScope, error) {
+	defaults, err := settingsPathsForTool(tool)
+	if err != nil {
+		return SettingsScope{}, err
+	}
+	scope := SettingsScope{Paths: []string{}, Exclude: []string{}, Blocked: credentialPaths(tool)}
+	candidates := slices.Clone(defaults)
+	if preset != nil {
+		scope.Merge = preset.Merge
+		candidates = append(candidates, preset.Include...)
+		for _, ex := range preset.Exclude {
+			scope.Exclude = append(scope.Exclude, cleanSettingsPath(ex))
+		}
+	}
+	for _, rel := range candidates {
+		clean, err := validateSettingsPath(tool, rel)
+		if err != nil {
+			return SettingsScope{}, err
+		}
+		if !slices.Contains(scope.Paths, clean) && !scope.skip(clean) {
+			scope.Paths = append(scope.Paths, clean)
+		}
+	}
+	return scope, nil
+}
+
+// applyScope is what applying preset copies: the paths its last snapshot
+// captured, or for older presets that did not record them, the allowlisted
+// paths present in presetDir. Current excludes always apply.
+func applyScope(tool store.Tool, preset *store.SettingsPreset, presetDir string) (SettingsScope, error) {
+	scope, err := captureScope(tool, preset)
+	if err != nil {
+		return SettingsScope{}, err
+	}
+	if preset != nil && len(preset.Paths) > 0 {
+		scope.Paths = []string{}
+		for _, rel := range preset.Paths {
+			if clean, err := validateSettingsPath(tool, rel); err == nil && !scope.skip(clean) {
+				scope.Paths = append(scope.Paths, clean)
+			}
+		}
+		return scope, nil
+	}
+	present := []string{}
+	for _, rel := range scope.Paths {
+		if _, err := os.Stat(filepath.Join(presetDir, filepath.FromSlash(rel))); err == nil {
+			present = append(present, rel)
+		}
+	}
+	scope.Paths = present
+	return scope, nil
+}
+
+// SettingsPresetScope returns what snapshotting into a preset would capture.
+// The preset need not exist yet.
+func (m *Manager) SettingsPresetScope(tool store.Tool, preset string) (SettingsScope, error) {
+	st, err := m.Load()
+	if err != nil {
+		return SettingsScope{}, err
+	}
+	_, p := store.FindSettingsPreset(st, tool, preset)
+	return captureScope(tool, p)
+}
+
+// SetSettingsPresetPaths replaces a preset's include and exclude lists,
+// creating its state entry if needed. They take effect on the next snapshot;
+// excludes also apply to the next apply.
+func (m *Manager) SetSettingsPresetPaths(tool store.Tool, preset string, include, exclude []string) error {
+	if err := store.ValidatePresetName(preset); err != nil {
+		return err
+	}
+	cleanInclude := []string{}
+	for _, rel := range include {
+		clean, err := validateSettingsPath(tool, rel)
+		if err != nil {
+			return err
+		}
+		cleanInclude = append(cleanInclude, clean)
+	}
+	cleanExclude := []string{}
+	for _, ex := range exclude {
+		clean := cleanSettingsPath(ex)
+		if _, err := path.Match(clean, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", ex, err)
+		}
+		cleanExclude = append(cleanExclude, clean)
+	}
+	if _, err := settingsPathsForTool(tool); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	return m.store.Update(func(st *store.State) error {
+		_, p := store.FindSettingsPreset(st, tool, preset)
+		if p == nil {
+			st.SettingsPresets = append(st.SettingsPresets, store.SettingsPreset{Tool: tool, Name: preset, CreatedAt: now, UpdatedAt: now})
+			p = &st.SettingsPresets[len(st.SettingsPresets)-1]
+		}
+		p.Include = cleanInclude
+		p.Exclude = cleanExclude
+		return nil
+	})
+}
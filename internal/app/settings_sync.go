@@ -0,0 +1,302 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// SettingsSyncResult reports what ApplySyncedSettings did, by allowlisted
+// path.
+type SettingsSyncResult struct {
+	Preset string
+	Policy store.SyncEditPolicy
+	// Applied paths took preset changes the profile had not edited.
+	Applied []string
+	// Overwritten paths had local edits replaced by the preset.
+	Overwritten []string
+	// Kept paths hold local edits left in place.
+	Kept []string
+	// Promoted paths had local edits copied into the preset.
+	Promoted []string
+	// Conflicts are local edits not promoted because the preset changed too.
+	Conflicts []string
+}
+
+// Changed reports whether the profile or the preset was written to.
+func (r SettingsSyncResult) Changed() bool {
+	return len(r.Applied)+len(r.Overwritten)+len(r.Promoted) > 0
+}
+
+// SetSettingsSyncPolicy sets what syncing does with local edits to a bound
+// profile.
+func (m *Manager) SetSettingsSyncPolicy(tool store.Tool, profileName string, policy store.SyncEditPolicy) error {
+	st, err := m.Load()
+	if err != nil {
+		return err
+	}
+	_, canonicalProfile, err := m.resolveSettingsProfileDir(st, tool, profileName)
+	if err != nil {
+		return err
+	}
+	return m.store.Update(func(st *store.State) error {
+		idx, sync := store.FindSettingsSync(st, tool, canonicalProfile)
+		if sync == nil {
+			return fmt.Errorf("%s/%s is not synced to a settings preset", tool, canonicalProfile)
+		}
+		if policy == store.SyncOverwrite {
+			policy = ""
+		}
+		st.SettingsSync[idx].LocalEdits = policy
+		st.SettingsSync[idx].UpdatedAt = time.Now().UTC()
+		return nil
+	})
+}
+
+// ApplySyncedSettings brings a profile bound to a settings preset back in
+// line with it before launch. Each allowlisted path is compared with the
+// preset and with the baseline recorded at the last sync: preset changes are
+// copied in, and local edits are handled per the binding's policy. The
+// preset is compared as layered over its parents, so a parent's changes
+// reach every profile bound to a child. Unbound profiles return an empty
+// result.
+//
+// It all runs under the state lock, so two shims launching the same profile
+// cannot both apply against the same baselines.
+func (m *Manager) ApplySyncedSettings(profile store.Profile) (SettingsSyncResult, error) {
+	var res SettingsSyncResult
+	err := m.store.Update(func(st *store.State) error {
+		var err error
+		res, err = m.syncSettings(st, profile)
+		return err
+	})
+	if errors.Is(err, errSettingsInSync) {
+		return res, nil
+	}
+	return res, err
+}
+
+// errSettingsInSync is how syncSettings reports that state needs no write.
+var errSettingsInSync = errors.New("settings in sync")
+
+// syncSettings does ApplySyncedSettings' work against st, which the caller
+// holds locked and saves.
+func (m *Manager) syncSettings(st *store.State, profile store.Profile) (SettingsSyncResult, error) {
+	_, sync := store.FindSettingsSync(st, profile.Tool, profile.Name)
+	if sync == nil {
+		return SettingsSyncResult{}, errSettingsInSync
+	}
+	res := SettingsSyncResult{Preset: sync.Preset, Policy: sync.Policy()}
+	profileDir, err := m.validatedManagedProfileDir(profile)
+	if err != nil {
+		return res, err
+	}
+	// presetDir holds the effective preset, layered over its parents; edits
+	// are promoted into the preset's own directory.
+	presetDir, scope, cleanup, err := m.effectivePreset(st, profile.Tool, sync.Preset)
+	defer cleanup()
+	if err != nil {
+		return res, err
+	}
+	ownDir, err := m.expectedPresetDir(profile.Tool, sync.Preset)
+	if err != nil {
+		return res, err
+	}
+
+	baseline := map[string]string{}
+	presetBaseline := map[string]string{}
+	for _, rel := range scope.Paths {
+		presetSum, err := scope.digest(presetDir, rel)
+		if err != nil {
+			return res, err
+		}
+		localSum, err := scope.digest(profileDir, rel)
+		if err != nil {
+			return res, err
+		}
+		// What applying would leave behind: the preset's copy, or the
+		// profile's merged with it.
+		wantSum, err := scope.desiredDigest(presetDir, profileDir, rel)
+		if err != nil {
+			return res, err
+		}
+		base, ok := sync.Baseline[rel]
+		if !ok {
+			// Bindings made before baselines were recorded: assume the
+			// profile still holds what was last applied.
+			base = localSum
+		}
+		presetBase, ok := sync.PresetBaseline[rel]
+		if !ok {
+			presetBase = presetSum
+		}
+		baseline[rel] = base
+		presetBaseline[rel] = presetSum
+
+		switch {
+		case wantSum == localSum:
+			baseline[rel] = localSum
+		case localSum == base:
+			if err := scope.applyPath(presetDir, profileDir, rel); err != nil {
+				return res, fmt.Errorf("sync settings path %q: %w", rel, err)
+			}
+			res.Applied = append(res.Applied, rel)
+			baseline[rel] = wantSum
+		case res.Policy == store.SyncPreserve:
+			res.Kept = append(res.Kept, rel)
+		case res.Policy == store.SyncPromote && presetSum == presetBase:
+			if err := scope.promotePath(profileDir, ownDir, rel); err != nil {
+				return res, fmt.Errorf("promote settings path %q: %w", rel, err)
+			}
+			res.Promoted = append(res.Promoted, rel)
+			baseline[rel] = localSum
+		case res.Policy == store.SyncPromote:
+			res.Conflicts = append(res.Conflicts, rel)
+		default:
+			if err := scope.applyPath(presetDir, profileDir, rel); err != nil {
+				return res, fmt.Errorf("sync settings path %q: %w", rel, err)
+			}
+			res.Overwritten = append(res.Overwritten, rel)
+			baseline[rel] = wantSum
+		}
+	}
+
+	if len(res.Promoted) > 0 {
+		// Record the preset as it now resolves, so the promoted edits do
+		// not read as preset changes on the next sync.
+		promotedDir, promotedScope, promotedCleanup, err := m.effectivePreset(st, profile.Tool, sync.Preset)
+		defer promotedCleanup()
+		if err != nil {
+			return res, err
+		}
+		for _, rel := range res.Promoted {
+			if presetBaseline[rel], err = promotedScope.digest(promotedDir, rel); err != nil {
+				return res, err
+			}
+		}
+	}
+	if maps.Equal(baseline, sync.Baseline) && maps.Equal(presetBaseline, sync.PresetBaseline) && len(res.Promoted) == 0 {
+		return res, errSettingsInSync
+	}
+	sync.Baseline = baseline
+	sync.PresetBaseline = presetBaseline
+	if len(res.Promoted) == 0 {
+		return res, nil
+	}
+	// Promoted edits become a revision of the preset, sourced from the
+	// profile, as a snapshot from it would.
+	_, p := store.FindSettingsPreset(st, profile.Tool, res.Preset)
+	if p == nil {
+		return res, nil
+	}
+	now := time.Now().UTC()
+	p.UpdatedAt = now
+	p.Paths = promotedPaths(p.Paths, res.Promoted)
+	ownScope, err := applyScope(profile.Tool, p, ownDir)
+	if err != nil {
+		return res, err
+	}
+	_, err = m.recordPresetRevision(p, profile.Tool, res.Preset, ownDir, ownScope, profile.Name, now)
+	return res, err
+}
+
+// promotedPaths returns a preset's paths once promoted ones are in it: a
+// child may gain a path it used to inherit. Presets without recorded paths
+// cover whatever they hold and are left as they are.
+func promotedPaths(paths, promoted []string) []string {
+	if len(paths) == 0 {
+		return paths
+	}
+	out := slices.Clone(paths)
+	for _, rel := range promoted {
+		if !slices.Contains(out, rel) {
+			out = append(out, rel)
+		}
+	}
+	return out
+}
+
+// recordSettingsBaseline stores what a freshly applied profile and its preset
+// hold as the binding's baselines.
+func (m *Manager) recordSettingsBaseline(st *store.State, tool store.Tool, preset, profileDir, profileRef string) error {
+	presetDir, scope, cleanup, err := m.effectivePreset(st, tool, preset)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+	baseline := map[string]string{}
+	presetBaseline := map[string]string{}
+	for _, rel := range scope.Paths {
+		sum, err := scope.digest(profileDir, rel)
+		if err != nil {
+			return err
+		}
+		baseline[rel] = sum
+		if presetBaseline[rel], err = scope.digest(presetDir, rel); err != nil {
+			return err
+		}
+	}
+	if idx, s := store.FindSettingsSync(st, tool, profileRef); s != nil {
+		st.SettingsSync[idx].Baseline = baseline
+		st.SettingsSync[idx].PresetBaseline = presetBaseline
+	}
+	return nil
+}
+
+// settingsDigest fingerprints a settings file or directory. It returns ""
+// for a missing path; a directory covers every file's relative path and
+// content, except what skip drops.
+func settingsDigest(path string, skip func(rel string) bool) (string, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return digestBytes(b), nil
+	}
+	h := sha256.New()
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		if skip(filepath.ToSlash(rel)) {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), len(b))
+		h.Write(b)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func digestBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/derekurban/profilex-cli/internal/store"
@@ -130,12 +131,188 @@ func TestApplySyncedSettingsForProfile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	applied, preset, err := m.ApplySyncedSettings(target)
+	res, err := m.ApplySyncedSettings(target)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if applied || preset != "" {
-		t.Fatalf("expected no auto-apply, got applied=%v preset=%q", applied, preset)
+	if res.Preset != "shared-preset" || res.Changed() {
+		t.Fatalf("expected an in-sync profile to be left alone, got %+v", res)
+	}
+
+	if err := os.WriteFile(filepath.Join(p1.Dir, "config.toml"), []byte("model = \"v2\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SnapshotSettings(store.ToolCodex, "source", "shared-preset"); err != nil {
+		t.Fatal(err)
+	}
+	res, err = m.ApplySyncedSettings(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Applied) != 1 || res.Applied[0] != "config.toml" {
+		t.Fatalf("expected the preset change to be applied, got %+v", res)
+	}
+	if got, _ := os.ReadFile(filepath.Join(target.Dir, "config.toml")); string(got) != "model = \"v2\"\n" {
+		t.Fatalf("expected synced config, got %q", got)
+	}
+
+	// The tool rewrites its config: the default policy puts the preset back.
+	if err := os.WriteFile(filepath.Join(target.Dir, "config.toml"), []byte("model = \"drift\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err = m.ApplySyncedSettings(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Overwritten) != 1 {
+		t.Fatalf("expected local edits to be overwritten, got %+v", res)
+	}
+	if got, _ := os.ReadFile(filepath.Join(target.Dir, "config.toml")); string(got) != "model = \"v2\"\n" {
+		t.Fatalf("expected drift to be reverted, got %q", got)
+	}
+}
+
+func TestApplySyncedSettingsLocalEditPolicies(t *testing.T) {
+	m := newTestManager(t)
+	source, _, err := m.EnsureProfile(store.ToolCodex, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _, err := m.EnsureProfile(store.ToolCodex, "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source.Dir, "config.toml"), []byte("model = \"a\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SnapshotSettings(store.ToolCodex, "source", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSettingsSync(store.ToolCodex, "target", "team", true); err != nil {
+		t.Fatal(err)
+	}
+	presetFile := filepath.Join(m.Root(), "presets", "codex", "team", "config.toml")
+	localFile := filepath.Join(target.Dir, "config.toml")
+
+	if err := m.SetSettingsSyncPolicy(store.ToolCodex, "target", store.SyncPreserve); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(localFile, []byte("model = \"mine\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := m.ApplySyncedSettings(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Kept) != 1 || res.Changed() {
+		t.Fatalf("expected local edits to be preserved, got %+v", res)
+	}
+	if got, _ := os.ReadFile(localFile); string(got) != "model = \"mine\"\n" {
+		t.Fatalf("expected local config to survive, got %q", got)
+	}
+
+	if err := m.SetSettingsSyncPolicy(store.ToolCodex, "target", store.SyncPromote); err != nil {
+		t.Fatal(err)
+	}
+	before, err := m.SettingsPresetLog(store.ToolCodex, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = m.ApplySyncedSettings(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Promoted) != 1 {
+		t.Fatalf("expected local edits to be promoted, got %+v", res)
+	}
+	if got, _ := os.ReadFile(presetFile); string(got) != "model = \"mine\"\n" {
+		t.Fatalf("expected preset to take the local edit, got %q", got)
+	}
+	after, err := m.SettingsPresetLog(store.ToolCodex, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before)+1 || after[len(after)-1].Source != "target" {
+		t.Fatalf("expected promotion to add a revision sourced from target, got %+v", after)
+	}
+
+	// Both sides changed since the last sync: promote refuses to pick one.
+	if err := os.WriteFile(localFile, []byte("model = \"local\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(presetFile, []byte("model = \"upstream\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err = m.ApplySyncedSettings(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Conflicts) != 1 || res.Changed() {
+		t.Fatalf("expected a conflict, got %+v", res)
+	}
+	if got, _ := os.ReadFile(presetFile); string(got) != "model = \"upstream\"\n" {
+		t.Fatalf("expected conflicting preset to be left alone, got %q", got)
+	}
+}
+
+func TestApplySyncedSettingsConcurrentLaunches(t *testing.T) {
+	m := newTestManager(t)
+	source, _, err := m.EnsureProfile(store.ToolCodex, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _, err := m.EnsureProfile(store.ToolCodex, "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source.Dir, "config.toml"), []byte("model = \"a\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SnapshotSettings(store.ToolCodex, "source", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSettingsSync(store.ToolCodex, "target", "team", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSettingsSyncPolicy(store.ToolCodex, "target", store.SyncPromote); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target.Dir, "config.toml"), []byte("model = \"mine\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	before, err := m.SettingsPresetLog(store.ToolCodex, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Shims launching at once: only one may see the local edit.
+	results := make([]SettingsSyncResult, 8)
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = m.ApplySyncedSettings(target)
+		}()
+	}
+	wg.Wait()
+	promoted := 0
+	for i, res := range results {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		promoted += len(res.Promoted)
+	}
+	if promoted != 1 {
+		t.Fatalf("expected the edit to be promoted once, got %+v", results)
+	}
+	after, err := m.SettingsPresetLog(store.ToolCodex, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before)+1 {
+		t.Fatalf("expected one new revision, got %+v", after)
 	}
 }
 
@@ -317,3 +494,68 @@ func TestDeleteSettingsPresetRemovesSyncBindings(t *testing.T) {
 		t.Fatalf("expected sync binding to be removed with preset")
 	}
 }
+
+func TestSettingsPresetPathsCaptureDirectoriesButNeverCredentials(t *testing.T) {
+	m := newTestManager(t)
+	source, _, err := m.EnsureProfile(store.ToolClaude, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _, err := m.EnsureProfile(store.ToolClaude, "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(dir, rel, body string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(source.Dir, "settings.json", `{"theme":"dark"}`)
+	write(source.Dir, "commands/review.md", "review")
+	write(source.Dir, "commands/scratch.md", "scratch")
+	write(source.Dir, "skills/go/SKILL.md", "skill")
+	write(source.Dir, "skills/.credentials.json", "SECRET")
+	write(target.Dir, "commands/old.md", "old")
+	write(target.Dir, "commands/scratch.md", "target scratch")
+
+	for _, cred := range []string{".credentials.json", ".claude.json"} {
+		if err := m.SetSettingsPresetPaths(store.ToolClaude, "team", []string{cred}, nil); err == nil {
+			t.Fatalf("expected including %s to be refused", cred)
+		}
+	}
+	if err := m.SetSettingsPresetPaths(store.ToolClaude, "team", []string{"skills"}, []string{"commands/scratch.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SnapshotSettings(store.ToolClaude, "source", "team"); err != nil {
+		t.Fatal(err)
+	}
+	presetDir := filepath.Join(m.Root(), "presets", "claude", "team")
+	if _, err := os.Stat(filepath.Join(presetDir, "skills", ".credentials.json")); !os.IsNotExist(err) {
+		t.Fatalf("credential file must not be captured, stat err=%v", err)
+	}
+	if _, err := os.Stat(filepath.Join(presetDir, "commands", "scratch.md")); !os.IsNotExist(err) {
+		t.Fatalf("excluded file must not be captured, stat err=%v", err)
+	}
+
+	if err := m.ApplySettingsPreset(store.ToolClaude, "team", "target"); err != nil {
+		t.Fatal(err)
+	}
+	for rel, want := range map[string]string{
+		"settings.json":       `{"theme":"dark"}`,
+		"commands/review.md":  "review",
+		"commands/scratch.md": "target scratch",
+		"skills/go/SKILL.md":  "skill",
+	} {
+		if got, err := os.ReadFile(filepath.Join(target.Dir, filepath.FromSlash(rel))); err != nil || string(got) != want {
+			t.Fatalf("%s: expected %q, got %q (%v)", rel, want, got, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(target.Dir, "commands", "old.md")); !os.IsNotExist(err) {
+		t.Fatalf("expected the commands directory to be replaced, stat err=%v", err)
+	}
+}
@@ -0,0 +1,569 @@
+package app
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// tomlDoc is a line-level view of a TOML file, enough to merge settings key
+// by key while comments, blank lines and formatting of untouched keys
+// survive. mergeSettings checks the result against what go-toml decodes.
+type tomlDoc struct {
+	sections []*tomlSection
+}
+
+// tomlSection is the root table (empty header) or one [table] or
+// [[array]] block.
+type tomlSection struct {
+	header  string
+	name    string
+	array   bool
+	entries []*tomlEntry
+}
+
+// tomlEntry is one key with its value lines, or a run of comment and blank
+// lines (empty key).
+type tomlEntry struct {
+	key   string
+	lines []string
+}
+
+func parseTOML(b []byte) *tomlDoc {
+	doc := &tomlDoc{sections: []*tomlSection{{}}}
+	cur := doc.sections[0]
+	var open *tomlEntry
+	depth, quote := 0, ""
+	text := strings.TrimRight(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n")
+	if text == "" {
+		return doc
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if open != nil {
+			open.lines = append(open.lines, line)
+			depth, quote = tomlScan(line, depth, quote)
+			if depth <= 0 && quote == "" {
+				open = nil
+			}
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "[["):
+			cur = &tomlSection{header: line, name: tomlTableName(trimmed, "[[", "]]"), array: true}
+			doc.sections = append(doc.sections, cur)
+		case strings.HasPrefix(trimmed, "["):
+			cur = &tomlSection{header: line, name: tomlTableName(trimmed, "[", "]")}
+			doc.sections = append(doc.sections, cur)
+		case trimmed != "" && !strings.HasPrefix(trimmed, "#") && strings.Contains(trimmed, "="):
+			key, value, _ := cutTOMLKey(trimmed)
+			e := &tomlEntry{key: normalizeTOMLKey(key), lines: []string{line}}
+			cur.entries = append(cur.entries, e)
+			if depth, quote = tomlScan(value, 0, ""); depth > 0 || quote != "" {
+				open = e
+			}
+		default:
+			if n := len(cur.entries); n > 0 && cur.entries[n-1].key == "" {
+				cur.entries[n-1].lines = append(cur.entries[n-1].lines, line)
+			} else {
+				cur.entries = append(cur.entries, &tomlEntry{lines: []string{line}})
+			}
+		}
+	}
+	return doc
+}
+
+// tomlScan carries bracket depth and an open multi-line string delimiter
+// across the lines of a value.
+func tomlScan(s string, depth int, quote string) (int, string) {
+	for i := 0; i < len(s); i++ {
+		if quote != "" {
+			if strings.HasPrefix(s[i:], quote) {
+				i += len(quote) - 1
+				quote = ""
+			} else if s[i] == '\\' && quote[0] == '"' {
+				i++
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(s[i:], `"""`), strings.HasPrefix(s[i:], `'''`):
+			quote = s[i : i+3]
+			i += 2
+			// A triple-quoted string closed on the same line is not open.
+			if end := strings.Index(s[i+1:], quote); end >= 0 {
+				i += end + 3
+				quote = ""
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = string(s[i])
+		case s[i] == '#':
+			return depth, quote
+		case s[i] == '[' || s[i] == '{':
+			depth++
+		case s[i] == ']' || s[i] == '}':
+			depth--
+		}
+	}
+	// Single-quoted and basic strings cannot span lines.
+	if quote == `"` || quote == `'` {
+		quote = ""
+	}
+	return depth, quote
+}
+
+func tomlTableName(header, open, close string) string {
+	inner := strings.TrimPrefix(header, open)
+	if i := strings.Index(inner, close); i >= 0 {
+		inner = inner[:i]
+	}
+	return normalizeTOMLKey(inner)
+}
+
+// normalizeTOMLKey spells a dotted key one way, so keys TOML treats as the
+// same compare equal: "a.b" and 'a.b' are one key, a.b is two.
+func normalizeTOMLKey(key string) string {
+	parts := tomlKeyParts(key)
+	if parts == nil {
+		return strings.TrimSpace(key)
+	}
+	return joinTOMLKey(parts)
+}
+
+// tomlKeyParts splits a dotted key the way the decoder reads it, or returns
+// nil when key is not one.
+func tomlKeyParts(key string) []string {
+	var doc map[string]any
+	if err := toml.Unmarshal([]byte(key+" = 0"), &doc); err != nil {
+		return nil
+	}
+	var parts []string
+	for v := any(doc); ; {
+		m, ok := v.(map[string]any)
+		if !ok || len(m) != 1 {
+			return parts
+		}
+		for k, child := range m {
+			parts = append(parts, k)
+			v = child
+		}
+	}
+}
+
+// joinTOMLKey writes key parts as a dotted key, quoting those that cannot
+// be bare.
+func joinTOMLKey(parts []string) string {
+	out := make([]string, len(parts))
+	for i, p := range parts {
+		out[i] = p
+		if p == "" || strings.ContainsFunc(p, func(r rune) bool {
+			return !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-')
+		}) {
+			out[i] = strconv.Quote(p)
+		}
+	}
+	return strings.Join(out, ".")
+}
+
+// cutTOMLKey splits a key/value line at the first = outside a quoted key.
+func cutTOMLKey(line string) (key, value string, ok bool) {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return line[:i], line[i+1:], true
+		}
+	}
+	return line, "", false
+}
+
+func (d *tomlDoc) bytes() []byte {
+	lines := []string{}
+	for _, s := range d.sections {
+		if s.header != "" {
+			lines = append(lines, s.header)
+		}
+		for _, e := range s.entries {
+			lines = append(lines, e.lines...)
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func (d *tomlDoc) table(name string) *tomlSection {
+	for _, s := range d.sections {
+		if !s.array && s.name == name && (s.header != "" || name == "") {
+			return s
+		}
+	}
+	return nil
+}
+
+// lookup finds the entry that sets a dotted path, whether it sits under a
+// [table] header or is spelled as a dotted key in a parent table.
+func (d *tomlDoc) lookup(path string) *tomlEntry {
+	for _, s := range d.sections {
+		if s.array {
+			continue
+		}
+		for _, e := range s.entries {
+			if e.key != "" && joinKey(s.name, e.key) == path {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// redefined returns the first key set both as a value and as a table, as
+// splicing a table written inline into one written under a [header] does.
+func (d *tomlDoc) redefined() string {
+	paths := []string{}
+	for _, s := range d.sections {
+		if s.array {
+			continue
+		}
+		if s.name != "" {
+			paths = append(paths, s.name)
+		}
+		for _, e := range s.entries {
+			if e.key != "" {
+				paths = append(paths, joinKey(s.name, e.key))
+			}
+		}
+	}
+	for _, s := range d.sections {
+		if s.array {
+			continue
+		}
+		for _, e := range s.entries {
+			if e.key == "" {
+				continue
+			}
+			key := joinKey(s.name, e.key)
+			seen := 0
+			for _, p := range paths {
+				if p == key {
+					seen++
+				}
+				if seen > 1 || strings.HasPrefix(p, key+".") {
+					return key
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// withValue returns e's lines with src's value. Keys spelled alike keep
+// src's lines as they are; otherwise e's key is kept, since it is relative
+// to the table e sits in.
+func (e *tomlEntry) withValue(src *tomlEntry) []string {
+	key, _, _ := cutTOMLKey(e.lines[0])
+	srcKey, value, _ := cutTOMLKey(src.lines[0])
+	if strings.TrimSpace(key) == strings.TrimSpace(srcKey) {
+		return slices.Clone(src.lines)
+	}
+	return append([]string{strings.TrimRight(key, " \t") + " = " + strings.TrimSpace(value)}, src.lines[1:]...)
+}
+
+// insertEntry adds a preset key the profile lacks where the profile already
+// keeps that part of the document: under the deepest [table] it has on the
+// key's path, or beside dotted keys that spell the same table. Otherwise the
+// preset's own table is added.
+func (d *tomlDoc) insertEntry(ps *tomlSection, pe *tomlEntry) {
+	base := tomlKeyParts(ps.name)
+	path := append(slices.Clone(base), tomlKeyParts(pe.key)...)
+	for i := len(path) - 1; i > len(base); i-- {
+		if s := d.table(joinTOMLKey(path[:i])); s != nil {
+			s.insert(relativeTOMLEntry(pe, path[i:]))
+			return
+		}
+	}
+	for i := len(path) - 1; i > 0; i-- {
+		parent := joinTOMLKey(path[:i]) + "."
+		for _, s := range d.sections {
+			depth := len(tomlKeyParts(s.name))
+			if s.array || depth >= i || s.name != "" && !strings.HasPrefix(parent, s.name+".") {
+				continue
+			}
+			for at, e := range s.entries {
+				if e.key != "" && strings.HasPrefix(joinKey(s.name, e.key), parent) {
+					s.entries = slices.Insert(s.entries, at+1, relativeTOMLEntry(pe, path[depth:]))
+					return
+				}
+			}
+		}
+	}
+	s := d.table(ps.name)
+	if s == nil {
+		s = &tomlSection{header: ps.header, name: ps.name}
+		d.appendSection(s)
+	}
+	s.insert(&tomlEntry{key: pe.key, lines: slices.Clone(pe.lines)})
+}
+
+// relativeTOMLEntry is e's value under key, a dotted key relative to the
+// table it goes into.
+func relativeTOMLEntry(e *tomlEntry, key []string) *tomlEntry {
+	k := joinTOMLKey(key)
+	_, value, _ := cutTOMLKey(e.lines[0])
+	return &tomlEntry{key: k, lines: append([]string{k + " = " + strings.TrimSpace(value)}, e.lines[1:]...)}
+}
+
+// insert adds e after the section's last key, ahead of trailing comments
+// and blank lines that usually belong to the next table.
+func (s *tomlSection) insert(e *tomlEntry) {
+	at := len(s.entries)
+	for at > 0 && s.entries[at-1].key == "" {
+		at--
+	}
+	s.entries = slices.Insert(s.entries, at, e)
+}
+
+// mergeTOML applies preset onto local. Keys the preset sets replace the
+// profile's, or union with them when both are arrays and union is set.
+// [[array]] tables are replaced as a whole, or have the preset's blocks
+// added when union is set.
+func mergeTOML(local, preset *tomlDoc, union bool) *tomlDoc {
+	replacedArrays := map[string]bool{}
+	for _, ps := range preset.sections {
+		if ps.array {
+			mergeTOMLArrayTable(local, ps, union, replacedArrays)
+			continue
+		}
+		keys := 0
+		for _, pe := range ps.entries {
+			if pe.key == "" {
+				continue
+			}
+			keys++
+			le := local.lookup(joinKey(ps.name, pe.key))
+			switch {
+			case le == nil:
+				local.insertEntry(ps, pe)
+			case union:
+				if lines, ok := unionTOMLArrays(le, pe); ok {
+					le.lines = lines
+				} else {
+					le.lines = le.withValue(pe)
+				}
+			default:
+				le.lines = le.withValue(pe)
+			}
+		}
+		// An empty table in the preset still exists once merged.
+		if keys == 0 && local.table(ps.name) == nil && local.lookup(ps.name) == nil {
+			local.appendSection(&tomlSection{header: ps.header, name: ps.name})
+		}
+	}
+	return local
+}
+
+func mergeTOMLArrayTable(local *tomlDoc, ps *tomlSection, union bool, replaced map[string]bool) {
+	block := &tomlSection{header: ps.header, name: ps.name, array: true, entries: cloneTOMLEntries(ps.entries)}
+	if union {
+		for _, ls := range local.sections {
+			if ls.array && ls.name == ps.name && tomlBody(ls) == tomlBody(ps) {
+				return
+			}
+		}
+		local.insertAfterLast(ps.name, block)
+		return
+	}
+	if !replaced[ps.name] {
+		replaced[ps.name] = true
+		kept := local.sections[:0]
+		at := -1
+		for _, ls := range local.sections {
+			if ls.array && ls.name == ps.name {
+				if at < 0 {
+					at = len(kept)
+				}
+				continue
+			}
+			kept = append(kept, ls)
+		}
+		local.sections = kept
+		if at >= 0 {
+			local.sections = slices.Insert(local.sections, at, block)
+			return
+		}
+		local.appendSection(block)
+		return
+	}
+	local.insertAfterLast(ps.name, block)
+}
+
+// appendSection adds s at the end, separated from what precedes it by a
+// blank line.
+func (d *tomlDoc) appendSection(s *tomlSection) {
+	last := d.sections[len(d.sections)-1]
+	if n := len(last.entries); (n > 0 || last.header != "") && !(n > 0 && last.entries[n-1].key == "" && strings.TrimSpace(last.entries[n-1].lines[len(last.entries[n-1].lines)-1]) == "") {
+		last.entries = append(last.entries, &tomlEntry{lines: []string{""}})
+	}
+	d.sections = append(d.sections, s)
+}
+
+func (d *tomlDoc) insertAfterLast(name string, s *tomlSection) {
+	for i := len(d.sections) - 1; i >= 0; i-- {
+		if d.sections[i].array && d.sections[i].name == name {
+			d.sections = slices.Insert(d.sections, i+1, s)
+			return
+		}
+	}
+	d.appendSection(s)
+}
+
+// projectTOML keeps the preset's layout with local's values for every key
+// both set. Keys and tables local lacks are dropped.
+func projectTOML(local, preset *tomlDoc) *tomlDoc {
+	out := &tomlDoc{}
+	arrays := map[string]bool{}
+	for _, ps := range preset.sections {
+		if ps.array {
+			if arrays[ps.name] {
+				continue
+			}
+			arrays[ps.name] = true
+			for _, ls := range local.sections {
+				if ls.array && ls.name == ps.name {
+					out.sections = append(out.sections, &tomlSection{header: ls.header, name: ls.name, array: true, entries: cloneTOMLEntries(ls.entries)})
+				}
+			}
+			continue
+		}
+		s := &tomlSection{header: ps.header, name: ps.name}
+		found := false
+		for _, pe := range ps.entries {
+			if pe.key == "" {
+				s.entries = append(s.entries, &tomlEntry{lines: slices.Clone(pe.lines)})
+				continue
+			}
+			if le := local.lookup(joinKey(ps.name, pe.key)); le != nil {
+				s.entries = append(s.entries, &tomlEntry{key: pe.key, lines: pe.withValue(le)})
+				found = true
+			}
+		}
+		if ps.name != "" && !found && local.table(ps.name) == nil {
+			continue
+		}
+		out.sections = append(out.sections, s)
+	}
+	if len(out.sections) == 0 || out.sections[0].name != "" || out.sections[0].header != "" {
+		out.sections = append([]*tomlSection{{}}, out.sections...)
+	}
+	return out
+}
+
+// unionTOMLArrays merges two array values item by item, keeping local's
+// order. ok is false when either value is not an array.
+func unionTOMLArrays(local, preset *tomlEntry) ([]string, bool) {
+	li, lok := tomlArrayItems(local.lines)
+	pi, pok := tomlArrayItems(preset.lines)
+	if !lok || !pok {
+		return nil, false
+	}
+	for _, item := range pi {
+		if !slices.Contains(li, item) {
+			li = append(li, item)
+		}
+	}
+	prefix, _, _ := cutTOMLKey(local.lines[0])
+	return []string{strings.TrimRight(prefix, " ") + " = [" + strings.Join(li, ", ") + "]"}, true
+}
+
+func tomlArrayItems(lines []string) ([]string, bool) {
+	parts := []string{}
+	for i, line := range lines {
+		if i == 0 {
+			_, line, _ = cutTOMLKey(line)
+		}
+		parts = append(parts, stripTOMLComment(line))
+	}
+	value := strings.TrimSpace(strings.Join(parts, " "))
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return nil, false
+	}
+	value = value[1 : len(value)-1]
+	items := []string{}
+	depth, quote, start := 0, byte(0), 0
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(value[start:i]))
+			start = i + 1
+		}
+	}
+	items = append(items, strings.TrimSpace(value[start:]))
+	return slices.DeleteFunc(items, func(s string) bool { return s == "" }), true
+}
+
+func stripTOMLComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlBody is a section's keys and values, ignoring comments and layout,
+// for telling [[array]] blocks apart.
+func tomlBody(s *tomlSection) string {
+	out := []string{}
+	for _, e := range s.entries {
+		if e.key == "" {
+			continue
+		}
+		for _, line := range e.lines {
+			out = append(out, strings.TrimSpace(stripTOMLComment(line)))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func cloneTOMLEntries(entries []*tomlEntry) []*tomlEntry {
+	out := make([]*tomlEntry, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, &tomlEntry{key: e.key, lines: slices.Clone(e.lines)})
+	}
+	return out
+}
@@ -0,0 +1,297 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+const (
+	// DefaultStatusTTL is how long a cached status probe is reused.
+	DefaultStatusTTL = 5 * time.Minute
+	// StatusTTLVar overrides DefaultStatusTTL, e.g. "30s" or "0" to disable.
+	StatusTTLVar = "PROFILEX_STATUS_TTL"
+
+	statusWorkers      = 4
+	statusProbeTimeout = 8 * time.Second
+	statusCacheVersion = 1
+)
+
+type StatusRow struct {
+	Profile store.Profile   `json:"profile"`
+	Status  adapters.Status `json:"status"`
+	Error   string          `json:"error,omitempty"`
+	// Version and BinaryPath describe the executable the profile launches.
+	// BinaryError is set when it is missing or fails to report a version.
+	Version     string    `json:"version,omitempty"`
+	BinaryPath  string    `json:"binary_path,omitempty"`
+	BinaryError string    `json:"binary_error,omitempty"`
+	CheckedAt   time.Time `json:"checked_at"`
+	// Cached is true when the row came from the status cache.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// StatusOptions tunes StatusRowsWith. The zero value reads through the cache
+// with the TTL StatusTTL resolves.
+type StatusOptions struct {
+	// Refresh ignores cached rows; fresh results are still written back.
+	Refresh bool
+	// TTL overrides the cache lifetime. Zero means StatusTTL(), so
+	// PROFILEX_STATUS_TTL applies and "0" there disables the cache; a
+	// negative TTL disables it regardless.
+	TTL time.Duration
+	// OnRow is called as each row completes, with its index in the result.
+	// Calls are serialized.
+	OnRow func(index int, row StatusRow)
+}
+
+type statusCacheEntry struct {
+	Fingerprint string    `json:"fingerprint"`
+	Row         StatusRow `json:"row"`
+}
+
+type statusCacheFile struct {
+	Version int                         `json:"version"`
+	Entries map[string]statusCacheEntry `json:"entries"`
+}
+
+// StatusTTL resolves the cache lifetime from PROFILEX_STATUS_TTL.
+func StatusTTL() (time.Duration, error) {
+	raw := strings.TrimSpace(os.Getenv(StatusTTLVar))
+	if raw == "" {
+		return DefaultStatusTTL, nil
+	}
+	if raw == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q", StatusTTLVar, raw)
+	}
+	return d, nil
+}
+
+func (m *Manager) StatusRows(ctx context.Context, filterTool *store.Tool) ([]StatusRow, error) {
+	return m.StatusRowsWith(ctx, filterTool, StatusOptions{})
+}
+
+// StatusRowsWith probes profiles on a bounded worker pool, reusing cached
+// rows whose fingerprint still matches. Rows are returned in state order.
+func (m *Manager) StatusRowsWith(ctx context.Context, filterTool *store.Tool, opts StatusOptions) ([]StatusRow, error) {
+	st, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+	m.recoverCrashedRuns(st)
+	ttl := opts.TTL
+	if ttl == 0 {
+		if ttl, err = StatusTTL(); err != nil {
+			return nil, err
+		}
+	}
+	cache := m.loadStatusCache()
+
+	profiles := []store.Profile{}
+	for _, p := range st.Profiles {
+		if filterTool == nil || p.Tool == *filterTool {
+			profiles = append(profiles, p)
+		}
+	}
+	rows := make([]StatusRow, len(profiles))
+	fingerprints := make([]string, len(profiles))
+
+	var (
+		emitMu sync.Mutex
+		wg     sync.WaitGroup
+	)
+	emit := func(i int, row StatusRow) {
+		rows[i] = row
+		if opts.OnRow != nil {
+			emitMu.Lock()
+			opts.OnRow(i, row)
+			emitMu.Unlock()
+		}
+	}
+
+	prober := newBinaryProber()
+	jobs := make(chan int)
+	workers := min(statusWorkers, len(profiles))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				row, fp := m.probeStatusRow(ctx, st, profiles[i], prober, cache, ttl, opts.Refresh)
+				fingerprints[i] = fp
+				emit(i, row)
+			}
+		}()
+	}
+	for i := range profiles {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Failed probes are not cached so the next call retries them.
+	dirty := false
+	for i, row := range rows {
+		if fingerprints[i] == "" || row.Error != "" || row.Cached {
+			continue
+		}
+		cache.Entries[statusCacheKey(row.Profile)] = statusCacheEntry{Fingerprint: fingerprints[i], Row: row}
+		dirty = true
+	}
+	if dirty && ttl > 0 {
+		_ = m.saveStatusCache(cache)
+	}
+	return rows, nil
+}
+
+// CachedStatus returns the cached row for a profile when it is still valid,
+// without probing anything.
+func (m *Manager) CachedStatus(profile store.Profile) (StatusRow, bool) {
+	ttl, err := StatusTTL()
+	if err != nil || ttl == 0 {
+		return StatusRow{}, false
+	}
+	st, err := m.Load()
+	if err != nil {
+		return StatusRow{}, false
+	}
+	entry, ok := m.loadStatusCache().Entries[statusCacheKey(profile)]
+	if !ok || time.Since(entry.Row.CheckedAt) > ttl {
+		return StatusRow{}, false
+	}
+	if fp, err := m.statusFingerprint(st, profile); err != nil || fp != entry.Fingerprint {
+		return StatusRow{}, false
+	}
+	entry.Row.Cached = true
+	return entry.Row, true
+}
+
+func (m *Manager) probeStatusRow(ctx context.Context, st *store.State, p store.Profile, prober *binaryProber, cache *statusCacheFile, ttl time.Duration, refresh bool) (StatusRow, string) {
+	dir, err := m.validatedManagedProfileDir(p)
+	if err != nil {
+		return StatusRow{Profile: p, Error: err.Error(), CheckedAt: time.Now().UTC()}, ""
+	}
+	p.Dir = dir
+
+	fp, fpErr := m.statusFingerprint(st, p)
+	if fpErr == nil && !refresh && ttl > 0 {
+		if entry, ok := cache.Entries[statusCacheKey(p)]; ok && entry.Fingerprint == fp && time.Since(entry.Row.CheckedAt) <= ttl {
+			row := entry.Row
+			row.Profile = p
+			row.Cached = true
+			return row, fp
+		}
+	}
+
+	row := StatusRow{Profile: p, CheckedAt: time.Now().UTC()}
+	if adapter, err := m.adapterFor(p); err == nil {
+		bin := prober.probe(ctx, adapter)
+		row.Version, row.BinaryPath, row.BinaryError = bin.Version, bin.Path, bin.Error
+	}
+	ctxOne, cancel := context.WithTimeout(ctx, statusProbeTimeout)
+	status, sErr := m.StatusForProfile(ctxOne, p)
+	cancel()
+	row.Status = status
+	if sErr != nil {
+		row.Error = sErr.Error()
+	}
+	if fpErr != nil {
+		fp = ""
+	}
+	return row, fp
+}
+
+// statusFingerprint changes whenever a cached row could be stale: a login or
+// logout rewrites credential files, and upgrading or re-pinning the binary
+// changes its path or modification time.
+func (m *Manager) statusFingerprint(st *store.State, p store.Profile) (string, error) {
+	adapter, err := adapters.Get(p.Tool)
+	if err != nil {
+		return "", err
+	}
+	pin, _ := st.BinaryFor(p)
+	adapter = adapter.WithBinary(pin)
+	parts := []string{p.Dir, string(p.Auth), fmt.Sprint(p.Vault), adapter.Binary()}
+	if path, err := exec.LookPath(adapter.Binary()); err == nil {
+		parts = append(parts, path+"@"+modStamp(path))
+	}
+	for _, f := range adapter.CredentialFiles() {
+		parts = append(parts, f+"@"+modStamp(filepath.Join(p.Dir, f)))
+	}
+	return strings.Join(parts, "|"), nil
+}
+
+func modStamp(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+}
+
+func statusCacheKey(p store.Profile) string {
+	return string(p.Tool) + "/" + p.Name
+}
+
+func (m *Manager) statusCachePath() string {
+	return filepath.Join(m.Root(), "cache", "status.json")
+}
+
+// loadStatusCache never fails: a missing or corrupt cache is treated as
+// empty.
+func (m *Manager) loadStatusCache() *statusCacheFile {
+	cache := &statusCacheFile{Version: statusCacheVersion, Entries: map[string]statusCacheEntry{}}
+	b, err := os.ReadFile(m.statusCachePath())
+	if err != nil {
+		return cache
+	}
+	var loaded statusCacheFile
+	if json.Unmarshal(b, &loaded) != nil || loaded.Version != statusCacheVersion || loaded.Entries == nil {
+		return cache
+	}
+	return &loaded
+}
+
+func (m *Manager) saveStatusCache(cache *statusCacheFile) error {
+	path := m.statusCachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// invalidateStatus drops a profile's cached row after an operation that
+// changes its status.
+func (m *Manager) invalidateStatus(p store.Profile) {
+	cache := m.loadStatusCache()
+	if _, ok := cache.Entries[statusCacheKey(p)]; !ok {
+		return
+	}
+	delete(cache.Entries, statusCacheKey(p))
+	_ = m.saveStatusCache(cache)
+}
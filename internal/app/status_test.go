@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// writeFakeStatusBinary installs a claude stand-in that logs each status
+// probe to calls so tests can count them.
+func writeFakeStatusBinary(t *testing.T, dir, calls string) {
+	t.Helper()
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = \"--version\" ]; then echo '2.1.0 (Claude Code)'; exit 0; fi\n" +
+		"echo \"$CLAUDE_CONFIG_DIR\" >> '" + calls + "'\n" +
+		"echo '{\"loggedIn\": true, \"authMethod\": \"claude.ai\"}'\n"
+	if err := os.WriteFile(filepath.Join(dir, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatusRowsCacheProbesUntilCredentialsChange(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the tool binary")
+	}
+	bin := t.TempDir()
+	calls := filepath.Join(t.TempDir(), "calls")
+	writeFakeStatusBinary(t, bin, calls)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv(StatusTTLVar, "")
+
+	m := newTestManager(t)
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, name := range names {
+		if _, _, err := m.EnsureProfile(store.ToolClaude, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	probes := func() int {
+		b, _ := os.ReadFile(calls)
+		return strings.Count(string(b), "\n")
+	}
+
+	seen := map[int]bool{}
+	rows, err := m.StatusRowsWith(context.Background(), nil, StatusOptions{OnRow: func(i int, _ StatusRow) {
+		if seen[i] {
+			t.Errorf("row %d emitted twice", i)
+		}
+		seen[i] = true
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != len(names) || probes() != len(names) {
+		t.Fatalf("expected %d emitted and probed rows, got %d emitted, %d probed", len(names), len(seen), probes())
+	}
+	for i, row := range rows {
+		if row.Profile.Name != names[i] || !row.Status.LoggedIn || row.Cached || row.Version != "2.1.0" {
+			t.Fatalf("unexpected row %d: %+v", i, row)
+		}
+	}
+
+	rows, err = m.StatusRows(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probes() != len(names) || !rows[0].Cached || !rows[0].Status.LoggedIn {
+		t.Fatalf("second call should be served from cache, probed %d, row %+v", probes(), rows[0])
+	}
+	if row, ok := m.CachedStatus(rows[1].Profile); !ok || !row.Status.LoggedIn {
+		t.Fatalf("expected cached row for %s", rows[1].Profile.Name)
+	}
+
+	if err := os.WriteFile(filepath.Join(rows[2].Profile.Dir, ".credentials.json"), []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.CachedStatus(rows[2].Profile); ok {
+		t.Fatalf("credential change should invalidate the cached row")
+	}
+	if _, err := m.StatusRows(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if probes() != len(names)+1 {
+		t.Fatalf("expected only the changed profile to be re-probed, got %d probes", probes())
+	}
+
+	if _, err := m.StatusRowsWith(context.Background(), nil, StatusOptions{Refresh: true}); err != nil {
+		t.Fatal(err)
+	}
+	if probes() != 2*len(names)+1 {
+		t.Fatalf("refresh should bypass the cache, got %d probes", probes())
+	}
+
+	t.Setenv(StatusTTLVar, "0")
+	if _, ok := m.CachedStatus(rows[0].Profile); ok {
+		t.Fatalf("a zero TTL should disable the cache")
+	}
+}
+
+func TestStatusOptionsZeroTTLFollowsEnvironment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the tool binary")
+	}
+	bin := t.TempDir()
+	calls := filepath.Join(t.TempDir(), "calls")
+	writeFakeStatusBinary(t, bin, calls)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	m := newTestManager(t)
+	if _, _, err := m.EnsureProfile(store.ToolClaude, "a"); err != nil {
+		t.Fatal(err)
+	}
+	probes := func() int {
+		b, _ := os.ReadFile(calls)
+		return strings.Count(string(b), "\n")
+	}
+	list := func(opts StatusOptions) {
+		t.Helper()
+		if _, err := m.StatusRowsWith(context.Background(), nil, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv(StatusTTLVar, "0")
+	list(StatusOptions{})
+	list(StatusOptions{})
+	if probes() != 2 {
+		t.Fatalf("a zero TTL with %s=0 should disable the cache, got %d probes", StatusTTLVar, probes())
+	}
+
+	t.Setenv(StatusTTLVar, "1h")
+	list(StatusOptions{})
+	list(StatusOptions{})
+	if probes() != 3 {
+		t.Fatalf("a zero TTL should use the %s lifetime, got %d probes", StatusTTLVar, probes())
+	}
+	list(StatusOptions{TTL: -1})
+	if probes() != 4 {
+		t.Fatalf("a negative TTL should disable the cache, got %d probes", probes())
+	}
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+	"github.com/derekurban/profilex-cli/internal/usage"
+)
+
+// usageTodayTTL bounds how often status lines rescan session logs.
+const usageTodayTTL = time.Minute
+
+// SetProfileColor sets the #rrggbb color terminal integrations use for a
+// profile. An empty color clears it.
+func (m *Manager) SetProfileColor(tool store.Tool, name, color string) (string, error) {
+	if strings.TrimSpace(color) != "" {
+		c, err := store.NormalizeColor(color)
+		if err != nil {
+			return "", err
+		}
+		color = c
+	} else {
+		color = ""
+	}
+	err := m.store.Update(func(st *store.State) error {
+		idx, p := store.FindProfile(st, tool, name)
+		if p == nil {
+			return fmt.Errorf("profile not found: %s/%s", tool, name)
+		}
+		st.Profiles[idx].Color = color
+		return nil
+	})
+	return color, err
+}
+
+// SetTerminalIntegrations replaces the enabled terminal integrations.
+func (m *Manager) SetTerminalIntegrations(names []string) error {
+	enabled := []string{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(store.TerminalIntegrations, name) {
+			return fmt.Errorf("unknown terminal integration %q (expected %s)", name, strings.Join(store.TerminalIntegrations, ", "))
+		}
+		if !slices.Contains(enabled, name) {
+			enabled = append(enabled, name)
+		}
+	}
+	return m.store.Update(func(st *store.State) error {
+		st.Terminal = &store.TerminalConfig{Integrations: enabled}
+		return nil
+	})
+}
+
+type usageTodayEntry struct {
+	CheckedAt time.Time       `json:"checked_at"`
+	Totals    usage.DayTotals `json:"totals"`
+}
+
+// UsageToday totals the profile's usage for the current local day. Results
+// are cached for a minute, since status lines call this every few seconds.
+func (m *Manager) UsageToday(st *store.State, profile store.Profile) (usage.DayTotals, error) {
+	now := time.Now()
+	path := filepath.Join(m.Root(), "cache", "usage-today.json")
+	key := statusCacheKey(profile)
+	cache := map[string]usageTodayEntry{}
+	if b, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(b, &cache)
+	}
+	if entry, ok := cache[key]; ok && now.Sub(entry.CheckedAt) < usageTodayTTL && entry.Totals.Date == now.Format("2006-01-02") {
+		return entry.Totals, nil
+	}
+
+	totals, err := usage.ProfileDay(st, profile, now)
+	if err != nil {
+		return totals, err
+	}
+	cache[key] = usageTodayEntry{CheckedAt: now.UTC(), Totals: totals}
+	// A failed write only costs the next call a rescan.
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err == nil {
+		if b, err := json.Marshal(cache); err == nil {
+			tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+			if os.WriteFile(tmp, b, 0o600) == nil && os.Rename(tmp, path) != nil {
+				_ = os.Remove(tmp)
+			}
+		}
+	}
+	return totals, nil
+}
@@ -0,0 +1,492 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/secrets"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// RunLease records the profilex processes currently running a profile. The
+// first holder unseals a vault profile and the last one re-seals it; a lease
+// whose holders are all dead marks a crashed run.
+type RunLease struct {
+	PIDs      []int     `json:"pids"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+type VaultStatus struct {
+	Profile   store.Profile `json:"profile"`
+	Enabled   bool          `json:"enabled"`
+	Sealed    bool          `json:"sealed"`
+	Plaintext []string      `json:"plaintext,omitempty"`
+	Lease     *RunLease     `json:"lease,omitempty"`
+	Recovered bool          `json:"recovered,omitempty"`
+}
+
+// EnableVault seals the profile's credential files and marks it as a vault
+// profile. Launches must then go through RunTool.
+func (m *Manager) EnableVault(tool store.Tool, name string) (store.Profile, error) {
+	profile, err := m.lookupProfile(tool, name)
+	if err != nil {
+		return store.Profile{}, err
+	}
+	resolve := func() (secrets.Key, error) { return m.secrets.ResolveKey(m.prompt, true) }
+	err = m.withVaultKey(resolve, func(need func() (secrets.Key, error)) error {
+		if lease := m.liveLease(profile); lease != nil {
+			return fmt.Errorf("%s/%s is running (pid %v); enable the vault after it exits", tool, name, lease.PIDs)
+		}
+		key, err := need()
+		if err != nil {
+			return err
+		}
+		return m.sealCredentials(profile, key)
+	})
+	if err != nil {
+		return store.Profile{}, err
+	}
+	if err := m.setVault(tool, name, true); err != nil {
+		return store.Profile{}, err
+	}
+	profile.Vault = true
+	return profile, nil
+}
+
+// DisableVault restores the credential files in plaintext and drops the
+// sealed blob.
+func (m *Manager) DisableVault(tool store.Tool, name string) (store.Profile, error) {
+	profile, err := m.lookupProfile(tool, name)
+	if err != nil {
+		return store.Profile{}, err
+	}
+	vault := m.secrets.Vault()
+	resolve := func() (secrets.Key, error) { return vault.UnlockKey(tool, name, m.prompt) }
+	err = m.withVaultKey(resolve, func(need func() (secrets.Key, error)) error {
+		if lease := m.liveLease(profile); lease != nil {
+			return fmt.Errorf("%s/%s is running (pid %v); disable the vault after it exits", tool, name, lease.PIDs)
+		}
+		if vault.Has(tool, name) && len(m.plaintextCredentials(profile)) == 0 {
+			key, err := need()
+			if err != nil {
+				return err
+			}
+			if err := m.unsealCredentials(profile, key); err != nil {
+				return err
+			}
+		}
+		_ = os.Remove(m.leasePath(profile))
+		return vault.Delete(tool, name)
+	})
+	if err != nil {
+		return store.Profile{}, err
+	}
+	if err := m.setVault(tool, name, false); err != nil {
+		return store.Profile{}, err
+	}
+	profile.Vault = false
+	return profile, nil
+}
+
+// VaultStatus reports whether the profile's credentials are sealed. A lease
+// left behind by a crashed run is recovered (credentials re-sealed) first.
+func (m *Manager) VaultStatus(tool store.Tool, name string) (VaultStatus, error) {
+	profile, err := m.lookupProfile(tool, name)
+	if err != nil {
+		return VaultStatus{}, err
+	}
+	out := VaultStatus{Profile: profile, Enabled: profile.Vault}
+	resolve := func() (secrets.Key, error) { return m.sealKey(profile, m.prompt) }
+	err = m.withVaultKey(resolve, func(need func() (secrets.Key, error)) error {
+		if profile.Vault {
+			recovered, err := m.recoverCrashedRun(profile, need)
+			if err != nil {
+				return err
+			}
+			out.Recovered = recovered
+		}
+		out.Lease = m.liveLease(profile)
+		out.Sealed = m.secrets.Vault().Has(tool, name)
+		out.Plaintext = m.plaintextCredentials(profile)
+		return nil
+	})
+	return out, err
+}
+
+// openVault takes a run lease for a vault profile, unsealing its credentials
+// when no other run holds them. A crashed run's plaintext is re-sealed first. The returned release re-seals and wipes them
+// once the last holder exits.
+func (m *Manager) openVault(profile store.Profile) (func() error, error) {
+	vault := m.secrets.Vault()
+	resolve := func() (secrets.Key, error) { return m.sealKey(profile, m.prompt) }
+	var key secrets.Key
+	err := m.withVaultKey(resolve, func(need func() (secrets.Key, error)) error {
+		if _, err := m.recoverCrashedRun(profile, need); err != nil {
+			return err
+		}
+		lease := m.liveLease(profile)
+		if lease == nil {
+			// Plaintext written while the profile was sealed is newer than
+			// the blob, so keep it as is; the release seals it.
+			if len(m.plaintextCredentials(profile)) == 0 && vault.Has(profile.Tool, profile.Name) {
+				k, err := need()
+				if err != nil {
+					return err
+				}
+				if err := m.unsealCredentials(profile, k); err != nil {
+					return err
+				}
+				key = k
+			}
+			lease = &RunLease{StartedAt: time.Now().UTC()}
+		}
+		lease.PIDs = append(lease.PIDs, os.Getpid())
+		return m.writeLease(profile, lease)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return m.withVaultKey(resolve, func(need func() (secrets.Key, error)) error {
+			lease := m.liveLease(profile)
+			if lease != nil {
+				pids := lease.PIDs[:0]
+				for _, pid := range lease.PIDs {
+					if pid != os.Getpid() {
+						pids = append(pids, pid)
+					}
+				}
+				lease.PIDs = pids
+			}
+			if lease != nil && len(lease.PIDs) > 0 {
+				return m.writeLease(profile, lease)
+			}
+			sealWith := key
+			if sealWith.KeyFile == "" && len(sealWith.Passphrase) == 0 {
+				k, err := need()
+				if err != nil {
+					return err
+				}
+				sealWith = k
+			}
+			if err := m.sealCredentials(profile, sealWith); err != nil {
+				return err
+			}
+			return removeIfExists(m.leasePath(profile))
+		})
+	}, nil
+}
+
+// runProfileCommand runs cmd interactively, unsealing vault profiles for the
+// duration of the run. While a vault profile runs, profilex stays alive
+// through Ctrl-C and forwards termination signals so it can re-seal.
+func (m *Manager) runProfileCommand(ctx context.Context, profile store.Profile, cmd *exec.Cmd) error {
+	if !profile.Vault {
+		return runInteractive(ctx, cmd)
+	}
+	release, err := m.openVault(profile)
+	if err != nil {
+		return err
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				// The terminal already delivers Ctrl-C to the child.
+				if sig != os.Interrupt && cmd.Process != nil {
+					_ = cmd.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	runErr := runInteractive(ctx, cmd)
+	signal.Stop(sigs)
+	close(done)
+
+	if err := release(); err != nil {
+		if runErr != nil {
+			return runErr
+		}
+		return fmt.Errorf("re-seal %s/%s: %w", profile.Tool, profile.Name, err)
+	}
+	return runErr
+}
+
+// recoverCrashedRun re-seals credentials left in plaintext by a run whose
+// lease holders all died. Callers must hold the state lock, through
+// withVaultKey with sealKey as the resolver.
+func (m *Manager) recoverCrashedRun(profile store.Profile, need func() (secrets.Key, error)) (bool, error) {
+	if _, err := os.Stat(m.leasePath(profile)); err != nil {
+		return false, nil
+	}
+	if m.liveLease(profile) != nil {
+		return false, nil
+	}
+	key, err := need()
+	if err != nil {
+		return false, err
+	}
+	if err := m.sealCredentials(profile, key); err != nil {
+		return false, err
+	}
+	return true, removeIfExists(m.leasePath(profile))
+}
+
+// errVaultKeyNeeded is how work done under the state lock asks for a vault
+// key that was not resolved beforehand.
+var errVaultKeyNeeded = errors.New("vault key needed")
+
+// withVaultKey runs fn under the state lock. fn gets the key through need,
+// which fails with errVaultKeyNeeded until one is resolved; withVaultKey then
+// drops the lock, resolves the key and runs fn again. A passphrase prompt
+// thus never holds the lock and blocks other profilex commands, and none is
+// shown when fn needs no key. fn must ask for it before changing anything.
+func (m *Manager) withVaultKey(resolve func() (secrets.Key, error), fn func(need func() (secrets.Key, error)) error) error {
+	var key *secrets.Key
+	need := func() (secrets.Key, error) {
+		if key == nil {
+			return secrets.Key{}, errVaultKeyNeeded
+		}
+		return *key, nil
+	}
+	for {
+		err := m.store.WithLock(func() error { return fn(need) })
+		if key != nil || !errors.Is(err, errVaultKeyNeeded) {
+			return err
+		}
+		k, err := resolve()
+		if err != nil {
+			return err
+		}
+		key = &k
+	}
+}
+
+// sealKey picks the key for re-sealing: the one the existing blob was sealed
+// with, or the store default for a first seal.
+func (m *Manager) sealKey(profile store.Profile, prompt secrets.PromptFunc) (secrets.Key, error) {
+	vault := m.secrets.Vault()
+	if vault.Has(profile.Tool, profile.Name) {
+		return vault.UnlockKey(profile.Tool, profile.Name, prompt)
+	}
+	return m.secrets.ResolveKey(prompt, true)
+}
+
+// recoverCrashedRuns re-seals every vault profile a crashed run left in
+// plaintext, for commands that only report status. It never prompts: a
+// profile whose key needs a passphrase is recovered on its next launch or
+// `vault status` instead.
+func (m *Manager) recoverCrashedRuns(st *store.State) {
+	for _, p := range st.Profiles {
+		if !p.Vault {
+			continue
+		}
+		if _, err := os.Stat(m.leasePath(p)); err != nil {
+			continue
+		}
+		dir, err := m.validatedManagedProfileDir(p)
+		if err != nil {
+			continue
+		}
+		p.Dir = dir
+		resolve := func() (secrets.Key, error) { return m.sealKey(p, nil) }
+		_ = m.withVaultKey(resolve, func(need func() (secrets.Key, error)) error {
+			_, err := m.recoverCrashedRun(p, need)
+			return err
+		})
+	}
+}
+
+// sealCredentials encrypts the plaintext credential files into the vault and
+// wipes them. With no plaintext present the existing blob is kept.
+func (m *Manager) sealCredentials(profile store.Profile, key secrets.Key) error {
+	adapter, err := adapters.Get(profile.Tool)
+	if err != nil {
+		return err
+	}
+	files := map[string]string{}
+	for _, name := range adapter.CredentialFiles() {
+		b, err := os.ReadFile(filepath.Join(profile.Dir, name))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		files[name] = base64.StdEncoding.EncodeToString(b)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(files)
+	if err != nil {
+		return err
+	}
+	if err := m.secrets.Vault().Set(profile.Tool, profile.Name, string(payload), key); err != nil {
+		return err
+	}
+	for name := range files {
+		if err := wipeFile(filepath.Join(profile.Dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) unsealCredentials(profile store.Profile, key secrets.Key) error {
+	payload, ok, err := m.secrets.Vault().Get(profile.Tool, profile.Name, key)
+	if err != nil || !ok {
+		return err
+	}
+	files := map[string]string{}
+	if err := json.Unmarshal([]byte(payload), &files); err != nil {
+		return fmt.Errorf("read vault for %s/%s: %w", profile.Tool, profile.Name, err)
+	}
+	for name, encoded := range files {
+		b, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(profile.Dir, filepath.Base(name)), b, 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) plaintextCredentials(profile store.Profile) []string {
+	adapter, err := adapters.Get(profile.Tool)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, name := range adapter.CredentialFiles() {
+		if _, err := os.Stat(filepath.Join(profile.Dir, name)); err == nil {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// vaultStatus reports a sealed vault profile as logged in without unsealing
+// it; account details are unavailable until the next run.
+func (m *Manager) vaultStatus(profile store.Profile) (adapters.Status, bool) {
+	if !profile.Vault || len(m.plaintextCredentials(profile)) > 0 {
+		return adapters.Status{}, false
+	}
+	if !m.secrets.Vault().Has(profile.Tool, profile.Name) {
+		return adapters.Status{}, false
+	}
+	return adapters.Status{LoggedIn: true, Method: "vault", Source: adapters.SourceVault}, true
+}
+
+func (m *Manager) leasePath(profile store.Profile) string {
+	return filepath.Join(m.Root(), "run", string(profile.Tool), profile.Name+".lease")
+}
+
+// liveLease returns the profile's lease with dead holders dropped, or nil
+// when no live process holds it.
+func (m *Manager) liveLease(profile store.Profile) *RunLease {
+	b, err := os.ReadFile(m.leasePath(profile))
+	if err != nil {
+		return nil
+	}
+	var lease RunLease
+	if err := json.Unmarshal(b, &lease); err != nil {
+		return nil
+	}
+	live := []int{}
+	for _, pid := range lease.PIDs {
+		if store.ProcessAlive(pid) {
+			live = append(live, pid)
+		}
+	}
+	if len(live) == 0 {
+		return nil
+	}
+	lease.PIDs = live
+	return &lease
+}
+
+func (m *Manager) writeLease(profile store.Profile, lease *RunLease) error {
+	path := m.leasePath(profile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(lease, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
+
+func (m *Manager) lookupProfile(tool store.Tool, name string) (store.Profile, error) {
+	st, err := m.Load()
+	if err != nil {
+		return store.Profile{}, err
+	}
+	p, err := m.GetProfile(st, tool, name)
+	if err != nil {
+		return store.Profile{}, err
+	}
+	dir, err := m.validatedManagedProfileDir(p)
+	if err != nil {
+		return store.Profile{}, err
+	}
+	p.Dir = dir
+	return p, nil
+}
+
+func (m *Manager) setVault(tool store.Tool, name string, enabled bool) error {
+	return m.store.Update(func(st *store.State) error {
+		idx, p := store.FindProfile(st, tool, name)
+		if p == nil {
+			return fmt.Errorf("profile not found: %s/%s", tool, name)
+		}
+		st.Profiles[idx].Vault = enabled
+		return nil
+	})
+}
+
+// wipeFile overwrites path with zeros before removing it. This is best
+// effort on copy-on-write and journaling filesystems.
+func wipeFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		_, _ = f.Write(make([]byte, info.Size()))
+		_ = f.Sync()
+		_ = f.Close()
+	}
+	return os.Remove(path)
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
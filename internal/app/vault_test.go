@@ -0,0 +1,228 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/secrets"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func newVaultTestManager(t *testing.T) (*Manager, store.Profile) {
+	t.Helper()
+	m := newTestManager(t)
+	p, _, err := m.EnsureProfile(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(secrets.EnvKeyFile, "")
+	t.Setenv(secrets.EnvPassphrase, "")
+	if err := secrets.GenerateKeyFile(m.Secrets().DefaultKeyFile()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(p.Dir, ".credentials.json"), []byte(`{"claudeAiOauth":{"accessToken":"v1"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(p.Dir, ".claude.json"), []byte(`{"primaryApiKey":"sk-ant-test"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err = m.EnableVault(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, p
+}
+
+func TestVaultSealsBetweenRuns(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script stand-in for claude")
+	}
+	m, p := newVaultTestManager(t)
+	creds := filepath.Join(p.Dir, ".credentials.json")
+	if _, err := os.Stat(creds); !os.IsNotExist(err) {
+		t.Fatalf("credentials should be wiped after enable: %v", err)
+	}
+	// .claude.json holds the API key, so it is sealed too.
+	config := filepath.Join(p.Dir, ".claude.json")
+	if _, err := os.Stat(config); !os.IsNotExist(err) {
+		t.Fatalf(".claude.json should be wiped after enable: %v", err)
+	}
+
+	// The stand-in fails unless the credentials are present, then rotates
+	// the token the way a refresh would.
+	bin := t.TempDir()
+	script := `#!/bin/sh
+grep -q v1 "$CLAUDE_CONFIG_DIR/.credentials.json" || exit 3
+printf '{"claudeAiOauth":{"accessToken":"v2"}}' > "$CLAUDE_CONFIG_DIR/.credentials.json"
+`
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if err := m.RunTool(context.Background(), p, nil); err != nil {
+		t.Fatalf("run should see unsealed credentials: %v", err)
+	}
+	if _, err := os.Stat(creds); !os.IsNotExist(err) {
+		t.Fatalf("credentials should be re-sealed after the run: %v", err)
+	}
+	if _, err := os.Stat(m.leasePath(p)); !os.IsNotExist(err) {
+		t.Fatalf("lease should be released: %v", err)
+	}
+
+	if _, err := m.DisableVault(store.ToolClaude, "work"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(creds)
+	if err != nil || string(b) != `{"claudeAiOauth":{"accessToken":"v2"}}` {
+		t.Fatalf("expected refreshed credentials restored, got %q err=%v", b, err)
+	}
+	if b, err := os.ReadFile(config); err != nil || string(b) != `{"primaryApiKey":"sk-ant-test"}` {
+		t.Fatalf("expected .claude.json restored, got %q err=%v", b, err)
+	}
+}
+
+// simulateCrashedRun leaves p as a run that unsealed, refreshed its token
+// and then died without re-sealing.
+func simulateCrashedRun(t *testing.T, m *Manager, p store.Profile) string {
+	t.Helper()
+	dead := exec.Command(os.Args[0], "-test.run=^$")
+	if err := dead.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.writeLease(p, &RunLease{PIDs: []int{dead.Process.Pid}}); err != nil {
+		t.Fatal(err)
+	}
+	creds := filepath.Join(p.Dir, ".credentials.json")
+	if err := os.WriteFile(creds, []byte(`{"claudeAiOauth":{"accessToken":"v3"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return creds
+}
+
+func TestVaultStatusRecoversCrashedRun(t *testing.T) {
+	m, p := newVaultTestManager(t)
+	creds := simulateCrashedRun(t, m, p)
+
+	status, err := m.VaultStatus(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Recovered || !status.Sealed || len(status.Plaintext) != 0 || status.Lease != nil {
+		t.Fatalf("expected crashed run to be recovered, got %+v", status)
+	}
+	if _, err := os.Stat(creds); !os.IsNotExist(err) {
+		t.Fatalf("plaintext should be wiped on recovery: %v", err)
+	}
+}
+
+func TestStatusRowsRecoverCrashedRun(t *testing.T) {
+	m, p := newVaultTestManager(t)
+	creds := simulateCrashedRun(t, m, p)
+
+	if _, err := m.StatusRowsWith(context.Background(), nil, StatusOptions{Refresh: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(creds); !os.IsNotExist(err) {
+		t.Fatalf("listing profiles should re-seal a crashed run's plaintext: %v", err)
+	}
+	if _, err := os.Stat(m.leasePath(p)); !os.IsNotExist(err) {
+		t.Fatalf("the crashed run's lease should be cleared: %v", err)
+	}
+}
+
+func TestRunRecoversCrashedRunBeforeUnsealing(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script stand-in for claude")
+	}
+	m, p := newVaultTestManager(t)
+	creds := simulateCrashedRun(t, m, p)
+
+	bin := t.TempDir()
+	script := "#!/bin/sh\ngrep -q v3 \"$CLAUDE_CONFIG_DIR/.credentials.json\" || exit 3\n"
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if err := m.RunTool(context.Background(), p, nil); err != nil {
+		t.Fatalf("run should see the crashed run's refreshed token: %v", err)
+	}
+	if _, err := os.Stat(creds); !os.IsNotExist(err) {
+		t.Fatalf("credentials should be sealed after the run: %v", err)
+	}
+	if _, err := os.Stat(m.leasePath(p)); !os.IsNotExist(err) {
+		t.Fatalf("lease should be released: %v", err)
+	}
+}
+
+func TestRemoveVaultProfileRequiresPurge(t *testing.T) {
+	m, p := newVaultTestManager(t)
+	if err := m.RemoveProfile(store.ToolClaude, "work", false); err == nil {
+		t.Fatalf("expected remove without --purge to refuse while credentials are sealed")
+	}
+	if !m.Secrets().Vault().Has(store.ToolClaude, "work") {
+		t.Fatalf("refused remove must keep the sealed credentials")
+	}
+
+	if err := m.RemoveProfile(store.ToolClaude, "work", true); err != nil {
+		t.Fatal(err)
+	}
+	if m.Secrets().Vault().Has(store.ToolClaude, "work") {
+		t.Fatalf("expected purge to delete the sealed credentials")
+	}
+	p, _, err := m.EnsureProfile(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, ok, _ := m.OfflineStatusForProfile(p); ok && status.LoggedIn {
+		t.Fatalf("a re-added profile must not pick up the old credentials: %+v", status)
+	}
+}
+
+func TestVaultPassphrasePromptDoesNotHoldStateLock(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script stand-in for claude")
+	}
+	m := newTestManager(t)
+	p, _, err := m.EnsureProfile(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(secrets.EnvKeyFile, "")
+	t.Setenv(secrets.EnvPassphrase, "")
+	prompts := 0
+	m.SetPassphrasePrompt(func(bool) ([]byte, error) {
+		prompts++
+		if _, err := os.Stat(filepath.Join(m.Root(), "state.lock")); err == nil {
+			t.Errorf("passphrase prompt %d ran while holding the state lock", prompts)
+		}
+		return []byte("correct horse"), nil
+	})
+	if err := os.WriteFile(filepath.Join(p.Dir, ".credentials.json"), []byte(`{"claudeAiOauth":{"accessToken":"v1"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = m.EnableVault(store.ToolClaude, "work"); err != nil {
+		t.Fatal(err)
+	}
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if err := m.RunTool(context.Background(), p, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.DisableVault(store.ToolClaude, "work"); err != nil {
+		t.Fatal(err)
+	}
+	// Enable, unseal for the run and disable each ask once; the re-seal
+	// after the run reuses the key it was unsealed with.
+	if prompts != 3 {
+		t.Fatalf("expected 3 prompts, got %d", prompts)
+	}
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+type adapterRow struct {
+	Name       string   `json:"name"`
+	Binary     string   `json:"binary"`
+	BinaryPath string   `json:"binary_path,omitempty"`
+	Env        []string `json:"env"`
+	Source     string   `json:"source"`
+}
+
+func cmdAdapters(rootDir string, args []string) error {
+	if hasHelp(args) {
+		printAdaptersHelp()
+		return nil
+	}
+	jsonOut, rest := extractBool(args, "--json")
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", rest)
+	}
+	// Loading the manager registers definitions from adapters.d.
+	if _, err := newManager(rootDir); err != nil {
+		return err
+	}
+
+	rows := []adapterRow{}
+	for _, def := range adapters.Definitions() {
+		row := adapterRow{Name: def.Name, Binary: def.Binary, Env: []string{}, Source: def.Source}
+		for _, e := range def.EnvAssignments("") {
+			row.Env = append(row.Env, e.Name)
+		}
+		if path, err := exec.LookPath(def.Binary); err == nil {
+			row.BinaryPath = path
+		}
+		rows = append(rows, row)
+	}
+	if jsonOut {
+		b, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	for _, row := range rows {
+		found := Green("✓")
+		if row.BinaryPath == "" {
+			found = Yellow("⚠ not in PATH")
+		}
+		fmt.Printf("%s  %s %s  %s\n", renderToolBadge(store.Tool(row.Name)), row.Binary, found, Dim(strings.Join(row.Env, ", ")+" · "+row.Source))
+	}
+	return nil
+}
+
+func printAdaptersHelp() {
+	fmt.Printf("Usage:\n")
+	fmt.Printf("  profilex adapters [--json]\n\n")
+	fmt.Printf("Lists the built-in adapters plus definitions loaded from ~/.profilex/%s/*.json.\n", adapters.DefinitionDir)
+	fmt.Printf("A file reusing a built-in name overrides it. See docs/COMMANDS.md for the format.\n")
+}
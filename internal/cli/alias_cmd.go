@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/derekurban/profilex-cli/internal/app"
+	"github.com/derekurban/profilex-cli/internal/shim"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func cmdAlias(rootDir string, args []string) error {
+	if len(args) == 0 || hasHelp(args) {
+		printAliasHelp()
+		return nil
+	}
+
+	sub := args[0]
+	rest := args[1:]
+	switch sub {
+	case "add":
+		return cmdAliasAdd(rootDir, rest)
+	case "remove", "rm":
+		return cmdAliasRemove(rootDir, rest)
+	case "list":
+		return cmdAliasList(rootDir, rest)
+	case "template":
+		return cmdAliasTemplate(rootDir, rest)
+	default:
+		return fmt.Errorf("unknown alias subcommand %q", sub)
+	}
+}
+
+func printAliasHelp() {
+	fmt.Printf("Usage:\n")
+	fmt.Printf("  profilex alias add <alias> <tool> <profile> [--force]   Add a shim called <alias>\n")
+	fmt.Printf("  profilex alias remove <alias>                           Remove an alias and its shim\n")
+	fmt.Printf("  profilex alias list [--json]                            List aliases and template names\n")
+	fmt.Printf("  profilex alias template <tool> [<template>|--clear] [--force]\n")
+	fmt.Printf("                                                          Name an extra shim per profile, e.g. cc-{profile}\n\n")
+	fmt.Printf("Alias shims are installed next to a profile's <tool>-<profile> shim and follow it:\n")
+	fmt.Printf("they are created when it is installed and removed with `shim uninstall --all`.\n")
+	fmt.Printf("Names that already run another executable on PATH are refused unless --force is given.\n")
+}
+
+func cmdAliasAdd(rootDir string, args []string) error {
+	force, args := extractBool(args, "--force")
+	if hasHelp(args) || len(args) != 3 {
+		fmt.Printf("Usage: profilex alias add <alias> <tool> <profile> [--force]\n")
+		return nil
+	}
+	name := args[0]
+	tool, err := parseTool(args[1])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+	if _, err := mgr.GetProfile(st, tool, args[2]); err != nil {
+		return err
+	}
+	if err := checkShimNameFree(st, name, force); err != nil {
+		return err
+	}
+	if _, err := mgr.AddAlias(name, tool, args[2]); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s Alias %s → %s\n", Green("✓"), Bold(name), Bold(string(tool)+"/"+args[2]))
+	if !reportAliasShims(mgr) {
+		fmt.Printf("   💡 Run %s to create its shim.\n", Bold("profilex shim install"))
+	}
+	return nil
+}
+
+func cmdAliasRemove(rootDir string, args []string) error {
+	if hasHelp(args) || len(args) != 1 {
+		fmt.Printf("Usage: profilex alias remove <alias>\n")
+		return nil
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	alias, err := mgr.RemoveAlias(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s Removed alias %s %s\n", Green("✓"), Bold(alias.Name), Dim("(was "+string(alias.Tool)+"/"+alias.Profile+")"))
+	reportAliasShims(mgr)
+	return nil
+}
+
+type aliasEntry struct {
+	Name    string     `json:"name"`
+	Tool    store.Tool `json:"tool"`
+	Profile string     `json:"profile"`
+	// Source is "alias" or "template".
+	Source string `json:"source"`
+	// Shadows is another executable on PATH with the same name.
+	Shadows string `json:"shadows,omitempty"`
+}
+
+func cmdAliasList(rootDir string, args []string) error {
+	jsonOut, args := extractBool(args, "--json")
+	if hasHelp(args) || len(args) != 0 {
+		fmt.Printf("Usage: profilex alias list [--json]\n")
+		return nil
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+	dir, _ := shim.DefaultShimDir()
+	entries := []aliasEntry{}
+	for _, t := range shim.Targets(st) {
+		if !t.Alias {
+			continue
+		}
+		e := aliasEntry{Name: t.Name, Tool: t.Profile.Tool, Profile: t.Profile.Name, Source: "template"}
+		if _, a := store.FindAlias(st, t.Name); a != nil {
+			e.Source = "alias"
+		}
+		if path, ok := shim.PathCollision(dir, t.Name); ok {
+			e.Shadows = path
+		}
+		entries = append(entries, e)
+	}
+
+	if jsonOut {
+		b, _ := json.MarshalIndent(map[string]any{
+			"aliases":   entries,
+			"templates": st.ShimNameTemplates,
+		}, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+	if len(entries) == 0 && len(st.ShimNameTemplates) == 0 {
+		fmt.Printf("No aliases defined.\n")
+		return nil
+	}
+	for _, tool := range store.SupportedTools {
+		if tmpl := st.ShimNameTemplates[tool]; tmpl != "" {
+			fmt.Printf("  %s template: %s\n", Bold(string(tool)), tmpl)
+		}
+	}
+	for _, e := range entries {
+		line := fmt.Sprintf("  %-20s → %s/%s", e.Name, e.Tool, e.Profile)
+		if e.Source == "template" {
+			line += " " + Dim("(template)")
+		}
+		if e.Shadows != "" {
+			line += fmt.Sprintf("  %s also on PATH: %s", Yellow("⚠"), e.Shadows)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func cmdAliasTemplate(rootDir string, args []string) error {
+	clear, args := extractBool(args, "--clear")
+	force, args := extractBool(args, "--force")
+	if hasHelp(args) || len(args) < 1 || len(args) > 2 || (clear && len(args) == 2) {
+		fmt.Printf("Usage: profilex alias template <tool> [<template>|--clear] [--force]\n")
+		fmt.Printf("{tool} and {profile} are replaced; {profile} is required.\n")
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 && !clear {
+		if tmpl := st.ShimNameTemplates[tool]; tmpl != "" {
+			fmt.Println(tmpl)
+		} else {
+			fmt.Printf("No shim name template for %s.\n", tool)
+		}
+		return nil
+	}
+
+	tmpl := ""
+	if !clear {
+		tmpl = args[1]
+		if err := store.ValidateShimNameTemplate(tmpl); err != nil {
+			return err
+		}
+		for _, p := range st.Profiles {
+			if p.Tool != tool {
+				continue
+			}
+			name := store.ExpandShimNameTemplate(tmpl, tool, p.Name)
+			if owner, ok := shim.NameOwner(st, name); ok && owner.Profile.Tool == tool && owner.Profile.Name == p.Name {
+				continue
+			}
+			if err := checkShimNameFree(st, name, force); err != nil {
+				return err
+			}
+		}
+	}
+	if err := mgr.SetShimNameTemplate(tool, tmpl); err != nil {
+		return err
+	}
+	if clear {
+		fmt.Printf("%s Cleared the shim name template for %s\n", Green("✓"), Bold(string(tool)))
+	} else {
+		fmt.Printf("%s %s profiles also get a %s shim\n", Green("✓"), Bold(string(tool)), Bold(tmpl))
+	}
+	reportAliasShims(mgr)
+	return nil
+}
+
+// checkShimNameFree refuses names another shim already uses, and names that
+// already run some other executable on PATH unless force is set.
+func checkShimNameFree(st *store.State, name string, force bool) error {
+	if owner, ok := shim.NameOwner(st, name); ok {
+		return fmt.Errorf("shim name %s is already used by %s/%s", name, owner.Profile.Tool, owner.Profile.Name)
+	}
+	dir, err := shim.DefaultShimDir()
+	if err != nil {
+		return err
+	}
+	if path, ok := shim.PathCollision(dir, name); ok {
+		if !force {
+			return fmt.Errorf("%s already runs %s; pick another name or use --force", name, path)
+		}
+		fmt.Fprintf(os.Stderr, "%s profilex: %s also runs %s; PATH order decides which one wins\n", Yellow("⚠"), name, path)
+	}
+	return nil
+}
+
+// reportAliasShims brings alias shims in line with state and prints what it
+// created or removed. It reports whether anything was created.
+func reportAliasShims(mgr *app.Manager) bool {
+	fixed, err := repairShimDrift(mgr)
+	if err != nil {
+		fmt.Printf("   %s Could not update shims: %v\n", Yellow("⚠"), err)
+	}
+	created := false
+	for _, s := range fixed {
+		switch s.State {
+		case shim.StateOrphaned:
+			fmt.Printf("   removed: %s\n", Dim(s.Path))
+		default:
+			fmt.Printf("   🔗 Shim:   %s\n", Cyan(s.Path))
+			if s.Alias {
+				created = true
+			}
+		}
+	}
+	return created
+}
@@ -0,0 +1,308 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/app"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func cmdAuth(rootDir string, args []string) error {
+	if len(args) == 0 || hasHelp(args) {
+		printAuthHelp()
+		return nil
+	}
+
+	sub := args[0]
+	rest := args[1:]
+	switch sub {
+	case "check":
+		return cmdAuthCheck(rootDir, rest)
+	default:
+		return fmt.Errorf("unknown auth subcommand %q", sub)
+	}
+}
+
+func printAuthHelp() {
+	fmt.Printf("Usage:\n")
+	fmt.Printf("  profilex auth check [--tool <tool>] [--within <age>] [--json]\n\n")
+	fmt.Printf("Scan every profile's credentials offline and flag expired or expiring OAuth tokens.\n")
+	fmt.Printf("Exits with code 1 when any profile needs attention.\n\n")
+	fmt.Printf("Options:\n")
+	fmt.Printf("  --within <age>   Warning window (default: $PROFILEX_AUTH_WARN_WINDOW or 24h)\n")
+}
+
+func cmdAuthCheck(rootDir string, args []string) error {
+	toolFlag, args := extractFlag(args, "--tool")
+	withinRaw, args := extractFlag(args, "--within")
+	jsonOut, args := extractBool(args, "--json")
+	if hasHelp(args) {
+		printAuthHelp()
+		return nil
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unknown argument(s): %s", strings.Join(args, " "))
+	}
+
+	var filter *store.Tool
+	if toolFlag != "" {
+		t, err := parseTool(toolFlag)
+		if err != nil {
+			return err
+		}
+		filter = &t
+	}
+	window, err := authWarnWindow()
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(withinRaw) != "" {
+		if window, err = parseAge(withinRaw); err != nil {
+			return err
+		}
+	}
+
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	checks, err := mgr.CheckAuth(filter, window)
+	if err != nil {
+		return err
+	}
+
+	attention := 0
+	for _, c := range checks {
+		if c.Health.NeedsAttention() {
+			attention++
+		}
+	}
+
+	if jsonOut {
+		payload := map[string]any{
+			"window":    window.String(),
+			"attention": attention,
+			"profiles":  checks,
+		}
+		b, _ := json.MarshalIndent(payload, "", "  ")
+		fmt.Println(string(b))
+	} else {
+		if len(checks) == 0 {
+			fmt.Printf("No profiles found.\n")
+		}
+		now := time.Now()
+		for _, c := range checks {
+			label := string(c.Profile.Tool) + "/" + c.Profile.Name
+			fmt.Printf("  %s %-24s %s\n", authHealthIcon(c.Health), label, authHealthText(c.Health, c.Status, now))
+			if c.Error != "" {
+				fmt.Printf("    %s\n", Dim(c.Error))
+			}
+		}
+	}
+
+	if attention > 0 {
+		return app.ExitCodeError{Code: 1}
+	}
+	return nil
+}
+
+// authWarnWindow resolves the expiry warning window from
+// PROFILEX_AUTH_WARN_WINDOW, defaulting to app.DefaultAuthWarnWindow.
+func authWarnWindow() (time.Duration, error) {
+	raw := strings.TrimSpace(os.Getenv("PROFILEX_AUTH_WARN_WINDOW"))
+	if raw == "" {
+		return app.DefaultAuthWarnWindow, nil
+	}
+	d, err := parseAge(raw)
+	if err != nil {
+		return 0, fmt.Errorf("PROFILEX_AUTH_WARN_WINDOW: %w", err)
+	}
+	return d, nil
+}
+
+func authHealthIcon(h app.AuthHealth) string {
+	switch h {
+	case app.AuthHealthOK:
+		return Green("●")
+	case app.AuthHealthExpiring:
+		return Yellow("⚠")
+	case app.AuthHealthExpired:
+		return Red("✗")
+	default:
+		return Dim("○")
+	}
+}
+
+func authHealthText(h app.AuthHealth, st adapters.Status, now time.Time) string {
+	switch h {
+	case app.AuthHealthExpiring:
+		return Yellow(describeExpiry(*st.ExpiresAt, now))
+	case app.AuthHealthExpired:
+		return Red(describeExpiry(*st.ExpiresAt, now))
+	case app.AuthHealthOK:
+		if st.ExpiresAt != nil && !st.Refreshable {
+			return Green("ok") + " " + Dim(describeExpiry(*st.ExpiresAt, now))
+		}
+		return Green("ok")
+	case app.AuthHealthLoggedOut:
+		return Dim("not authenticated")
+	default:
+		return Dim("no credentials found")
+	}
+}
+
+// warnAuthExpiry prints a one-line stderr warning for a profile whose OAuth
+// token is expired or about to expire. Errors are ignored: the warning must
+// never block a launch.
+func warnAuthExpiry(mgr *app.Manager, profile store.Profile) {
+	window, err := authWarnWindow()
+	if err != nil {
+		return
+	}
+	status, ok, err := mgr.OfflineStatusForProfile(profile)
+	if err != nil || !ok {
+		return
+	}
+	health := app.EvaluateAuth(status, time.Now(), window)
+	if !health.NeedsAttention() {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s profilex: %s/%s %s; run `profilex login %s %s` to sign in again\n",
+		Yellow("⚠"), profile.Tool, profile.Name, describeExpiry(*status.ExpiresAt, time.Now()), profile.Tool, profile.Name)
+}
+
+// --- login / logout ---
+
+func cmdLogin(rootDir string, args []string) error {
+	apiKey, args := extractFlag(args, "--api-key")
+	if hasHelp(args) || len(args) < 1 || len(args) > 2 {
+		fmt.Printf("Usage: profilex login <tool> [profile] [--api-key <key>|-]\n\n")
+		fmt.Printf("Run the tool's login flow under the profile's environment.\n")
+		fmt.Printf("Pass --api-key - to read the key from stdin.\n")
+		return nil
+	}
+
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	profileOptional := ""
+	if len(args) == 2 {
+		profileOptional = args[1]
+	}
+
+	if apiKey == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		apiKey = string(b)
+	}
+	apiKey = strings.TrimSpace(apiKey)
+
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+	profile, err := mgr.ResolveProfile(st, tool, profileOptional)
+	if err != nil {
+		return err
+	}
+
+	status, err := mgr.Login(context.Background(), profile, adapters.LoginOptions{APIKey: apiKey})
+	if err != nil {
+		return err
+	}
+	printAuthResult("Logged in", profile, status)
+	return nil
+}
+
+func cmdLogout(rootDir string, args []string) error {
+	all, args := extractBool(args, "--all")
+	toolFlag, args := extractFlag(args, "--tool")
+	if hasHelp(args) || (!all && (len(args) < 1 || len(args) > 2)) || (all && len(args) > 0) {
+		fmt.Printf("Usage:\n")
+		fmt.Printf("  profilex logout <tool> [profile]\n")
+		fmt.Printf("  profilex logout --all [--tool <tool>]\n")
+		return nil
+	}
+
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+
+	var profiles []store.Profile
+	if all {
+		var filter *store.Tool
+		if toolFlag != "" {
+			t, err := parseTool(toolFlag)
+			if err != nil {
+				return err
+			}
+			filter = &t
+		}
+		for _, p := range st.Profiles {
+			if filter == nil || p.Tool == *filter {
+				profiles = append(profiles, p)
+			}
+		}
+		if len(profiles) == 0 {
+			fmt.Printf("No profiles found.\n")
+			return nil
+		}
+	} else {
+		tool, err := parseTool(args[0])
+		if err != nil {
+			return err
+		}
+		profileOptional := ""
+		if len(args) == 2 {
+			profileOptional = args[1]
+		}
+		p, err := mgr.ResolveProfile(st, tool, profileOptional)
+		if err != nil {
+			return err
+		}
+		profiles = append(profiles, p)
+	}
+
+	failures := 0
+	for _, p := range profiles {
+		status, err := mgr.Logout(context.Background(), p)
+		if err != nil {
+			failures++
+			fmt.Printf("   %s Failed to log out %s/%s: %v\n", Yellow("!"), p.Tool, p.Name, err)
+			continue
+		}
+		printAuthResult("Logged out", p, status)
+	}
+	if failures > 0 {
+		return fmt.Errorf("failed to log out %d profile(s)", failures)
+	}
+	return nil
+}
+
+func printAuthResult(verb string, profile store.Profile, status adapters.Status) {
+	fmt.Printf("%s %s %s/%s\n", Green("✓"), verb, profile.Tool, Bold(profile.Name))
+	if status.LoggedIn {
+		if summary := accountSummary(status, time.Now()); summary != "" {
+			fmt.Printf("   👤 %s\n", Dim(summary))
+		}
+	}
+}
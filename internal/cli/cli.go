@@ -63,8 +63,46 @@ func Run(args []string) int {
 		err = cmdRename(rootDir, rest)
 	case "shim":
 		err = cmdShim(rootDir, rest)
+	case "alias":
+		err = cmdAlias(rootDir, rest)
+	case "term":
+		err = cmdTerm(rootDir, rest)
+	case "prompt":
+		err = cmdPrompt(rootDir, rest)
+	case "shell-init":
+		err = cmdShellInit(rootDir, rest)
+	case "completion":
+		err = cmdCompletion(rest)
+	case "env":
+		err = cmdEnv(rootDir, rest)
+	case "shell":
+		err = cmdShell(rootDir, rest)
+	case "__complete":
+		err = cmdComplete(rootDir, rest)
 	case "usage":
 		err = cmdUsage(rootDir, rest)
+	case "sessions":
+		err = cmdSessions(rootDir, rest)
+	case "du":
+		err = cmdDU(rootDir, rest)
+	case "auth":
+		err = cmdAuth(rootDir, rest)
+	case "secret":
+		err = cmdSecret(rootDir, rest)
+	case "vault":
+		err = cmdVault(rootDir, rest)
+	case "isolation":
+		err = cmdIsolation(rootDir, rest)
+	case "adapters":
+		err = cmdAdapters(rootDir, rest)
+	case "pin":
+		err = cmdPin(rootDir, rest)
+	case "doctor":
+		err = cmdDoctor(rootDir, rest)
+	case "login":
+		err = cmdLogin(rootDir, rest)
+	case "logout":
+		err = cmdLogout(rootDir, rest)
 	case "settings":
 		err = cmdSettings(rootDir, rest)
 	case "tui":
@@ -101,9 +139,28 @@ func printHelp() {
   use <tool> <profile>          Set the default profile for a tool
   rename <tool> <old> <new>     Rename a profile
   run <tool> [profile] -- ...   Run a tool with the given profile
+  shell <tool> [profile]        Open $SHELL with the profile's environment
+  env <tool> [profile] --shell  Print the profile's environment for eval
+  sessions export <id> [...]    Export a session transcript as md, html or json
+  sessions prune --older-than   Prune (and optionally archive) old sessions
+  du [--tool <t>] [--json]      Report disk use per profile and shared pool
+  login <tool> [profile]        Log a profile in (--api-key for API key login)
+  logout <tool> [profile]       Log a profile out (--all for every profile)
+  secret set <tool> <profile>   Store an encrypted API key for a profile
+  vault enable|disable|status   Encrypt a profile's credentials while idle
+  isolation <tool> <profile>    Show or set inherited env var stripping
+  auth check [--json]           Flag expired or expiring OAuth tokens
+  adapters [--json]             List built-in and custom tool adapters
+  pin <tool> [profile] --path   Pin the tool binary a profile (or tool) runs
+  doctor [--json]               Check binaries, versions and shim setup
   settings <subcommand>         Manage settings snapshots/presets/apply
   shim install [--dir <d>]      Reinstall shims for all profiles
   shim uninstall [--all]        Remove shims
+  alias add|remove|list         Extra shim names such as cw for claude-work
+  term enable|disable|color     Terminal title, tmux, iTerm and WezTerm integrations
+  prompt [--format <f>]         Print the active profile and today's usage for a status line
+  shell-init <shell> [--prompt] Print profile functions for bash/zsh/fish/pwsh
+  completion <shell>            Print a completion script for bash/zsh/fish/pwsh
   tui                           Launch interactive terminal UI
   usage export [options]        Export unified local usage bundle (for ProfileX-UI)
 
@@ -186,6 +243,7 @@ func cmdAdd(rootDir string, args []string) error {
 	}
 
 	shimPath, shimErr := installShimForProfile(profile)
+	repaired, repairErr := repairShimDrift(mgr)
 
 	fmt.Printf("%s Created profile %s\n", Green("✓"), Bold(string(tool)+"/"+profile.Name))
 	fmt.Printf("   📁 Config: %s\n", Dim(profile.Dir))
@@ -211,6 +269,7 @@ func cmdAdd(rootDir string, args []string) error {
 	} else {
 		fmt.Printf("   🔗 Shim:   %s\n", Cyan(shimPath))
 	}
+	reportShimRepairs(repaired, repairErr)
 
 	shimName := shim.Name(tool, profile.Name)
 	fmt.Println()
@@ -284,6 +343,7 @@ func cmdRemove(rootDir string, args []string) error {
 	shimName := shim.Name(tool, args[1])
 	fmt.Printf("%s Removed profile %s\n", Green("✓"), Bold(string(tool)+"/"+args[1]))
 	fmt.Printf("   Shim %s has been uninstalled.\n", Cyan(shimName))
+	reportShimRepairs(repairShimDrift(mgr))
 	if purge {
 		fmt.Printf("   Profile directory purged from disk.\n")
 	}
@@ -361,10 +421,11 @@ func cmdUninstall(rootDir string, args []string) error {
 
 func cmdList(rootDir string, args []string) error {
 	toolFlag, args := extractFlag(args, "--tool")
-	jsonOut, _ := extractBool(args, "--json")
+	jsonOut, args := extractBool(args, "--json")
+	refresh, args := extractBool(args, "--refresh")
 
 	if hasHelp(args) {
-		fmt.Printf("Usage: profilex list [--tool <tool>] [--json]\n")
+		fmt.Printf("Usage: profilex list [--tool <tool>] [--refresh] [--json]\n")
 		return nil
 	}
 
@@ -387,10 +448,36 @@ func cmdList(rootDir string, args []string) error {
 		filter = &t
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	window, err := authWarnWindow()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	rows, err := mgr.StatusRows(ctx, filter)
+	matching := 0
+	for _, p := range st.Profiles {
+		if filter == nil || p.Tool == *filter {
+			matching++
+		}
+	}
+	if matching == 0 && !jsonOut {
+		fmt.Printf("No profiles found.\n\n")
+		fmt.Printf("💡 Get started: %s\n", Bold("profilex add claude <profile-name>"))
+		return nil
+	}
+
+	opts := app.StatusOptions{Refresh: refresh}
+	printer := &listPrinter{st: st, window: window, pending: map[int]app.StatusRow{}}
+	if !jsonOut {
+		fmt.Printf("%s\n\n", Bold("📋 Profiles"))
+		// Rows are printed in state order as soon as every earlier row is
+		// done, so slow probes do not hold back the ones before them.
+		opts.OnRow = printer.add
+	}
+
+	rows, err := mgr.StatusRowsWith(ctx, filter, opts)
 	if err != nil {
 		return err
 	}
@@ -402,55 +489,143 @@ func cmdList(rootDir string, args []string) error {
 		return nil
 	}
 
-	if len(rows) == 0 {
-		fmt.Printf("No profiles found.\n\n")
-		fmt.Printf("💡 Get started: %s\n", Bold("profilex add claude <profile-name>"))
-		return nil
+	fmt.Println()
+	if len(printer.hints) > 0 {
+		fmt.Printf("💡 Run %s to launch with that profile.\n", Bold(printer.hints[0]))
 	}
 
-	fmt.Printf("%s\n\n", Bold("📋 Profiles"))
+	return nil
+}
 
-	// Group by tool
-	var currentTool store.Tool
-	var hints []string
-	for _, r := range rows {
-		if r.Profile.Tool != currentTool {
-			if currentTool != "" {
-				fmt.Println()
-			}
-			currentTool = r.Profile.Tool
-			fmt.Printf("  %s\n", Bold(string(currentTool)))
-		}
+// listPrinter prints status rows in order while they arrive out of order
+// from the probe workers.
+type listPrinter struct {
+	st          *store.State
+	window      time.Duration
+	pending     map[int]app.StatusRow
+	next        int
+	currentTool store.Tool
+	hints       []string
+}
 
-		isDefault := st.Defaults[r.Profile.Tool] == r.Profile.Name
-		var icon, status, suffix string
+func (lp *listPrinter) add(index int, row app.StatusRow) {
+	lp.pending[index] = row
+	for {
+		r, ok := lp.pending[lp.next]
+		if !ok {
+			return
+		}
+		delete(lp.pending, lp.next)
+		lp.next++
+		lp.print(r)
+	}
+}
 
-		if r.Error != "" {
+func (lp *listPrinter) print(r app.StatusRow) {
+	if r.Profile.Tool != lp.currentTool {
+		if lp.currentTool != "" {
+			fmt.Println()
+		}
+		lp.currentTool = r.Profile.Tool
+		fmt.Printf("  %s\n", Bold(string(lp.currentTool)))
+	}
+
+	isDefault := lp.st.Defaults[r.Profile.Tool] == r.Profile.Name
+	var icon, status, suffix string
+
+	if r.Error != "" {
+		icon = Yellow("⚠")
+		status = Dim("error")
+	} else if r.Status.LoggedIn {
+		switch app.EvaluateAuth(r.Status, time.Now(), lp.window) {
+		case app.AuthHealthExpired:
+			icon = Red("✗")
+			status = Red("token expired")
+		case app.AuthHealthExpiring:
 			icon = Yellow("⚠")
-			status = Dim("error")
-		} else if r.Status.LoggedIn {
+			status = Yellow("token expiring")
+		default:
 			icon = Green("●")
 			status = Green("logged in")
-		} else {
-			icon = Dim("○")
-			status = Dim("not authenticated")
 		}
+	} else {
+		icon = Dim("○")
+		status = Dim("not authenticated")
+	}
 
-		if isDefault {
-			suffix = " " + Cyan("(default)")
-		}
+	if isDefault {
+		suffix = " " + Cyan("(default)")
+	}
 
-		shimName := shim.Name(r.Profile.Tool, r.Profile.Name)
-		fmt.Printf("    %s %-20s %s%s\n", icon, r.Profile.Name, status, suffix)
-		hints = append(hints, shimName)
+	fmt.Printf("    %s %-20s %s%s\n", icon, r.Profile.Name, status, suffix)
+	details := accountSummary(r.Status, time.Now())
+	if v := versionSummary(r, lp.st); v != "" {
+		details = strings.TrimPrefix(details+" · "+v, " · ")
+	}
+	if details != "" {
+		fmt.Printf("      %-20s %s\n", "", Dim(details))
+	}
+	if r.BinaryPath == "" && r.BinaryError != "" {
+		fmt.Printf("      %-20s %s\n", "", Red("✗ "+r.BinaryError))
 	}
+	lp.hints = append(lp.hints, shim.Name(r.Profile.Tool, r.Profile.Name))
+}
 
-	fmt.Println()
-	if len(hints) > 0 {
-		fmt.Printf("💡 Run %s to launch with that profile.\n", Bold(hints[0]))
+// versionSummary renders the tool version a profile runs, noting pins.
+func versionSummary(r app.StatusRow, st *store.State) string {
+	if r.Version == "" {
+		return ""
+	}
+	v := r.Version
+	if v[0] >= '0' && v[0] <= '9' {
+		v = "v" + v
+	}
+	if _, scope := st.BinaryFor(r.Profile); scope != "" {
+		v += " (pinned)"
 	}
+	return v
+}
 
-	return nil
+// accountSummary renders the account details known for a status as a single
+// "email · org · plan · auth · expiry" line.
+func accountSummary(st adapters.Status, now time.Time) string {
+	parts := []string{}
+	for _, v := range []string{st.Email, st.Organization, st.Plan} {
+		if strings.TrimSpace(v) != "" {
+			parts = append(parts, v)
+		}
+	}
+	switch st.AuthKind {
+	case adapters.AuthAPIKey:
+		parts = append(parts, "API key")
+	case adapters.AuthOAuth:
+		parts = append(parts, "OAuth")
+	}
+	if st.ExpiresAt != nil && !st.Refreshable {
+		parts = append(parts, describeExpiry(*st.ExpiresAt, now))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func describeExpiry(at, now time.Time) string {
+	d := at.Sub(now)
+	if d <= 0 {
+		return "token expired " + humanDuration(-d) + " ago"
+	}
+	return "token expires in " + humanDuration(d)
+}
+
+func humanDuration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return "<1m"
+	}
 }
 
 // --- use ---
@@ -476,6 +651,7 @@ func cmdUse(rootDir string, args []string) error {
 	}
 
 	fmt.Printf("%s Default for %s set to %s\n", Green("✓"), Bold(string(tool)), Bold(args[1]))
+	reportShimRepairs(repairShimDrift(mgr))
 	return nil
 }
 
@@ -522,6 +698,7 @@ func cmdRename(rootDir string, args []string) error {
 	fmt.Printf("%s Renamed %s to %s\n", Green("✓"),
 		Bold(string(tool)+"/"+oldName),
 		Bold(string(tool)+"/"+newName))
+	reportShimRepairs(repairShimDrift(mgr))
 
 	return nil
 }
@@ -566,6 +743,10 @@ func cmdRun(rootDir string, args []string) error {
 		return err
 	}
 
+	if _, err := warnEnvConflicts(mgr, profile); err != nil {
+		return err
+	}
+	syncSettingsBeforeLaunch(mgr, profile)
 	return mgr.RunTool(context.Background(), profile, toolArgs)
 }
 
@@ -574,8 +755,9 @@ func cmdRun(rootDir string, args []string) error {
 func cmdShim(rootDir string, args []string) error {
 	if len(args) == 0 || hasHelp(args) {
 		fmt.Printf("Usage:\n")
-		fmt.Printf("  profilex shim install [--dir <d>]\n")
-		fmt.Printf("  profilex shim env <tool> <profile>\n")
+		fmt.Printf("  profilex shim install [--dir <d>] [--force] [--compiled|--dynamic]\n")
+		fmt.Printf("  profilex shim status [--dir <d>] [--json]\n")
+		fmt.Printf("  profilex shim env [--compile] <tool> <profile>\n")
 		fmt.Printf("  profilex shim uninstall [--all] [<tool> <profile>]\n")
 		return nil
 	}
@@ -586,6 +768,8 @@ func cmdShim(rootDir string, args []string) error {
 	switch sub {
 	case "install":
 		return cmdShimInstall(rootDir, rest)
+	case "status":
+		return cmdShimStatus(rootDir, rest)
 	case "env":
 		return cmdShimEnv(rootDir, rest)
 	case "uninstall":
@@ -596,8 +780,9 @@ func cmdShim(rootDir string, args []string) error {
 }
 
 func cmdShimEnv(rootDir string, args []string) error {
+	compile, args := extractBool(args, "--compile")
 	if hasHelp(args) || len(args) != 2 {
-		fmt.Printf("Usage: profilex shim env <tool> <profile>\n")
+		fmt.Printf("Usage: profilex shim env [--compile] <tool> <profile>\n")
 		return nil
 	}
 
@@ -621,21 +806,42 @@ func cmdShimEnv(rootDir string, args []string) error {
 		return err
 	}
 
-	adapter, err := adapters.Get(tool)
+	// Warnings go to stderr so the KEY=VALUE stream stays parseable.
+	if profile.Vault {
+		fmt.Fprintf(os.Stderr, "%s profilex: %s/%s is a vault profile; run `profilex shim install` so its shim unseals credentials\n",
+			Yellow("⚠"), profile.Tool, profile.Name)
+	}
+	syncSettingsBeforeLaunch(mgr, profile)
+	env, err := profileEnv(mgr, st, profile)
 	if err != nil {
 		return err
 	}
+	if compile {
+		// The sidecar only saves the next launch some time, so failing to
+		// write it must not fail this one.
+		if compiled, ok := compiledProfileEnv(st, profile); ok && st.CompiledShims {
+			_ = shim.WriteCompiled(mgr.Root(), profile, st.Generation, compiled)
+		} else {
+			_ = shim.RemoveCompiled(mgr.Root(), profile)
+		}
+	}
 
-	// Output plain KEY=VALUE lines for shim scripts to import.
-	fmt.Printf("%s=%s\n", adapter.EnvVar(), profile.Dir)
-	fmt.Printf("PROFILEX_TOOL=%s\n", tool)
-	fmt.Printf("PROFILEX_PROFILE=%s\n", profile.Name)
-	fmt.Printf("PROFILEX_SHIM_NAME=%s\n", shim.Name(tool, profile.Name))
+	// Output plain KEY=VALUE lines for shim scripts to import. A bare KEY=
+	// line tells the shim to unset an inherited variable.
+	for _, e := range env {
+		fmt.Println(e)
+	}
 	return nil
 }
 
 func cmdShimInstall(rootDir string, args []string) error {
-	dir, _ := extractFlag(args, "--dir")
+	dir, args := extractFlag(args, "--dir")
+	force, args := extractBool(args, "--force")
+	compiled, args := extractBool(args, "--compiled")
+	dynamic, _ := extractBool(args, "--dynamic")
+	if compiled && dynamic {
+		return fmt.Errorf("--compiled and --dynamic cannot be combined")
+	}
 	if dir == "" {
 		d, err := shim.DefaultShimDir()
 		if err != nil {
@@ -654,27 +860,165 @@ func cmdShimInstall(rootDir string, args []string) error {
 		return err
 	}
 
+	if compiled || dynamic {
+		if err := mgr.SetCompiledShims(compiled); err != nil {
+			return err
+		}
+		st.CompiledShims = compiled
+	}
+	// Compiled environments are rebuilt on the next launch of each shim,
+	// which also picks up edits to adapters.d that state does not track.
+	if err := shim.ClearCompiled(mgr.Root()); err != nil {
+		return err
+	}
+
 	bin := resolveProfileXBin()
-	count := 0
-	failures := 0
-	for _, p := range st.Profiles {
-		path, err := shim.Install(dir, p, bin)
+	targets := shim.Targets(st)
+	statuses, err := shim.Check(dir, targets, bin)
+	if err != nil {
+		return err
+	}
+	foreign := map[string]bool{}
+	for _, s := range statuses {
+		if s.State == shim.StateForeign {
+			foreign[s.Path] = true
+		}
+	}
+
+	written, unchanged, skipped, failures := 0, 0, 0, 0
+	for _, t := range targets {
+		if path := shim.Path(dir, t.Name); foreign[path] && !force {
+			skipped++
+			fmt.Printf("   %s Skipped %s: not written by profilex (use --force to replace)\n", Yellow("!"), path)
+			continue
+		}
+		path, changed, err := shim.Ensure(dir, t, bin)
 		if err != nil {
 			failures++
-			fmt.Printf("   %s Failed to install shim for %s/%s: %v\n", Yellow("!"), p.Tool, p.Name, err)
+			fmt.Printf("   %s Failed to install shim %s for %s/%s: %v\n", Yellow("!"), t.Name, t.Profile.Tool, t.Profile.Name, err)
+			continue
+		}
+		if !changed {
+			unchanged++
 			continue
 		}
 		fmt.Printf("   -> %s\n", Cyan(path))
-		count++
+		written++
+	}
+	// Orphans are only judged against the state shims actually read.
+	removed := 0
+	for _, s := range statuses {
+		if s.State == shim.StateOrphaned && usesDefaultRoot(mgr) {
+			if err := os.Remove(s.Path); err == nil {
+				fmt.Printf("   removed orphaned: %s\n", Dim(s.Path))
+				removed++
+			}
+		}
 	}
 
-	fmt.Printf("\n%s Installed %d shim(s) in %s\n", Green("ok"), count, Dim(dir))
+	fmt.Printf("\n%s Installed %d shim(s) in %s", Green("ok"), written, Dim(dir))
+	if unchanged > 0 {
+		fmt.Printf(", %d already up to date", unchanged)
+	}
+	if removed > 0 {
+		fmt.Printf(", removed %d orphaned", removed)
+	}
+	fmt.Println()
+	if st.CompiledShims {
+		fmt.Printf("   Compiled mode: shims reuse their resolved environment until state changes %s\n", Dim("(--dynamic to turn off)"))
+	}
 	if failures > 0 {
 		return fmt.Errorf("failed to install %d shim(s)", failures)
 	}
+	if skipped > 0 {
+		return fmt.Errorf("skipped %d shim(s) that profilex did not write", skipped)
+	}
+	return nil
+}
+
+func cmdShimStatus(rootDir string, args []string) error {
+	dir, args := extractFlag(args, "--dir")
+	jsonOut, args := extractBool(args, "--json")
+	if hasHelp(args) || len(args) != 0 {
+		fmt.Printf("Usage: profilex shim status [--dir <d>] [--json]\n")
+		fmt.Printf("Exits with code 1 when any shim is missing, stale, foreign or orphaned.\n")
+		return nil
+	}
+	if dir == "" {
+		d, err := shim.DefaultShimDir()
+		if err != nil {
+			return err
+		}
+		dir = d
+	}
+
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+	statuses, err := shim.Check(dir, shim.Targets(st), resolveProfileXBin())
+	if err != nil {
+		return err
+	}
+	drift := 0
+	for _, s := range statuses {
+		if s.State != shim.StateOK {
+			drift++
+		}
+	}
+
+	if jsonOut {
+		b, _ := json.MarshalIndent(statuses, "", "  ")
+		fmt.Println(string(b))
+	} else {
+		fmt.Printf("\n%s %s\n\n", Bold("🔗 Shims"), Dim(dir))
+		if len(statuses) == 0 {
+			fmt.Printf("   No profiles or shims found.\n")
+		}
+		for _, s := range statuses {
+			line := fmt.Sprintf("   %s %-28s %s", shimStateIcon(s.State), s.Name, shimStateLabel(s.State))
+			if s.Detail != "" {
+				line += "  " + Dim(s.Detail)
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+		if drift > 0 {
+			fmt.Printf("   💡 Run %s to fix missing, stale and orphaned shims.\n", Bold("profilex shim install"))
+		}
+	}
+	if drift > 0 {
+		return app.ExitCodeError{Code: 1}
+	}
 	return nil
 }
 
+func shimStateIcon(s shim.State) string {
+	switch s {
+	case shim.StateOK:
+		return Green("✓")
+	case shim.StateForeign:
+		return Red("✗")
+	default:
+		return Yellow("!")
+	}
+}
+
+func shimStateLabel(s shim.State) string {
+	switch s {
+	case shim.StateOK:
+		return Green(string(s))
+	case shim.StateForeign:
+		return Red(string(s))
+	default:
+		return Yellow(string(s))
+	}
+}
+
 func cmdShimUninstall(rootDir string, args []string) error {
 	all, args := extractBool(args, "--all")
 	dir, args := extractFlag(args, "--dir")
@@ -728,15 +1072,28 @@ func resolvedVersion() string {
 }
 
 func newManager(rootDir string) (*app.Manager, error) {
+	var (
+		mgr *app.Manager
+		err error
+	)
 	if strings.TrimSpace(rootDir) == "" {
-		return app.NewDefaultManager()
+		mgr, err = app.NewDefaultManager()
+	} else {
+		abs := rootDir
+		if !filepath.IsAbs(rootDir) {
+			cwd, _ := os.Getwd()
+			abs = filepath.Join(cwd, rootDir)
+		}
+		mgr, err = app.NewManager(abs)
 	}
-	abs := rootDir
-	if !filepath.IsAbs(rootDir) {
-		cwd, _ := os.Getwd()
-		abs = filepath.Join(cwd, rootDir)
+	if err != nil {
+		return nil, err
+	}
+	mgr.SetPassphrasePrompt(promptPassphrase)
+	if err := mgr.AdapterLoadError(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s profilex: skipping invalid adapter definition: %v\n", Yellow("⚠"), err)
 	}
-	return app.NewManager(abs)
+	return mgr, nil
 }
 
 func parseTool(raw string) (store.Tool, error) {
@@ -763,6 +1120,40 @@ func installShimForProfile(profile store.Profile) (string, error) {
 	return shim.Install(dir, profile, resolveProfileXBin())
 }
 
+// repairShimDrift refreshes stale shims and removes orphaned ones after a
+// command changes profiles. Shims always read the default root, so managers
+// for any other root leave them alone.
+func repairShimDrift(mgr *app.Manager) ([]shim.Status, error) {
+	if !usesDefaultRoot(mgr) {
+		return nil, nil
+	}
+	dir, err := shim.DefaultShimDir()
+	if err != nil {
+		return nil, err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return nil, err
+	}
+	return shim.Repair(dir, shim.Targets(st), resolveProfileXBin())
+}
+
+// usesDefaultRoot reports whether mgr manages the state shims read.
+func usesDefaultRoot(mgr *app.Manager) bool {
+	root, err := store.DefaultRoot()
+	return err == nil && filepath.Clean(root) == filepath.Clean(mgr.Root())
+}
+
+func reportShimRepairs(fixed []shim.Status, err error) {
+	if err != nil {
+		fmt.Printf("   %s Could not repair shims: %v\n", Yellow("⚠"), err)
+	}
+	if len(fixed) == 0 {
+		return
+	}
+	fmt.Printf("   %s Repaired %d drifted shim(s) %s\n", Cyan("↻"), len(fixed), Dim("(see profilex shim status)"))
+}
+
 func resolveProfileXBin() string {
 	if exe, err := os.Executable(); err == nil && strings.TrimSpace(exe) != "" {
 		return exe
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/derekurban/profilex-cli/internal/app"
 	"github.com/derekurban/profilex-cli/internal/store"
@@ -196,3 +199,485 @@ func TestShimEnvOutputsProfileEnvironment(t *testing.T) {
 		t.Fatalf("missing PROFILEX_SHIM_NAME output: %q", stdout)
 	}
 }
+
+func TestShimEnvWarnsOnExpiringTokenViaStderr(t *testing.T) {
+	root := t.TempDir()
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile, _, err := mgr.EnsureProfile(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Now().Add(2 * time.Hour).UnixMilli()
+	creds := fmt.Sprintf(`{"claudeAiOauth":{"accessToken":"a","expiresAt":%d}}`, expiresAt)
+	if err := os.WriteFile(filepath.Join(profile.Dir, ".credentials.json"), []byte(creds), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "shim", "env", "claude", "work"})
+	})
+	if code != 0 {
+		t.Fatalf("expected exit 0, got %d (stderr: %q)", code, stderr)
+	}
+	if !strings.Contains(stderr, "token expires in") {
+		t.Fatalf("expected expiry warning on stderr, got %q", stderr)
+	}
+	if strings.Contains(stdout, "token") {
+		t.Fatalf("warning leaked into KEY=VALUE output: %q", stdout)
+	}
+
+	t.Setenv("PROFILEX_AUTH_WARN_WINDOW", "1h")
+	_, stderr, _ = captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "shim", "env", "claude", "work"})
+	})
+	if stderr != "" {
+		t.Fatalf("expected no warning outside the window, got %q", stderr)
+	}
+}
+
+func TestShimEnvUnsetsConflictingVariables(t *testing.T) {
+	root := t.TempDir()
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mgr.EnsureProfile(store.ToolClaude, "work"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ANTHROPIC_API_KEY", "sk-other-account")
+	t.Setenv(app.EnvPolicyVar, "")
+
+	stdout, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "shim", "env", "claude", "work"})
+	})
+	if code != 0 {
+		t.Fatalf("expected exit 0, got %d", code)
+	}
+	if !strings.Contains(stdout, "ANTHROPIC_API_KEY=\n") {
+		t.Fatalf("expected unset line for inherited key, got %q", stdout)
+	}
+
+	if _, err := mgr.SetEnvIsolation(store.ToolClaude, "work", nil, []string{"ANTHROPIC_API_KEY"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, _ = captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "shim", "env", "claude", "work"})
+	})
+	if strings.Contains(stdout, "ANTHROPIC_API_KEY") {
+		t.Fatalf("allow-listed variable should pass through, got %q", stdout)
+	}
+
+	warn := store.EnvPolicyWarn
+	if _, err := mgr.SetEnvIsolation(store.ToolClaude, "work", &warn, nil, []string{"ANTHROPIC_API_KEY"}); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, _ := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "shim", "env", "claude", "work"})
+	})
+	if strings.Contains(stdout, "ANTHROPIC_API_KEY") || !strings.Contains(stderr, "ANTHROPIC_API_KEY is set") {
+		t.Fatalf("warn policy should keep the variable and warn, got stdout=%q stderr=%q", stdout, stderr)
+	}
+}
+
+func TestShimEnvPrintsEveryAdapterAssignment(t *testing.T) {
+	root := t.TempDir()
+	defs := filepath.Join(root, "adapters.d")
+	if err := os.MkdirAll(defs, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	def := `{"name": "opencode", "binary": "opencode", "env": {"XDG_CONFIG_HOME": "{profile}/config", "XDG_DATA_HOME": "{profile}/data"}}`
+	if err := os.WriteFile(filepath.Join(defs, "opencode.json"), []byte(def), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _, err := mgr.EnsureProfile("opencode", "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filepath.Join(p.Dir, "data")); err != nil || !info.IsDir() {
+		t.Fatalf("expected assigned directories to be created: %v", err)
+	}
+
+	stdout, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "shim", "env", "opencode", "work"})
+	})
+	if code != 0 {
+		t.Fatalf("expected exit 0, got %d", code)
+	}
+	for _, want := range []string{"XDG_CONFIG_HOME=" + filepath.Join(p.Dir, "config"), "XDG_DATA_HOME=" + filepath.Join(p.Dir, "data")} {
+		if !strings.Contains(stdout, want+"\n") {
+			t.Fatalf("expected %q in shim env output, got %q", want, stdout)
+		}
+	}
+}
+
+func TestCompleteSuggestsCommandsProfilesAndPresets(t *testing.T) {
+	root := t.TempDir()
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"work", "personal"} {
+		if _, _, err := mgr.EnsureProfile(store.ToolClaude, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := mgr.EnsureProfile(store.ToolCodex, "wide"); err != nil {
+		t.Fatal(err)
+	}
+	p, err := mgr.GetProfile(mustLoad(t, mgr), store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(p.Dir, "settings.json"), []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.SnapshotSettings(store.ToolClaude, "work", "strict"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"se"}, "secret\nsessions\nsettings\n"},
+		{[]string{"use", "claude", ""}, "personal\nwork\n"},
+		{[]string{"use", "codex", "w"}, "wide\n"},
+		{[]string{"settings", "apply", "claude", ""}, "strict\n"},
+		{[]string{"settings", "snapshot", "claude", "d"}, "default\n"},
+		{[]string{"list", "--"}, "--json\n--refresh\n--tool\n"},
+		{[]string{"sessions", "export", "--format", ""}, "md\nhtml\njson\n"},
+		{[]string{"logout", "--tool", "co"}, "codex\n"},
+	}
+	for _, tc := range cases {
+		args := append([]string{"--root", root, "__complete"}, tc.words...)
+		stdout, stderr, code := captureRunOutput(t, func() int { return Run(args) })
+		if code != 0 || stderr != "" {
+			t.Fatalf("%v: exit %d, stderr %q", tc.words, code, stderr)
+		}
+		if stdout != tc.want {
+			t.Fatalf("%v: expected %q, got %q", tc.words, tc.want, stdout)
+		}
+	}
+}
+
+func mustLoad(t *testing.T, mgr *app.Manager) *store.State {
+	t.Helper()
+	st, err := mgr.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestEnvShellOutputRoundTripsThroughBash(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("evaluates bash output")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	root := t.TempDir()
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, _, err := mgr.EnsureProfile(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ANTHROPIC_API_KEY", "sk-other-account")
+	t.Setenv(app.EnvPolicyVar, "")
+
+	stdout, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "env", "claude", "work", "--shell", "bash"})
+	})
+	if code != 0 {
+		t.Fatalf("expected exit 0, got %d", code)
+	}
+	out, err := exec.Command("bash", "-c", stdout+`echo "${ANTHROPIC_API_KEY-unset}|$CLAUDE_CONFIG_DIR|$PROFILEX_PROFILE"`).Output()
+	if err != nil {
+		t.Fatalf("bash rejected env output %q: %v", stdout, err)
+	}
+	if want := "unset|" + profile.Dir + "|work\n"; string(out) != want {
+		t.Fatalf("expected %q, got %q", want, out)
+	}
+}
+
+func TestShellLaunchesUserShellWithProfileEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as $SHELL")
+	}
+	root := t.TempDir()
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, _, err := mgr.EnsureProfile(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.SetDefault(store.ToolClaude, "work"); err != nil {
+		t.Fatal(err)
+	}
+	fakeShell := filepath.Join(t.TempDir(), "fakesh")
+	script := "#!/bin/sh\necho \"args=$* dir=$CLAUDE_CONFIG_DIR tool=$PROFILEX_TOOL prompt=$PS1\"\nexit 3\n"
+	if err := os.WriteFile(fakeShell, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SHELL", fakeShell)
+	t.Setenv("PS1", "% ")
+	t.Setenv("PROFILEX_PROFILE", "")
+
+	stdout, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "shell", "claude"})
+	})
+	if code != 3 {
+		t.Fatalf("expected the shell's exit code, got %d", code)
+	}
+	if want := "args=-i dir=" + profile.Dir + " tool=claude prompt=(claude/work) % "; !strings.Contains(stdout, want) {
+		t.Fatalf("expected %q in output, got %q", want, stdout)
+	}
+}
+
+func TestAliasAddRefusesNamesTakenOnPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the colliding executable")
+	}
+	root := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "cw"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mgr.EnsureProfile(store.ToolClaude, "work"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, stderr, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "alias", "add", "cw", "claude", "work"})
+	})
+	if code != 1 || !strings.Contains(stderr, "already runs "+filepath.Join(bin, "cw")) {
+		t.Fatalf("expected a PATH collision error, got code %d: %s", code, stderr)
+	}
+	_, stderr, code = captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "alias", "add", "claude-work", "claude", "work"})
+	})
+	if code != 1 || !strings.Contains(stderr, "already used by claude/work") {
+		t.Fatalf("expected a shim name collision error, got code %d: %s", code, stderr)
+	}
+	if _, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "alias", "add", "cw", "claude", "work", "--force"})
+	}); code != 0 {
+		t.Fatalf("expected --force to add the alias, got code %d", code)
+	}
+	if err := mgr.RemoveProfile(store.ToolClaude, "work", false); err != nil {
+		t.Fatal(err)
+	}
+	if st := mustLoad(t, mgr); len(st.Aliases) != 0 {
+		t.Fatalf("expected aliases to go with their profile, got %+v", st.Aliases)
+	}
+}
+
+func TestPromptPrintsActiveProfileForStatusLines(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PROFILEX_TOOL", "")
+	t.Setenv("PROFILEX_PROFILE", "")
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mgr.EnsureProfile(store.ToolClaude, "work"); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "prompt"})
+	})
+	if code != 0 || stdout != "" {
+		t.Fatalf("expected no output without an active profile, got code %d: %q", code, stdout)
+	}
+
+	if _, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "term", "color", "claude", "work", "#3366AA"})
+	}); code != 0 {
+		t.Fatalf("term color failed with code %d", code)
+	}
+	t.Setenv("PROFILEX_TOOL", "claude")
+	t.Setenv("PROFILEX_PROFILE", "work")
+	stdout, _, code = captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "prompt", "--format", "tmux", "--no-usage"})
+	})
+	if code != 0 || stdout != "#[fg=#3366aa]claude/work#[default]\n" {
+		t.Fatalf("unexpected tmux segment, code %d: %q", code, stdout)
+	}
+}
+
+func TestShimEnvResyncsSettingsBeforeLaunch(t *testing.T) {
+	root := t.TempDir()
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, _, err := mgr.EnsureProfile(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := filepath.Join(profile.Dir, "settings.json")
+	if err := os.WriteFile(settings, []byte(`{"model":"opus"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.SnapshotSettings(store.ToolClaude, "work", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "settings", "sync", "claude", "work", "team"})
+	}); code != 0 {
+		t.Fatalf("settings sync failed with code %d", code)
+	}
+	if err := os.WriteFile(settings, []byte(`{"model":"haiku"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "shim", "env", "claude", "work"})
+	})
+	if code != 0 || !strings.Contains(stdout, "CLAUDE_CONFIG_DIR=") {
+		t.Fatalf("expected shim env to succeed, got code %d: %q", code, stdout)
+	}
+	if !strings.Contains(stderr, "replaced local edits to settings.json with preset team") {
+		t.Fatalf("expected the resync to be reported on stderr, got %q", stderr)
+	}
+	if got, _ := os.ReadFile(settings); string(got) != `{"model":"opus"}` {
+		t.Fatalf("expected settings.json to be resynced, got %q", got)
+	}
+}
+
+func TestSettingsApplyPreviewShowsMergedFileWithoutWriting(t *testing.T) {
+	root := t.TempDir()
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, _, err := mgr.EnsureProfile(store.ToolClaude, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _, err := mgr.EnsureProfile(store.ToolClaude, "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source.Dir, "settings.json"), []byte(`{"theme":"dark"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	settings := filepath.Join(target.Dir, "settings.json")
+	if err := os.WriteFile(settings, []byte(`{"model":"opus"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.SnapshotSettings(store.ToolClaude, "source", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "settings", "strategy", "claude", "team", "merge"})
+	}); code != 0 {
+		t.Fatalf("settings strategy failed with code %d", code)
+	}
+
+	stdout, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "settings", "apply", "claude", "team", "target", "--preview"})
+	})
+	if code != 0 || !strings.Contains(stdout, `"model": "opus"`) || !strings.Contains(stdout, `"theme": "dark"`) {
+		t.Fatalf("expected the merged file in the preview, got code %d: %q", code, stdout)
+	}
+	if got, _ := os.ReadFile(settings); string(got) != `{"model":"opus"}` {
+		t.Fatalf("preview must not write, got %q", got)
+	}
+}
+
+func TestSettingsDiffPrintsKeyLevelChanges(t *testing.T) {
+	root := t.TempDir()
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, _, err := mgr.EnsureProfile(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := filepath.Join(work.Dir, "settings.json")
+	if err := os.WriteFile(settings, []byte(`{"model":"opus","env":{"A":"1"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.SnapshotSettings(store.ToolClaude, "work", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(settings, []byte(`{"model":"haiku","env":{"A":"1","B":"2"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "settings", "diff", "claude", "team", "work"})
+	})
+	if code != 0 {
+		t.Fatalf("settings diff failed with code %d: %q", code, stdout)
+	}
+	for _, want := range []string{"--- preset team", "+++ profile work", "settings.json (changed)", `~ model = "opus" -> "haiku"`, `+ env.B = "2"`} {
+		if !strings.Contains(stdout, want) {
+			t.Fatalf("expected %q in diff output, got %q", want, stdout)
+		}
+	}
+}
+
+func TestSettingsLogAndRollback(t *testing.T) {
+	root := t.TempDir()
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, _, err := mgr.EnsureProfile(store.ToolClaude, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := filepath.Join(work.Dir, "settings.json")
+	for _, body := range []string{`{"model":"opus"}`, `{"model":"haiku"}`} {
+		if err := os.WriteFile(settings, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, code := captureRunOutput(t, func() int {
+			return Run([]string{"--root", root, "settings", "snapshot", "claude", "work", "team"})
+		}); code != 0 {
+			t.Fatalf("settings snapshot failed with code %d", code)
+		}
+	}
+
+	stdout, _, code := captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "settings", "log", "claude", "team"})
+	})
+	if code != 0 || !strings.Contains(stdout, "from work") || !strings.Contains(stdout, "* 2") {
+		t.Fatalf("expected two revisions from work, got code %d: %q", code, stdout)
+	}
+
+	stdout, _, code = captureRunOutput(t, func() int {
+		return Run([]string{"--root", root, "settings", "rollback", "claude", "team", "1"})
+	})
+	if code != 0 || !strings.Contains(stdout, "back to revision 1 (now revision 3)") {
+		t.Fatalf("expected rollback to record revision 3, got code %d: %q", code, stdout)
+	}
+	got, _ := os.ReadFile(filepath.Join(root, "presets", "claude", "team", "settings.json"))
+	if string(got) != `{"model":"opus"}` {
+		t.Fatalf("expected the preset restored to revision 1, got %q", got)
+	}
+}
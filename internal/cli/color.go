@@ -159,6 +159,12 @@ var (
 			Padding(0, 1).
 			Bold(true)
 
+	styleBadgeOther = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#F8FAFC")).
+			Background(colorMuted).
+			Padding(0, 1).
+			Bold(true)
+
 	styleMuted = lipgloss.NewStyle().
 			Foreground(colorMuted)
 
@@ -180,10 +186,14 @@ func renderKeyHint(key, desc string) string {
 }
 
 func renderToolBadge(tool store.Tool) string {
-	if tool == store.ToolClaude {
+	switch tool {
+	case store.ToolClaude:
 		return styleBadgeClaude.Render("claude")
+	case store.ToolCodex:
+		return styleBadgeCodex.Render("codex")
+	default:
+		return styleBadgeOther.Render(string(tool))
 	}
-	return styleBadgeCodex.Render("codex")
 }
 
 func renderDivider(width int) string {
@@ -0,0 +1,393 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/app"
+	"github.com/derekurban/profilex-cli/internal/shim"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// argKind says what a positional argument or flag value completes to.
+type argKind int
+
+const (
+	argNone argKind = iota
+	argTool
+	// argProfile completes profiles of the tool named by an earlier argTool.
+	argProfile
+	argProfileOrDefault
+	argPreset
+	// argSettingsRef completes presets, profiles and default.
+	argSettingsRef
+	argAlias
+	argShell
+	argChoice
+)
+
+type compArg struct {
+	kind    argKind
+	choices []string
+}
+
+// compSpec mirrors the flags and positionals a command's cmd* function
+// accepts. Keep it in sync when adding commands or flags.
+type compSpec struct {
+	flags      []string
+	valueFlags map[string]compArg
+	args       []compArg
+	subs       map[string]*compSpec
+}
+
+var (
+	toolArg             = compArg{kind: argTool}
+	profileArg          = compArg{kind: argProfile}
+	profileOrDefaultArg = compArg{kind: argProfileOrDefault}
+	presetArg           = compArg{kind: argPreset}
+	settingsRefArg      = compArg{kind: argSettingsRef}
+	aliasArg            = compArg{kind: argAlias}
+	shellArg            = compArg{kind: argShell}
+	pathArg             = compArg{kind: argNone}
+	jsonFlag            = []string{"--json"}
+)
+
+func choiceArg(choices ...string) compArg {
+	return compArg{kind: argChoice, choices: choices}
+}
+
+var completionTree = &compSpec{
+	valueFlags: map[string]compArg{"--root": pathArg},
+	subs: map[string]*compSpec{
+		"add":       {flags: []string{"--isolated", "--no-shared-skills"}, args: []compArg{toolArg}},
+		"remove":    {flags: []string{"--purge"}, args: []compArg{toolArg, profileArg}},
+		"uninstall": {flags: []string{"--purge"}},
+		"list":      {flags: []string{"--json", "--refresh"}, valueFlags: map[string]compArg{"--tool": toolArg}},
+		"use":       {args: []compArg{toolArg, profileArg}},
+		"rename":    {args: []compArg{toolArg, profileArg}},
+		"run":       {args: []compArg{toolArg, profileArg}},
+		"shell":     {args: []compArg{toolArg, profileArg}},
+		"env":       {valueFlags: map[string]compArg{"--shell": choiceArg(shim.EnvFormats...)}, args: []compArg{toolArg, profileArg}},
+		"shim": {subs: map[string]*compSpec{
+			"install":   {flags: []string{"--force", "--compiled", "--dynamic"}, valueFlags: map[string]compArg{"--dir": pathArg}},
+			"status":    {flags: jsonFlag, valueFlags: map[string]compArg{"--dir": pathArg}},
+			"env":       {args: []compArg{toolArg, profileArg}},
+			"uninstall": {flags: []string{"--all"}, valueFlags: map[string]compArg{"--dir": pathArg}, args: []compArg{toolArg, profileArg}},
+		}},
+		"alias": {subs: map[string]*compSpec{
+			"add":      {flags: []string{"--force"}, args: []compArg{pathArg, toolArg, profileArg}},
+			"remove":   {args: []compArg{aliasArg}},
+			"extend":   {flags: []string{"--clear"}, args: []compArg{toolArg, presetArg, presetArg}},
+			"diff":     {flags: jsonFlag, args: []compArg{toolArg, settingsRefArg, settingsRefArg}},
+			"log":      {flags: jsonFlag, args: []compArg{toolArg, presetArg}},
+			"rollback": {args: []compArg{toolArg, presetArg}},
+			"show":     {flags: []string{"--effective", "--json"}, args: []compArg{toolArg, presetArg}},
+			"list":     {flags: jsonFlag},
+			"template": {flags: []string{"--clear", "--force"}, args: []compArg{toolArg}},
+		}},
+		"term": {flags: jsonFlag, subs: map[string]*compSpec{
+			"enable":  {args: []compArg{choiceArg(store.TerminalIntegrations...)}},
+			"disable": {args: []compArg{choiceArg(store.TerminalIntegrations...)}},
+			"color":   {flags: []string{"--clear"}, args: []compArg{toolArg, profileArg}},
+		}},
+		"prompt": {flags: []string{"--no-usage"}, valueFlags: map[string]compArg{
+			"--format":  choiceArg(promptFormats...),
+			"--profile": pathArg,
+		}},
+		"shell-init": {flags: []string{"--prompt"}, args: []compArg{shellArg}},
+		"completion": {args: []compArg{shellArg}},
+		"usage": {subs: map[string]*compSpec{
+			"export": {flags: []string{"--deep"}, valueFlags: map[string]compArg{
+				"--out":       pathArg,
+				"--timezone":  pathArg,
+				"--cost-mode": choiceArg("auto", "calculate", "display"),
+				"--max-files": pathArg,
+			}},
+		}},
+		"sessions": {subs: map[string]*compSpec{
+			"export": {flags: []string{"--redact", "--redact-paths", "--redact-secrets"}, valueFlags: map[string]compArg{
+				"--format": choiceArg("md", "html", "json"),
+				"--out":    pathArg,
+			}},
+			"prune": {flags: []string{"--dry-run", "--json"}, valueFlags: map[string]compArg{
+				"--older-than": pathArg,
+				"--archive":    pathArg,
+			}},
+		}},
+		"du": {flags: jsonFlag, valueFlags: map[string]compArg{"--tool": toolArg}},
+		"auth": {subs: map[string]*compSpec{
+			"check": {flags: jsonFlag, valueFlags: map[string]compArg{"--tool": toolArg, "--within": pathArg}},
+		}},
+		"secret": {subs: map[string]*compSpec{
+			"set":    {args: []compArg{toolArg, profileArg}},
+			"rm":     {args: []compArg{toolArg, profileArg}},
+			"list":   {flags: jsonFlag},
+			"keygen": {valueFlags: map[string]compArg{"--out": pathArg}},
+		}},
+		"vault": {subs: map[string]*compSpec{
+			"enable":  {flags: jsonFlag, args: []compArg{toolArg, profileArg}},
+			"disable": {flags: jsonFlag, args: []compArg{toolArg, profileArg}},
+			"status":  {flags: jsonFlag, args: []compArg{toolArg, profileArg}},
+		}},
+		"isolation": {flags: jsonFlag, args: []compArg{toolArg, profileArg}, valueFlags: map[string]compArg{
+			"--policy":   choiceArg(string(store.EnvPolicyStrip), string(store.EnvPolicyWarn)),
+			"--allow":    pathArg,
+			"--disallow": pathArg,
+		}},
+		"adapters": {flags: jsonFlag},
+		"pin":      {flags: []string{"--clear"}, valueFlags: map[string]compArg{"--path": pathArg}, args: []compArg{toolArg, profileArg}},
+		"doctor":   {flags: jsonFlag},
+		"login":    {valueFlags: map[string]compArg{"--api-key": pathArg}, args: []compArg{toolArg, profileArg}},
+		"logout":   {flags: []string{"--all"}, valueFlags: map[string]compArg{"--tool": toolArg}, args: []compArg{toolArg, profileArg}},
+		"settings": {subs: map[string]*compSpec{
+			"snapshot": {args: []compArg{toolArg, profileOrDefaultArg, presetArg}, valueFlags: map[string]compArg{"--include": pathArg, "--exclude": pathArg}},
+			"paths":    {flags: []string{"--reset", "--json"}, args: []compArg{toolArg, presetArg}, valueFlags: map[string]compArg{"--include": pathArg, "--exclude": pathArg}},
+			"apply":    {flags: []string{"--preview", "--json"}, args: []compArg{toolArg, presetArg, profileOrDefaultArg}},
+			"strategy": {args: []compArg{toolArg, presetArg, choiceArg(string(store.MergeReplace), string(store.MergeDeep), string(store.MergeUnion))}},
+			"list":     {flags: jsonFlag, valueFlags: map[string]compArg{"--tool": toolArg}},
+			"sync": {flags: []string{"--off"}, args: []compArg{toolArg, profileOrDefaultArg, presetArg}, valueFlags: map[string]compArg{
+				"--local-edits": choiceArg(string(store.SyncOverwrite), string(store.SyncPreserve), string(store.SyncPromote)),
+			}},
+		}},
+		"tui":     {},
+		"version": {},
+		"help":    {},
+	},
+}
+
+// cmdComplete backs the generated completion scripts. args are the words
+// after "profilex", the last being the word under the cursor. It reads state
+// only; nothing is probed.
+func cmdComplete(rootDir string, args []string) error {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	// PowerShell cannot pass an empty argument to native commands, so its
+	// script sends a literal "" instead.
+	if args[len(args)-1] == `""` {
+		args[len(args)-1] = ""
+	}
+	for _, c := range completeWords(rootDir, args[:len(args)-1], args[len(args)-1]) {
+		fmt.Println(c)
+	}
+	return nil
+}
+
+func completeWords(rootDir string, words []string, cur string) []string {
+	spec := completionTree
+	var tool store.Tool
+	pos := 0
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if strings.HasPrefix(w, "-") {
+			if _, ok := spec.valueFlags[w]; ok {
+				i++
+			}
+			continue
+		}
+		if sub, ok := spec.subs[w]; ok && pos == 0 {
+			spec = sub
+			continue
+		}
+		if pos < len(spec.args) && spec.args[pos].kind == argTool {
+			tool = store.Tool(w)
+		}
+		pos++
+	}
+
+	var candidates []string
+	if n := len(words); n > 0 {
+		if arg, ok := spec.valueFlags[words[n-1]]; ok {
+			return filterPrefix(completeArg(rootDir, arg, tool), cur)
+		}
+	}
+	if strings.HasPrefix(cur, "-") {
+		candidates = append(candidates, spec.flags...)
+		for f := range spec.valueFlags {
+			candidates = append(candidates, f)
+		}
+	} else if len(spec.subs) > 0 && pos == 0 {
+		for name := range spec.subs {
+			candidates = append(candidates, name)
+		}
+	} else if pos < len(spec.args) {
+		candidates = completeArg(rootDir, spec.args[pos], tool)
+	}
+	sort.Strings(candidates)
+	return filterPrefix(candidates, cur)
+}
+
+func completeArg(rootDir string, arg compArg, tool store.Tool) []string {
+	switch arg.kind {
+	case argTool:
+		// Loading state also registers custom adapters from adapters.d.
+		loadCompletionState(rootDir)
+		out := []string{}
+		for _, t := range store.SupportedTools {
+			out = append(out, string(t))
+		}
+		return out
+	case argProfile, argProfileOrDefault:
+		st := loadCompletionState(rootDir)
+		out := []string{}
+		if arg.kind == argProfileOrDefault {
+			out = append(out, "default")
+		}
+		if st == nil {
+			return out
+		}
+		for _, p := range st.Profiles {
+			if p.Tool == tool {
+				out = append(out, p.Name)
+			}
+		}
+		return out
+	case argPreset:
+		st := loadCompletionState(rootDir)
+		out := []string{}
+		if st == nil {
+			return out
+		}
+		for _, p := range st.SettingsPresets {
+			if p.Tool == tool {
+				out = append(out, p.Name)
+			}
+		}
+		return out
+	case argSettingsRef:
+		return append(completeArg(rootDir, presetArg, tool), completeArg(rootDir, profileOrDefaultArg, tool)...)
+	case argAlias:
+		st := loadCompletionState(rootDir)
+		out := []string{}
+		if st == nil {
+			return out
+		}
+		for _, a := range st.Aliases {
+			out = append(out, a.Name)
+		}
+		return out
+	case argShell:
+		return shim.Shells
+	case argChoice:
+		return arg.choices
+	default:
+		return nil
+	}
+}
+
+// loadCompletionState reads state without the adapter warnings newManager
+// prints, since stderr would garble the shell's completion menu.
+func loadCompletionState(rootDir string) *store.State {
+	root, err := resolveRootDir(rootDir)
+	if err != nil {
+		return nil
+	}
+	mgr, err := app.NewManager(root)
+	if err != nil {
+		return nil
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return nil
+	}
+	return st
+}
+
+func filterPrefix(candidates []string, prefix string) []string {
+	out := []string{}
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func cmdCompletion(args []string) error {
+	if hasHelp(args) || len(args) != 1 {
+		printCompletionHelp()
+		if len(args) != 1 && !hasHelp(args) {
+			return fmt.Errorf("expected exactly one shell")
+		}
+		return nil
+	}
+	shell, err := shim.NormalizeShell(args[0])
+	if err != nil {
+		return err
+	}
+	const name = "profilex"
+	switch shell {
+	case "bash":
+		fmt.Printf(bashCompletion, name)
+	case "zsh":
+		fmt.Printf(zshCompletion, name)
+	case "fish":
+		fmt.Printf(fishCompletion, name)
+	case "powershell":
+		fmt.Printf(powershellCompletion, name)
+	}
+	return nil
+}
+
+func printCompletionHelp() {
+	fmt.Printf(`Usage: profilex completion <%s>
+
+Print a completion script for profilex. Profile and preset names are
+looked up on each completion through "profilex __complete".
+
+  bash:        eval "$(profilex completion bash)"
+  zsh:         source <(profilex completion zsh)
+  fish:        profilex completion fish | source
+  PowerShell:  profilex completion powershell | Out-String | Invoke-Expression
+`, strings.Join(shim.Shells, "|"))
+}
+
+const bashCompletion = `# profilex completion for bash
+_profilex() {
+  local IFS=$'\n'
+  COMPREPLY=($("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _profilex %[1]s
+`
+
+const zshCompletion = `#compdef %[1]s
+# profilex completion for zsh
+_profilex() {
+  local -a candidates
+  candidates=("${(@f)$(${words[1]} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+  candidates=(${candidates:#})
+  if (( ${#candidates} )); then
+    compadd -a candidates
+  else
+    _files
+  fi
+}
+if [ "$funcstack[1]" = "_profilex" ]; then
+  _profilex "$@"
+else
+  compdef _profilex %[1]s
+fi
+`
+
+const fishCompletion = `# profilex completion for fish
+function __profilex_complete
+    set -l words (commandline -opc)
+    set -l cur (commandline -ct)
+    $words[1] __complete $words[2..-1] "$cur" 2>/dev/null
+end
+complete -c %[1]s -f -a '(__profilex_complete)'
+`
+
+const powershellCompletion = `# profilex completion for PowerShell
+Register-ArgumentCompleter -Native -CommandName %[1]s -ScriptBlock {
+  param($wordToComplete, $commandAst, $cursorPosition)
+  $words = @($commandAst.CommandElements | Where-Object { $_.Extent.EndOffset -le $cursorPosition } | ForEach-Object { $_.ToString() })
+  $exe = $words[0]
+  $rest = @($words | Select-Object -Skip 1)
+  if ($wordToComplete -eq '') { $rest += '""' }
+  & $exe __complete @rest 2>$null | ForEach-Object {
+    [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+  }
+}
+`
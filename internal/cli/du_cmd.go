@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func cmdDU(rootDir string, args []string) error {
+	toolFlag, args := extractFlag(args, "--tool")
+	jsonOut, args := extractBool(args, "--json")
+	if hasHelp(args) || len(args) > 0 {
+		fmt.Printf("Usage: profilex du [--tool <tool>] [--json]\n\n")
+		fmt.Printf("Report disk use per profile, per shared pool and per subdirectory.\n")
+		fmt.Printf("Shared links are listed on each profile but counted once under the shared pool.\n")
+		return nil
+	}
+
+	var filter *store.Tool
+	if toolFlag != "" {
+		t, err := parseTool(toolFlag)
+		if err != nil {
+			return err
+		}
+		filter = &t
+	}
+
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	report, err := mgr.DiskUsage(filter)
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		b, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+
+	fmt.Printf("%s\n\n", Bold("💾 Disk usage"))
+	if len(report.Profiles) == 0 {
+		fmt.Printf("  No profiles found.\n")
+	}
+	for _, p := range report.Profiles {
+		fmt.Printf("  %s %s\n", Bold(fmt.Sprintf("%-24s", string(p.Profile.Tool)+"/"+p.Profile.Name)), formatBytes(p.Bytes))
+		for _, e := range p.Breakdown {
+			if e.Linked != "" {
+				fmt.Printf("      %-22s %s\n", e.Name, Dim("→ "+e.Linked))
+				continue
+			}
+			fmt.Printf("      %-22s %10s  %s\n", e.Name, formatBytes(e.Bytes), Dim(fmt.Sprintf("%d files", e.Files)))
+		}
+	}
+
+	if len(report.Shared) > 0 {
+		fmt.Printf("\n  %s\n", Bold("shared"))
+		for _, e := range report.Shared {
+			fmt.Printf("      %-22s %10s  %s\n", e.Name, formatBytes(e.Bytes), Dim(fmt.Sprintf("%d files", e.Files)))
+		}
+	}
+
+	fmt.Printf("\n  %-24s %s\n", Bold("total"), Bold(formatBytes(report.Total)))
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %s", float64(n)/float64(div), strings.Split("KiB MiB GiB TiB PiB", " ")[exp])
+}
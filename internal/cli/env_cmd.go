@@ -0,0 +1,267 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/app"
+	"github.com/derekurban/profilex-cli/internal/shim"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// profileEnv assembles the variables a profile launches with, in the order
+// `profilex shim env` prints them. An empty value unsets an inherited
+// variable. Expiry and conflict warnings go to stderr.
+func profileEnv(mgr *app.Manager, st *store.State, profile store.Profile) ([]adapters.EnvAssignment, error) {
+	adapter, err := adapters.Get(profile.Tool)
+	if err != nil {
+		return nil, err
+	}
+	warnAuthExpiry(mgr, profile)
+	launchEnv, err := mgr.LaunchEnv(profile)
+	if err != nil {
+		return nil, err
+	}
+	conflicts, err := warnEnvConflicts(mgr, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	stripped := []string{}
+	for _, c := range conflicts {
+		if c.Action == app.EnvStripped {
+			stripped = append(stripped, c.Name)
+		}
+	}
+	return assembleProfileEnv(adapter, st, profile, stripped, launchEnv), nil
+}
+
+// compiledProfileEnv is profileEnv for a compiled shim, which outlives the
+// environment it was resolved in: it strips every conflicting variable the
+// profile does not allow rather than only those set now. ok is false for
+// profiles whose environment cannot be reused: vault and API key profiles,
+// whose credentials must not be written out, profiles that only warn about
+// conflicts, and profiles synced to a settings preset, which must go through
+// `shim env` to be resynced before each launch.
+func compiledProfileEnv(st *store.State, profile store.Profile) (env []adapters.EnvAssignment, ok bool) {
+	if profile.Vault || profile.Auth == store.AuthModeAPIKey {
+		return nil, false
+	}
+	if _, sync := store.FindSettingsSync(st, profile.Tool, profile.Name); sync != nil {
+		return nil, false
+	}
+	stripped, ok := app.StrippedEnvVars(profile)
+	if !ok {
+		return nil, false
+	}
+	adapter, err := adapters.Get(profile.Tool)
+	if err != nil {
+		return nil, false
+	}
+	return assembleProfileEnv(adapter, st, profile, stripped, nil), true
+}
+
+func assembleProfileEnv(adapter adapters.Adapter, st *store.State, profile store.Profile, stripped, launchEnv []string) []adapters.EnvAssignment {
+	env := []adapters.EnvAssignment{}
+	for _, name := range stripped {
+		env = append(env, adapters.EnvAssignment{Name: name})
+	}
+	env = append(env, adapter.Env(profile.Dir)...)
+	if bin, _ := st.BinaryFor(profile); bin != "" {
+		env = append(env, adapters.EnvAssignment{Name: "PROFILEX_TOOL_BIN", Value: bin})
+	}
+	for _, kv := range launchEnv {
+		name, value, _ := strings.Cut(kv, "=")
+		env = append(env, adapters.EnvAssignment{Name: name, Value: value})
+	}
+	term := strings.Join(st.TerminalIntegrations(), ",")
+	if term == "" {
+		term = "none"
+	}
+	return append(env,
+		adapters.EnvAssignment{Name: "PROFILEX_TOOL", Value: string(profile.Tool)},
+		adapters.EnvAssignment{Name: "PROFILEX_PROFILE", Value: profile.Name},
+		adapters.EnvAssignment{Name: "PROFILEX_SHIM_NAME", Value: shim.Name(profile.Tool, profile.Name)},
+		adapters.EnvAssignment{Name: "PROFILEX_TERM", Value: term},
+		adapters.EnvAssignment{Name: "PROFILEX_COLOR", Value: profile.Color},
+	)
+}
+
+// applyProfileEnv returns base with env applied: named variables are
+// replaced and empty assignments removed.
+func applyProfileEnv(base []string, env []adapters.EnvAssignment) []string {
+	drop := map[string]bool{}
+	for _, e := range env {
+		drop[e.Name] = true
+	}
+	out := make([]string, 0, len(base)+len(env))
+	for _, kv := range base {
+		name, _, _ := strings.Cut(kv, "=")
+		if !drop[name] {
+			out = append(out, kv)
+		}
+	}
+	for _, e := range env {
+		if e.Value != "" {
+			out = append(out, e.String())
+		}
+	}
+	return out
+}
+
+// resolveProfileArgs parses "<tool> [profile]" and loads the profile.
+func resolveProfileArgs(rootDir string, args []string) (*app.Manager, *store.State, store.Profile, error) {
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return nil, nil, store.Profile{}, err
+	}
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return nil, nil, store.Profile{}, err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return nil, nil, store.Profile{}, err
+	}
+	profile, err := mgr.ResolveProfile(st, tool, name)
+	if err != nil {
+		return nil, nil, store.Profile{}, err
+	}
+	return mgr, st, profile, nil
+}
+
+func cmdEnv(rootDir string, args []string) error {
+	format, args := extractFlag(args, "--shell")
+	if hasHelp(args) || len(args) < 1 || len(args) > 2 {
+		fmt.Printf("Usage: profilex env <tool> [profile] [--shell %s]\n", strings.Join(shim.EnvFormats, "|"))
+		return nil
+	}
+	mgr, st, profile, err := resolveProfileArgs(rootDir, args)
+	if err != nil {
+		return err
+	}
+	if profile.Vault {
+		fmt.Fprintf(os.Stderr, "%s profilex: %s/%s is a vault profile; its credentials stay sealed unless launched via `profilex run` or `profilex shell`\n",
+			Yellow("⚠"), profile.Tool, profile.Name)
+	}
+	env, err := profileEnv(mgr, st, profile)
+	if err != nil {
+		return err
+	}
+	out, err := shim.FormatEnv(format, env)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+	return nil
+}
+
+func cmdShell(rootDir string, args []string) error {
+	if hasHelp(args) || len(args) < 1 || len(args) > 2 {
+		fmt.Printf("Usage: profilex shell <tool> [profile]\n")
+		return nil
+	}
+	mgr, st, profile, err := resolveProfileArgs(rootDir, args)
+	if err != nil {
+		return err
+	}
+	if active := os.Getenv("PROFILEX_PROFILE"); active != "" {
+		fmt.Fprintf(os.Stderr, "%s profilex: already in a %s/%s shell; nesting %s/%s inside it\n",
+			Yellow("⚠"), os.Getenv("PROFILEX_TOOL"), active, profile.Tool, profile.Name)
+	}
+	env, err := profileEnv(mgr, st, profile)
+	if err != nil {
+		return err
+	}
+
+	shellPath := userShell()
+	tmp, err := os.MkdirTemp("", "profilex-shell-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	shellArgs, promptEnv, err := shellPromptSetup(shellPath, tmp, profile)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(shellPath, shellArgs...)
+	cmd.Env = append(applyProfileEnv(os.Environ(), env), promptEnv...)
+	fmt.Printf("%s Entering %s shell %s\n", Cyan("→"), Bold(shim.Name(profile.Tool, profile.Name)), Dim("(exit to leave)"))
+	err = mgr.RunInProfile(context.Background(), profile, cmd)
+	fmt.Printf("%s Left %s shell\n", Cyan("←"), shim.Name(profile.Tool, profile.Name))
+	return err
+}
+
+func userShell() string {
+	if s := strings.TrimSpace(os.Getenv("SHELL")); s != "" {
+		return s
+	}
+	if runtime.GOOS == "windows" {
+		for _, name := range []string{"pwsh", "powershell"} {
+			if p, err := exec.LookPath(name); err == nil {
+				return p
+			}
+		}
+		if s := os.Getenv("COMSPEC"); s != "" {
+			return s
+		}
+		return "cmd.exe"
+	}
+	return "/bin/sh"
+}
+
+// shellPromptSetup returns the arguments and extra variables that start
+// shellPath interactively with the shell-init prompt hook loaded after the
+// user's own startup files. Files it needs are written to tmp. Shells it
+// does not know get a PS1 prefix.
+func shellPromptSetup(shellPath, tmp string, profile store.Profile) ([]string, []string, error) {
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(shellPath)), ".exe")
+	switch name {
+	case "bash":
+		rc := filepath.Join(tmp, "bashrc")
+		content := "[ -f ~/.bashrc ] && . ~/.bashrc\n" + shim.PromptHook("bash")
+		if err := os.WriteFile(rc, []byte(content), 0o600); err != nil {
+			return nil, nil, err
+		}
+		return []string{"--rcfile", rc, "-i"}, nil, nil
+	case "zsh":
+		// zsh reads its startup files from ZDOTDIR; point it at tmp, then
+		// hand back to the user's files before adding the hook.
+		orig := os.Getenv("ZDOTDIR")
+		userDir := `"${PROFILEX_ORIG_ZDOTDIR:-$HOME}"`
+		zshenv := "[ -f " + userDir + "/.zshenv ] && . " + userDir + "/.zshenv\n"
+		zshrc := `if [ -n "$PROFILEX_ORIG_ZDOTDIR" ]; then ZDOTDIR="$PROFILEX_ORIG_ZDOTDIR"; else unset ZDOTDIR; fi
+[ -f "${ZDOTDIR:-$HOME}/.zshrc" ] && . "${ZDOTDIR:-$HOME}/.zshrc"
+` + shim.PromptHook("zsh")
+		if err := os.WriteFile(filepath.Join(tmp, ".zshenv"), []byte(zshenv), 0o600); err != nil {
+			return nil, nil, err
+		}
+		if err := os.WriteFile(filepath.Join(tmp, ".zshrc"), []byte(zshrc), 0o600); err != nil {
+			return nil, nil, err
+		}
+		return []string{"-i"}, []string{"ZDOTDIR=" + tmp, "PROFILEX_ORIG_ZDOTDIR=" + orig}, nil
+	case "fish":
+		return []string{"-i", "-C", shim.PromptHook("fish")}, nil, nil
+	case "pwsh", "powershell":
+		return []string{"-NoLogo", "-NoExit", "-Command", shim.PromptHook("powershell")}, nil, nil
+	case "cmd":
+		return nil, []string{fmt.Sprintf("PROMPT=(%s/%s) $P$G", profile.Tool, profile.Name)}, nil
+	default:
+		ps1 := os.Getenv("PS1")
+		if ps1 == "" {
+			ps1 = "$ "
+		}
+		return []string{"-i"}, []string{fmt.Sprintf("PS1=(%s/%s) %s", profile.Tool, profile.Name, ps1)}, nil
+	}
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/app"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func cmdIsolation(rootDir string, args []string) error {
+	policyRaw, args := extractFlag(args, "--policy")
+	allowRaw, args := extractFlag(args, "--allow")
+	disallowRaw, args := extractFlag(args, "--disallow")
+	jsonOut, args := extractBool(args, "--json")
+	if hasHelp(args) || len(args) != 2 {
+		printIsolationHelp()
+		return nil
+	}
+
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+	profile, err := mgr.GetProfile(st, tool, args[1])
+	if err != nil {
+		return err
+	}
+
+	if policyRaw != "" || allowRaw != "" || disallowRaw != "" {
+		var policy *store.EnvPolicy
+		if policyRaw != "" {
+			p, err := app.ParseEnvPolicy(policyRaw)
+			if err != nil {
+				return err
+			}
+			policy = &p
+		}
+		profile, err = mgr.SetEnvIsolation(tool, profile.Name, policy, splitList(allowRaw), splitList(disallowRaw))
+		if err != nil {
+			return err
+		}
+	}
+
+	adapter, err := adapters.Get(tool)
+	if err != nil {
+		return err
+	}
+	conflicts, err := mgr.EnvConflicts(profile)
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		payload := map[string]any{
+			"profile":     profile,
+			"policy":      app.EffectiveEnvPolicy(profile),
+			"allow":       profile.EnvAllow,
+			"conflicting": adapter.ConflictingEnvVars(),
+			"present":     conflicts,
+		}
+		b, _ := json.MarshalIndent(payload, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+
+	fmt.Printf("%s %s/%s\n", Bold("🧪 Environment isolation"), tool, Bold(profile.Name))
+	fmt.Printf("   Policy:      %s\n", app.EffectiveEnvPolicy(profile))
+	fmt.Printf("   Watched:     %s\n", Dim(strings.Join(adapter.ConflictingEnvVars(), ", ")))
+	allow := "(none)"
+	if len(profile.EnvAllow) > 0 {
+		allow = strings.Join(profile.EnvAllow, ", ")
+	}
+	fmt.Printf("   Allowed:     %s\n", allow)
+	if len(conflicts) == 0 {
+		fmt.Printf("   %s No conflicting variables in this shell.\n", Green("✓"))
+	}
+	for _, c := range conflicts {
+		fmt.Printf("   %s %s %s\n", envActionIcon(c.Action), c.Name, Dim(string(c.Action)))
+	}
+	return nil
+}
+
+func printIsolationHelp() {
+	fmt.Printf("Usage: profilex isolation <tool> <profile> [--policy strip|warn] [--allow VAR,...] [--disallow VAR,...] [--json]\n\n")
+	fmt.Printf("Inherited variables such as ANTHROPIC_API_KEY or OPENAI_API_KEY would make a profile's\n")
+	fmt.Printf("tool use another account. By default they are stripped at launch and unset by shims.\n\n")
+	fmt.Printf("Options:\n")
+	fmt.Printf("  --policy <p>       strip (default) or warn; the global default is $%s\n", app.EnvPolicyVar)
+	fmt.Printf("  --allow <vars>     Always pass these variables through for this profile\n")
+	fmt.Printf("  --disallow <vars>  Remove variables from the allow-list\n")
+}
+
+// warnEnvConflicts reports conflicting variables the policy keeps on stderr
+// and returns every conflict found.
+func warnEnvConflicts(mgr *app.Manager, profile store.Profile) ([]app.EnvConflict, error) {
+	conflicts, err := mgr.EnvConflicts(profile)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conflicts {
+		if c.Action == app.EnvWarned {
+			fmt.Fprintf(os.Stderr, "%s profilex: %s is set in this shell and may override %s/%s's account\n",
+				Yellow("⚠"), c.Name, profile.Tool, profile.Name)
+		}
+	}
+	return conflicts, nil
+}
+
+func envActionIcon(a app.EnvAction) string {
+	switch a {
+	case app.EnvStripped:
+		return Green("✂")
+	case app.EnvWarned:
+		return Yellow("⚠")
+	default:
+		return Cyan("→")
+	}
+}
+
+func splitList(raw string) []string {
+	out := []string{}
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
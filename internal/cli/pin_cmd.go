@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/app"
+	"github.com/derekurban/profilex-cli/internal/shim"
+)
+
+func cmdPin(rootDir string, args []string) error {
+	path, args := extractFlag(args, "--path")
+	clear, args := extractBool(args, "--clear")
+	if hasHelp(args) || len(args) < 1 || len(args) > 2 || (path != "" && clear) {
+		printPinHelp()
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		st, err := mgr.Load()
+		if err != nil {
+			return err
+		}
+		if _, err := mgr.GetProfile(st, tool, name); err != nil {
+			return err
+		}
+	}
+
+	target := string(tool)
+	if name != "" {
+		target += "/" + name
+	}
+	switch {
+	case path != "":
+		resolved, err := mgr.PinBinary(tool, name, path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Pinned %s to %s\n", Green("✓"), Bold(target), Dim(resolved))
+	case clear:
+		if _, err := mgr.PinBinary(tool, name, ""); err != nil {
+			return err
+		}
+		fmt.Printf("%s Cleared binary pin for %s\n", Green("✓"), Bold(target))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	report, err := mgr.BinaryReport(ctx, &tool)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		filtered := report[:0]
+		for _, b := range report {
+			if b.Profile.Name == name {
+				filtered = append(filtered, b)
+			}
+		}
+		report = filtered
+	}
+	for _, b := range report {
+		fmt.Printf("   %s\n", describeBinary(b))
+	}
+	return nil
+}
+
+func printPinHelp() {
+	fmt.Printf("Usage:\n")
+	fmt.Printf("  profilex pin <tool> [profile]                 Show which binary each profile runs\n")
+	fmt.Printf("  profilex pin <tool> [profile] --path <bin>    Pin a tool binary (all profiles when no profile is given)\n")
+	fmt.Printf("  profilex pin <tool> [profile] --clear         Remove the pin\n\n")
+	fmt.Printf("A profile pin overrides the tool-wide pin. Shims pick up pins without reinstalling.\n")
+}
+
+// describeBinary renders one profile's binary as "tool/name  version  path".
+func describeBinary(b app.BinaryInfo) string {
+	label := fmt.Sprintf("%s/%s", b.Profile.Tool, b.Profile.Name)
+	pin := ""
+	if b.Pinned != "" {
+		pin = Dim(" (pinned: " + b.Pinned + ")")
+	}
+	switch {
+	case b.Missing():
+		return fmt.Sprintf("%s %-24s %s%s", Red("✗"), label, Red(b.Error), pin)
+	case b.Error != "":
+		return fmt.Sprintf("%s %-24s %s %s%s", Yellow("⚠"), label, Dim(b.Path), Yellow(b.Error), pin)
+	default:
+		return fmt.Sprintf("%s %-24s %-12s %s%s", Green("●"), label, b.Version, Dim(b.Path), pin)
+	}
+}
+
+type doctorCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func cmdDoctor(rootDir string, args []string) error {
+	jsonOut, args := extractBool(args, "--json")
+	if hasHelp(args) || len(args) != 0 {
+		fmt.Printf("Usage: profilex doctor [--json]\n\n")
+		fmt.Printf("Checks adapter definitions, shim setup and each profile's tool binary and version.\n")
+		fmt.Printf("Exits with code 1 when a problem is found.\n")
+		return nil
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+
+	checks := []doctorCheck{}
+	if err := mgr.AdapterLoadError(); err != nil {
+		checks = append(checks, doctorCheck{Name: "adapter definitions", Detail: err.Error()})
+	} else {
+		checks = append(checks, doctorCheck{Name: "adapter definitions", OK: true})
+	}
+	if dir, err := shim.DefaultShimDir(); err == nil {
+		check := doctorCheck{Name: "shim directory on PATH", OK: dirOnPath(dir), Detail: dir}
+		checks = append(checks, check)
+		if st, err := mgr.Load(); err == nil {
+			checks = append(checks, shimDriftCheck(mgr, dir, shim.Targets(st)))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	report, err := mgr.BinaryReport(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	problems := 0
+	for _, c := range checks {
+		if !c.OK {
+			problems++
+		}
+	}
+	for _, b := range report {
+		if b.Missing() {
+			problems++
+		}
+	}
+
+	if jsonOut {
+		payload := map[string]any{"checks": checks, "binaries": report, "problems": problems}
+		b, _ := json.MarshalIndent(payload, "", "  ")
+		fmt.Println(string(b))
+	} else {
+		fmt.Printf("%s\n\n", Bold("🩺 ProfileX doctor"))
+		for _, c := range checks {
+			icon := Green("●")
+			if !c.OK {
+				icon = Red("✗")
+			}
+			fmt.Printf("   %s %-24s %s\n", icon, c.Name, Dim(c.Detail))
+		}
+		if len(report) > 0 {
+			fmt.Printf("\n   %s\n", Bold("Tool binaries"))
+			for _, b := range report {
+				fmt.Printf("   %s\n", describeBinary(b))
+			}
+		}
+		fmt.Println()
+		if problems == 0 {
+			fmt.Printf("%s No problems found\n", Green("✓"))
+		} else {
+			fmt.Printf("%s %d problem(s) found\n", Red("✗"), problems)
+		}
+	}
+	if problems > 0 {
+		return app.ExitCodeError{Code: 1}
+	}
+	return nil
+}
+
+func dirOnPath(dir string) bool {
+	want := filepath.Clean(dir)
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		if strings.TrimSpace(p) != "" && filepath.Clean(p) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// shimDriftCheck flags stale, foreign and orphaned shims. Missing shims are
+// not a problem here: shell-init users may not want file shims at all.
+func shimDriftCheck(mgr *app.Manager, dir string, targets []shim.Target) doctorCheck {
+	check := doctorCheck{Name: "shims up to date", OK: true}
+	statuses, err := shim.Check(dir, targets, resolveProfileXBin())
+	if err != nil {
+		check.OK, check.Detail = false, err.Error()
+		return check
+	}
+	counts := map[shim.State]int{}
+	for _, s := range statuses {
+		if s.State == shim.StateOrphaned && !usesDefaultRoot(mgr) {
+			continue
+		}
+		counts[s.State]++
+	}
+	parts := []string{}
+	for _, state := range []shim.State{shim.StateStale, shim.StateForeign, shim.StateOrphaned} {
+		if counts[state] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[state], state))
+		}
+	}
+	if len(parts) > 0 {
+		check.OK = false
+		check.Detail = strings.Join(parts, ", ") + "; see profilex shim status"
+	}
+	return check
+}
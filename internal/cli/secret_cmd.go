@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/derekurban/profilex-cli/internal/secrets"
+)
+
+func cmdSecret(rootDir string, args []string) error {
+	if len(args) == 0 || hasHelp(args) {
+		printSecretHelp()
+		return nil
+	}
+
+	sub := args[0]
+	rest := args[1:]
+	switch sub {
+	case "set":
+		return cmdSecretSet(rootDir, rest)
+	case "rm", "unset":
+		return cmdSecretRemove(rootDir, rest)
+	case "list":
+		return cmdSecretList(rootDir, rest)
+	case "keygen":
+		return cmdSecretKeygen(rootDir, rest)
+	default:
+		return fmt.Errorf("unknown secret subcommand %q", sub)
+	}
+}
+
+func printSecretHelp() {
+	fmt.Printf("Usage:\n")
+	fmt.Printf("  profilex secret set <tool> <profile>     Store an API key (read from stdin or prompted)\n")
+	fmt.Printf("  profilex secret rm <tool> <profile>      Delete the key and return to OAuth files\n")
+	fmt.Printf("  profilex secret list [--json]            List stored keys (values are never shown)\n")
+	fmt.Printf("  profilex secret keygen [--out <file>]    Create a key file for headless use\n\n")
+	fmt.Printf("Secrets live in ~/.profilex/secrets/, encrypted with AES-256-GCM. The key comes from\n")
+	fmt.Printf("$%s, ~/.profilex/secrets/key, $%s, or an interactive prompt.\n", secrets.EnvKeyFile, secrets.EnvPassphrase)
+}
+
+func cmdSecretSet(rootDir string, args []string) error {
+	if hasHelp(args) || len(args) != 2 {
+		printSecretHelp()
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+
+	var value []byte
+	if term.IsTerminal(os.Stdin.Fd()) {
+		fmt.Fprintf(os.Stderr, "API key for %s/%s: ", tool, args[1])
+		value, err = term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+	} else {
+		value, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := mgr.SetAPIKey(tool, args[1], string(value)); err != nil {
+		return err
+	}
+	fmt.Printf("%s Stored API key for %s/%s\n", Green("✓"), tool, Bold(args[1]))
+	fmt.Printf("   🔐 Launches now inject the key into the tool's environment only.\n")
+	return nil
+}
+
+func cmdSecretRemove(rootDir string, args []string) error {
+	if hasHelp(args) || len(args) != 2 {
+		printSecretHelp()
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	if err := mgr.ClearAPIKey(tool, args[1]); err != nil {
+		return err
+	}
+	fmt.Printf("%s Removed API key for %s/%s\n", Green("✓"), tool, Bold(args[1]))
+	return nil
+}
+
+func cmdSecretList(rootDir string, args []string) error {
+	jsonOut, args := extractBool(args, "--json")
+	if hasHelp(args) {
+		printSecretHelp()
+		return nil
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	entries, err := mgr.Secrets().List()
+	if err != nil {
+		return err
+	}
+	if jsonOut {
+		b, _ := json.MarshalIndent(entries, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No secrets stored.\n")
+		return nil
+	}
+	for _, e := range entries {
+		fmt.Printf("  🔑 %s/%s %s\n", e.Tool, e.Profile, Dim("("+e.KDF+")"))
+	}
+	return nil
+}
+
+func cmdSecretKeygen(rootDir string, args []string) error {
+	out, args := extractFlag(args, "--out")
+	if hasHelp(args) {
+		printSecretHelp()
+		return nil
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(out) == "" {
+		out = mgr.Secrets().DefaultKeyFile()
+	}
+	if err := secrets.GenerateKeyFile(out); err != nil {
+		return err
+	}
+	fmt.Printf("%s Created key file %s\n", Green("✓"), Cyan(out))
+	if out != mgr.Secrets().DefaultKeyFile() {
+		fmt.Printf("   💡 Set %s=%s to use it.\n", secrets.EnvKeyFile, out)
+	}
+	return nil
+}
+
+// promptPassphrase reads the secret store passphrase from the terminal
+// without echo. The prompt goes to stderr so shim env output stays clean.
+func promptPassphrase(confirm bool) ([]byte, error) {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return nil, secrets.ErrNoKey
+	}
+	fmt.Fprint(os.Stderr, "ProfileX secret passphrase: ")
+	pass, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Confirm passphrase: ")
+		again, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		if string(again) != string(pass) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return pass, nil
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/app"
+	"github.com/derekurban/profilex-cli/internal/usage"
+)
+
+func cmdSessions(rootDir string, args []string) error {
+	if len(args) == 0 || hasHelp(args) {
+		printSessionsHelp()
+		return nil
+	}
+
+	sub := args[0]
+	rest := args[1:]
+	switch sub {
+	case "export":
+		return cmdSessionsExport(rootDir, rest)
+	case "prune":
+		return cmdSessionsPrune(rootDir, rest)
+	default:
+		return fmt.Errorf("unknown sessions subcommand %q", sub)
+	}
+}
+
+func printSessionsHelp() {
+	fmt.Printf("Usage:\n")
+	fmt.Printf("  profilex sessions export <id|file> [--format md|html|json] [--out <file>] [--redact-paths] [--redact-secrets]\n")
+	fmt.Printf("  profilex sessions prune --older-than <age> [--archive <file>] [--dry-run] [--json]\n\n")
+	fmt.Printf("Export options:\n")
+	fmt.Printf("  --format <fmt>         md|html|json (default: md)\n")
+	fmt.Printf("  --out <file>           Write to file instead of stdout\n")
+	fmt.Printf("  --redact-paths         Replace home and project paths with ~ and <project>\n")
+	fmt.Printf("  --redact-secrets       Mask API keys, tokens and password assignments\n")
+	fmt.Printf("  --redact               Shorthand for both redaction options\n")
+	fmt.Printf("\nPrune options:\n")
+	fmt.Printf("  --older-than <age>     Remove sessions last modified before this age (e.g. 90d, 12w, 36h)\n")
+	fmt.Printf("  --archive <file>       Write pruned sessions to a .tar, .tar.gz or .tar.zst first\n")
+	fmt.Printf("  --dry-run              Only report what would be pruned\n")
+}
+
+func cmdSessionsExport(rootDir string, args []string) error {
+	formatRaw, args := extractFlag(args, "--format")
+	outPath, args := extractFlag(args, "--out")
+	redactAll, args := extractBool(args, "--redact")
+	redactPaths, args := extractBool(args, "--redact-paths")
+	redactSecrets, args := extractBool(args, "--redact-secrets")
+
+	if hasHelp(args) || len(args) != 1 {
+		printSessionsHelp()
+		return nil
+	}
+
+	format, err := parseTranscriptFormat(formatRaw, outPath)
+	if err != nil {
+		return err
+	}
+
+	resolvedRoot, err := resolveRootDir(rootDir)
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+
+	path, err := usage.FindSessionFile(resolvedRoot, st, args[0])
+	if err != nil {
+		return err
+	}
+	transcript, err := usage.LoadTranscript(path)
+	if err != nil {
+		return err
+	}
+	usage.RedactTranscript(transcript, usage.RedactOptions{
+		Paths:   redactAll || redactPaths,
+		Secrets: redactAll || redactSecrets,
+	})
+
+	var buf bytes.Buffer
+	if err := usage.RenderTranscript(&buf, transcript, format); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(outPath) == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(outPath, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("%s Exported session %s\n", Green("✓"), Bold(transcript.SessionID))
+	fmt.Printf("   📄 File: %s\n", Dim(outPath))
+	fmt.Printf("   💬 Turns: %d\n", len(transcript.Turns))
+	return nil
+}
+
+func parseTranscriptFormat(raw, outPath string) (usage.TranscriptFormat, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "" {
+		switch strings.ToLower(filepath.Ext(outPath)) {
+		case ".html", ".htm":
+			return usage.TranscriptHTML, nil
+		case ".json":
+			return usage.TranscriptJSON, nil
+		}
+		return usage.TranscriptMarkdown, nil
+	}
+	switch raw {
+	case "md", "markdown":
+		return usage.TranscriptMarkdown, nil
+	case "html":
+		return usage.TranscriptHTML, nil
+	case "json":
+		return usage.TranscriptJSON, nil
+	default:
+		return "", fmt.Errorf("invalid --format %q (expected md|html|json)", raw)
+	}
+}
+
+func cmdSessionsPrune(rootDir string, args []string) error {
+	ageRaw, args := extractFlag(args, "--older-than")
+	archive, args := extractFlag(args, "--archive")
+	dryRun, args := extractBool(args, "--dry-run")
+	jsonOut, args := extractBool(args, "--json")
+
+	if hasHelp(args) {
+		printSessionsHelp()
+		return nil
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unknown argument(s): %s", strings.Join(args, " "))
+	}
+	if strings.TrimSpace(ageRaw) == "" {
+		return fmt.Errorf("--older-than is required (e.g. --older-than 90d)")
+	}
+	age, err := parseAge(ageRaw)
+	if err != nil {
+		return err
+	}
+
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	result, err := mgr.PruneSessions(app.PruneOptions{OlderThan: age, Archive: archive, DryRun: dryRun})
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		b, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+
+	verb := "Pruned"
+	if dryRun {
+		verb = "Would prune"
+		for _, f := range result.Files {
+			fmt.Printf("   %s  %10s  %s\n", f.ModTime.Local().Format("2006-01-02"), formatBytes(f.Bytes), Dim(f.Path))
+		}
+	}
+	fmt.Printf("%s %s %d of %d session file(s), %s (older than %s)\n",
+		Green("✓"), verb, len(result.Files), result.Scanned, formatBytes(result.Bytes), result.Cutoff.Local().Format("2006-01-02"))
+	if result.Archived > 0 {
+		fmt.Printf("   📦 Archive: %s\n", Dim(result.Archive))
+	}
+	for _, s := range result.Skipped {
+		fmt.Printf("   %s %s\n", Yellow("⚠"), s)
+	}
+	return nil
+}
+
+// parseAge accepts Go durations plus day (d) and week (w) suffixes.
+func parseAge(raw string) (time.Duration, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(raw, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v <= 0 {
+				return 0, fmt.Errorf("invalid age %q", raw)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q (expected e.g. 90d, 12w, 36h)", raw)
+	}
+	return d, nil
+}
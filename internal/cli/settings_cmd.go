@@ -3,17 +3,29 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
+	"github.com/derekurban/profilex-cli/internal/app"
 	"github.com/derekurban/profilex-cli/internal/store"
 )
 
 func cmdSettings(rootDir string, args []string) error {
 	if len(args) == 0 || hasHelp(args) {
 		fmt.Printf("Usage:\n")
-		fmt.Printf("  profilex settings snapshot <tool> <profile|default> <preset>\n")
-		fmt.Printf("  profilex settings apply <tool> <preset> <profile|default>\n")
+		fmt.Printf("  profilex settings snapshot <tool> <profile|default> <preset> [--include <paths>] [--exclude <paths>]\n")
+		fmt.Printf("  profilex settings apply <tool> <preset> <profile|default> [--preview [--json]]\n")
+		fmt.Printf("  profilex settings strategy <tool> <preset> [replace|merge|union]\n")
+		fmt.Printf("  profilex settings extend <tool> <preset> <parent>|--clear\n")
+		fmt.Printf("  profilex settings show <tool> <preset> [--effective] [--json]\n")
+		fmt.Printf("  profilex settings diff <tool> <a> <b> [--json]\n")
+		fmt.Printf("  profilex settings log <tool> <preset> [--json]\n")
+		fmt.Printf("  profilex settings rollback <tool> <preset> <rev>\n")
 		fmt.Printf("  profilex settings list [--tool <tool>] [--json]\n")
+		fmt.Printf("  profilex settings paths <tool> <preset> [--include <paths>] [--exclude <paths>] [--reset] [--json]\n")
+		fmt.Printf("  profilex settings sync <tool> <profile|default> <preset> [--local-edits overwrite|preserve|promote]\n")
+		fmt.Printf("  profilex settings sync <tool> <profile|default> --off\n")
 		fmt.Printf("\n")
 		fmt.Printf("Special profile aliases: default, native, @default, @native\n")
 		return nil
@@ -28,14 +40,32 @@ func cmdSettings(rootDir string, args []string) error {
 		return cmdSettingsApply(rootDir, rest)
 	case "list":
 		return cmdSettingsList(rootDir, rest)
+	case "sync":
+		return cmdSettingsSync(rootDir, rest)
+	case "paths":
+		return cmdSettingsPaths(rootDir, rest)
+	case "strategy":
+		return cmdSettingsStrategy(rootDir, rest)
+	case "extend":
+		return cmdSettingsExtend(rootDir, rest)
+	case "show":
+		return cmdSettingsShow(rootDir, rest)
+	case "diff":
+		return cmdSettingsDiff(rootDir, rest)
+	case "log":
+		return cmdSettingsLog(rootDir, rest)
+	case "rollback":
+		return cmdSettingsRollback(rootDir, rest)
 	default:
 		return fmt.Errorf("unknown settings subcommand: %s", sub)
 	}
 }
 
 func cmdSettingsSnapshot(rootDir string, args []string) error {
+	include, args := extractFlag(args, "--include")
+	exclude, args := extractFlag(args, "--exclude")
 	if hasHelp(args) || len(args) != 3 {
-		fmt.Printf("Usage: profilex settings snapshot <tool> <profile|default> <preset>\n")
+		fmt.Printf("Usage: profilex settings snapshot <tool> <profile|default> <preset> [--include <paths>] [--exclude <paths>]\n")
 		return nil
 	}
 	tool, err := parseTool(args[0])
@@ -46,18 +76,92 @@ func cmdSettingsSnapshot(rootDir string, args []string) error {
 	if err != nil {
 		return err
 	}
+	if include != "" || exclude != "" {
+		if err := mgr.SetSettingsPresetPaths(tool, args[2], splitList(include), splitList(exclude)); err != nil {
+			return err
+		}
+	}
 	_, err = mgr.SnapshotSettings(tool, args[1], args[2])
 	if err != nil {
 		return err
 	}
 	fmt.Printf("%s Snapshot saved: %s/%s from %s\n", Green("ok"), tool, args[2], args[1])
-	fmt.Printf("   Included paths: %s\n", Dim(settingsPathHint(tool)))
+	if scope, err := mgr.SettingsPresetScope(tool, args[2]); err == nil {
+		fmt.Printf("   Included paths: %s\n", Dim(strings.Join(scope.Paths, ", ")))
+	}
+	if revs, err := mgr.SettingsPresetLog(tool, args[2]); err == nil && len(revs) > 0 {
+		fmt.Printf("   Revision: %d\n", revs[len(revs)-1].Rev)
+	}
+	return nil
+}
+
+func cmdSettingsPaths(rootDir string, args []string) error {
+	include, args := extractFlag(args, "--include")
+	exclude, args := extractFlag(args, "--exclude")
+	reset, args := extractBool(args, "--reset")
+	jsonOut, args := extractBool(args, "--json")
+	if hasHelp(args) || len(args) != 2 {
+		fmt.Printf("Usage: profilex settings paths <tool> <preset> [--include <paths>] [--exclude <paths>] [--reset] [--json]\n")
+		fmt.Printf("\nPaths are comma-separated and relative to the profile directory. --include adds to the\n")
+		fmt.Printf("tool's default allowlist; --exclude drops paths or files inside them (patterns allowed).\n")
+		fmt.Printf("Each flag replaces the preset's previous list; --reset clears both.\n")
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	if reset || include != "" || exclude != "" {
+		st, err := mgr.Load()
+		if err != nil {
+			return err
+		}
+		var current store.SettingsPreset
+		if _, p := store.FindSettingsPreset(st, tool, args[1]); p != nil && !reset {
+			current = *p
+		}
+		inc, exc := current.Include, current.Exclude
+		if include != "" {
+			inc = splitList(include)
+		}
+		if exclude != "" {
+			exc = splitList(exclude)
+		}
+		if err := mgr.SetSettingsPresetPaths(tool, args[1], inc, exc); err != nil {
+			return err
+		}
+	}
+	scope, err := mgr.SettingsPresetScope(tool, args[1])
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		b, _ := json.MarshalIndent(scope, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+	fmt.Printf("%s\n", Bold(string(tool)+"/"+args[1]))
+	fmt.Printf("  Included: %s\n", strings.Join(scope.Paths, ", "))
+	if len(scope.Exclude) > 0 {
+		fmt.Printf("  Excluded: %s\n", strings.Join(scope.Exclude, ", "))
+	}
+	if len(scope.Blocked) > 0 {
+		fmt.Printf("  Never captured: %s\n", Dim(strings.Join(scope.Blocked, ", ")))
+	}
 	return nil
 }
 
 func cmdSettingsApply(rootDir string, args []string) error {
+	preview, args := extractBool(args, "--preview")
+	jsonOut, args := extractBool(args, "--json")
 	if hasHelp(args) || len(args) != 3 {
-		fmt.Printf("Usage: profilex settings apply <tool> <preset> <profile|default>\n")
+		fmt.Printf("Usage: profilex settings apply <tool> <preset> <profile|default> [--preview [--json]]\n")
+		fmt.Printf("\n--preview prints each resulting file, merged per the preset's strategy, without writing.\n")
 		return nil
 	}
 	tool, err := parseTool(args[0])
@@ -68,6 +172,9 @@ func cmdSettingsApply(rootDir string, args []string) error {
 	if err != nil {
 		return err
 	}
+	if preview {
+		return printSettingsPreview(mgr, tool, args[1], args[2], jsonOut)
+	}
 	if err := mgr.ApplySettingsPreset(tool, args[1], args[2]); err != nil {
 		return err
 	}
@@ -75,6 +182,320 @@ func cmdSettingsApply(rootDir string, args []string) error {
 	return nil
 }
 
+func printSettingsPreview(mgr *app.Manager, tool store.Tool, preset, profile string, jsonOut bool) error {
+	previews, err := mgr.PreviewSettingsPreset(tool, preset, profile)
+	if err != nil {
+		return err
+	}
+	if jsonOut {
+		b, _ := json.MarshalIndent(previews, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+	fmt.Printf("%s %s\n", Bold("Preview: "+string(tool)+"/"+preset+" -> "+profile), Dim("(nothing written)"))
+	for _, p := range previews {
+		status := Dim("unchanged")
+		if p.Changed {
+			status = Yellow("changes")
+		}
+		fmt.Println()
+		switch {
+		case p.Removed:
+			fmt.Printf("%s %s %s\n", Cyan(p.Path), Dim("["+string(p.Strategy)+"]"), Red("removed"))
+		case p.Dir:
+			fmt.Printf("%s/ %s %s\n", Cyan(p.Path), Dim("["+string(p.Strategy)+"]"), status)
+		default:
+			fmt.Printf("%s %s %s\n", Cyan(p.Path), Dim("["+string(p.Strategy)+"]"), status)
+			fmt.Print(p.Content)
+			if p.Content != "" && !strings.HasSuffix(p.Content, "\n") {
+				fmt.Println()
+			}
+		}
+	}
+	return nil
+}
+
+func cmdSettingsStrategy(rootDir string, args []string) error {
+	if hasHelp(args) || len(args) < 2 || len(args) > 3 {
+		fmt.Printf("Usage: profilex settings strategy <tool> <preset> [replace|merge|union]\n")
+		fmt.Printf("\nHow applying the preset combines JSON and TOML files with the profile's own:\n")
+		fmt.Printf("  replace  overwrite them (default)\n")
+		fmt.Printf("  merge    merge objects and tables key by key, the preset winning; arrays replaced\n")
+		fmt.Printf("  union    like merge, but arrays keep the profile's entries and add the preset's\n")
+		fmt.Printf("Other files and directories are always replaced.\n")
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	if len(args) == 3 {
+		strategy, err := store.ParseMergeStrategy(args[2])
+		if err != nil {
+			return err
+		}
+		if err := mgr.SetSettingsPresetMerge(tool, args[1], strategy); err != nil {
+			return err
+		}
+		fmt.Printf("%s %s/%s now applies with %s\n", Green("ok"), tool, args[1], strategy)
+		return nil
+	}
+	scope, err := mgr.SettingsPresetScope(tool, args[1])
+	if err != nil {
+		return err
+	}
+	strategy := scope.Merge
+	if strategy == "" {
+		strategy = store.MergeReplace
+	}
+	fmt.Println(strategy)
+	return nil
+}
+
+func cmdSettingsExtend(rootDir string, args []string) error {
+	unset, args := extractBool(args, "--clear")
+	if hasHelp(args) || (unset && len(args) != 2) || (!unset && len(args) != 3) {
+		fmt.Printf("Usage: profilex settings extend <tool> <preset> <parent>\n")
+		fmt.Printf("       profilex settings extend <tool> <preset> --clear\n")
+		fmt.Printf("\nThe parent's files are applied first and the preset's are layered on top using the\n")
+		fmt.Printf("preset's strategy. Paths the preset lacks are inherited.\n")
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	parent := ""
+	if !unset {
+		parent = args[2]
+	}
+	if err := mgr.SetSettingsPresetParent(tool, args[1], parent); err != nil {
+		return err
+	}
+	if unset {
+		fmt.Printf("%s %s/%s no longer extends another preset\n", Green("ok"), tool, args[1])
+		return nil
+	}
+	chain, err := mgr.SettingsPresetChain(tool, args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s/%s extends %s\n", Green("ok"), tool, args[1], strings.Join(chain[:len(chain)-1], " <- "))
+	return nil
+}
+
+func cmdSettingsShow(rootDir string, args []string) error {
+	effective, args := extractBool(args, "--effective")
+	jsonOut, args := extractBool(args, "--json")
+	if hasHelp(args) || len(args) != 2 {
+		fmt.Printf("Usage: profilex settings show <tool> <preset> [--effective] [--json]\n")
+		fmt.Printf("\n--effective shows what the preset resolves to after layering it over its parents.\n")
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	view, err := mgr.ShowSettingsPreset(tool, args[1], effective)
+	if err != nil {
+		return err
+	}
+	if jsonOut {
+		b, _ := json.MarshalIndent(view, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+
+	fmt.Printf("%s\n", Bold(string(tool)+"/"+args[1]))
+	if len(view.Chain) > 1 {
+		fmt.Printf("  Extends:  %s\n", strings.Join(view.Chain[:len(view.Chain)-1], " <- "))
+	}
+	fmt.Printf("  Strategy: %s\n", view.Merge)
+	if effective {
+		fmt.Printf("  %s\n", Dim("(effective result of the whole chain)"))
+	}
+	if len(view.Files) == 0 {
+		fmt.Println("\n  (no files)")
+	}
+	for _, f := range view.Files {
+		from := ""
+		if effective {
+			from = " " + Dim("from "+strings.Join(f.From, ", "))
+		}
+		fmt.Println()
+		if f.Dir {
+			fmt.Printf("%s/%s\n", Cyan(f.Path), from)
+			continue
+		}
+		fmt.Printf("%s%s\n", Cyan(f.Path), from)
+		fmt.Print(f.Content)
+		if f.Content != "" && !strings.HasSuffix(f.Content, "\n") {
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+func cmdSettingsDiff(rootDir string, args []string) error {
+	jsonOut, args := extractBool(args, "--json")
+	if hasHelp(args) || len(args) != 3 {
+		fmt.Printf("Usage: profilex settings diff <tool> <a> <b> [--json]\n")
+		fmt.Printf("\nEach side is a preset, a profile or default. Prefix preset: or profile: when a name is both.\n")
+		fmt.Printf("<preset>@<rev> compares one of a preset's revisions (see settings log).\n")
+		fmt.Printf("JSON and TOML files are compared key by key, other files line by line.\n")
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	diff, err := mgr.DiffSettings(tool, args[1], args[2])
+	if err != nil {
+		return err
+	}
+	if jsonOut {
+		b, _ := json.MarshalIndent(diff, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+	fmt.Printf("%s %s\n", Red("---"), diff.A)
+	fmt.Printf("%s %s\n", Green("+++"), diff.B)
+	if len(diff.Files) == 0 {
+		fmt.Printf("\n%s\n", Dim("No differences."))
+		return nil
+	}
+	for _, line := range formatSettingsDiff(diff) {
+		switch {
+		case line == "":
+			fmt.Println()
+		case !strings.HasPrefix(line, " "):
+			fmt.Println(Cyan(line))
+		case strings.HasPrefix(line, "  +"):
+			fmt.Println(Green(line))
+		case strings.HasPrefix(line, "  -"):
+			fmt.Println(Red(line))
+		default:
+			fmt.Println(Yellow(line))
+		}
+	}
+	return nil
+}
+
+// formatSettingsDiff renders a diff as plain lines: a header per file, then
+// "  + ", "  - " and "  ~ " lines for added, removed and changed keys or
+// lines. Files are separated by a blank line.
+func formatSettingsDiff(diff app.SettingsDiff) []string {
+	lines := []string{}
+	for _, f := range diff.Files {
+		lines = append(lines, "", fmt.Sprintf("%s (%s)", f.Path, f.Status))
+		for _, k := range f.Keys {
+			switch k.Op {
+			case "added":
+				lines = append(lines, fmt.Sprintf("  + %s = %s", k.Key, k.B))
+			case "removed":
+				lines = append(lines, fmt.Sprintf("  - %s = %s", k.Key, k.A))
+			default:
+				lines = append(lines, fmt.Sprintf("  ~ %s = %s -> %s", k.Key, k.A, k.B))
+			}
+		}
+		for _, l := range f.Lines {
+			lines = append(lines, "  "+l)
+		}
+	}
+	return lines
+}
+
+func cmdSettingsLog(rootDir string, args []string) error {
+	jsonOut, args := extractBool(args, "--json")
+	if hasHelp(args) || len(args) != 2 {
+		fmt.Printf("Usage: profilex settings log <tool> <preset> [--json]\n")
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	revs, err := mgr.SettingsPresetLog(tool, args[1])
+	if err != nil {
+		return err
+	}
+	if jsonOut {
+		b, _ := json.MarshalIndent(revs, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+	if len(revs) == 0 {
+		fmt.Println("No revisions recorded yet; the next snapshot starts the history.")
+		return nil
+	}
+	fmt.Printf("%s\n", Bold(string(tool)+"/"+args[1]))
+	for i := len(revs) - 1; i >= 0; i-- {
+		r := revs[i]
+		source := r.Source
+		switch {
+		case source == "":
+			source = "(before history)"
+		case !strings.HasPrefix(source, "rollback"):
+			source = "from " + source
+		}
+		marker := "  "
+		if i == len(revs)-1 {
+			marker = Green("* ")
+		}
+		fmt.Printf("%s%-4s %s  %-24s %s\n", marker, fmt.Sprintf("%d", r.Rev), r.CreatedAt.Local().Format("2006-01-02 15:04"), source, Dim(r.Hash[:min(12, len(r.Hash))]))
+	}
+	fmt.Println()
+	fmt.Printf("%s\n", Dim("Compare with: profilex settings diff "+string(tool)+" "+args[1]+"@<rev> "+args[1]))
+	return nil
+}
+
+func cmdSettingsRollback(rootDir string, args []string) error {
+	if hasHelp(args) || len(args) != 3 {
+		fmt.Printf("Usage: profilex settings rollback <tool> <preset> <rev>\n")
+		fmt.Printf("\nRestores the preset's files from a revision and records that as a new revision.\n")
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	rev, err := strconv.Atoi(strings.TrimPrefix(args[2], "@"))
+	if err != nil || rev < 1 {
+		return fmt.Errorf("invalid revision %q", args[2])
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	saved, err := mgr.RollbackSettingsPreset(tool, args[1], rev)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s Rolled %s/%s back to revision %d (now revision %d)\n", Green("ok"), tool, args[1], rev, saved.Rev)
+	fmt.Printf("   %s\n", Dim("Synced profiles pick it up on their next launch; run settings apply to update others."))
+	return nil
+}
+
 func cmdSettingsList(rootDir string, args []string) error {
 	toolFlag, args := extractFlag(args, "--tool")
 	jsonOut, args := extractBool(args, "--json")
@@ -96,7 +517,7 @@ func cmdSettingsList(rootDir string, args []string) error {
 	if err != nil {
 		return err
 	}
-	presets, _, err := mgr.ListSettings(filter)
+	presets, syncs, err := mgr.ListSettings(filter)
 	if err != nil {
 		return err
 	}
@@ -104,6 +525,7 @@ func cmdSettingsList(rootDir string, args []string) error {
 	if jsonOut {
 		payload := map[string]any{
 			"presets": presets,
+			"sync":    syncs,
 		}
 		b, _ := json.MarshalIndent(payload, "", "  ")
 		fmt.Println(string(b))
@@ -115,7 +537,25 @@ func cmdSettingsList(rootDir string, args []string) error {
 		fmt.Println("  (none)")
 	} else {
 		for _, p := range presets {
-			fmt.Printf("  - %s/%s\n", p.Tool, p.Name)
+			notes := []string{}
+			if p.Parent != "" {
+				notes = append(notes, "extends "+p.Parent)
+			}
+			if p.Merge != "" && p.Merge != store.MergeReplace {
+				notes = append(notes, string(p.Merge))
+			}
+			if len(notes) > 0 {
+				fmt.Printf("  - %s/%s %s\n", p.Tool, p.Name, Dim("("+strings.Join(notes, ", ")+")"))
+			} else {
+				fmt.Printf("  - %s/%s\n", p.Tool, p.Name)
+			}
+		}
+	}
+	if len(syncs) > 0 {
+		fmt.Println()
+		fmt.Printf("%s\n", Bold("Synced Profiles"))
+		for _, s := range syncs {
+			fmt.Printf("  - %s/%s <- %s %s\n", s.Tool, s.Profile, s.Preset, Dim("(local edits: "+string(s.Policy())+")"))
 		}
 	}
 	fmt.Println()
@@ -137,13 +577,75 @@ func cmdSettingsList(rootDir string, args []string) error {
 	return nil
 }
 
-func settingsPathHint(tool store.Tool) string {
-	switch tool {
-	case store.ToolCodex:
-		return "config.toml"
-	case store.ToolClaude:
-		return "settings.json"
-	default:
-		return "(unknown)"
+func cmdSettingsSync(rootDir string, args []string) error {
+	off, args := extractBool(args, "--off")
+	rawPolicy, args := extractFlag(args, "--local-edits")
+	if hasHelp(args) || len(args) < 2 || len(args) > 3 || (off && len(args) != 2) || (len(args) == 2 && !off && rawPolicy == "") {
+		fmt.Printf("Usage: profilex settings sync <tool> <profile|default> <preset> [--local-edits overwrite|preserve|promote]\n")
+		fmt.Printf("       profilex settings sync <tool> <profile|default> --off\n")
+		fmt.Printf("\nBound profiles are brought back in line with the preset before each launch.\n")
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	var policy store.SyncEditPolicy
+	if rawPolicy != "" {
+		if policy, err = store.ParseSyncEditPolicy(rawPolicy); err != nil {
+			return err
+		}
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+
+	if off {
+		if err := mgr.SetSettingsSync(tool, args[1], "", false); err != nil {
+			return err
+		}
+		fmt.Printf("%s %s/%s is no longer synced\n", Green("ok"), tool, args[1])
+		return nil
+	}
+	if len(args) == 3 {
+		if err := mgr.SetSettingsSync(tool, args[1], args[2], true); err != nil {
+			return err
+		}
+		fmt.Printf("%s Synced %s/%s to settings preset %s\n", Green("ok"), tool, args[1], args[2])
+	}
+	if policy != "" {
+		if err := mgr.SetSettingsSyncPolicy(tool, args[1], policy); err != nil {
+			return err
+		}
+		fmt.Printf("   Local edits: %s\n", policy)
+	}
+	return nil
+}
+
+// syncSettingsBeforeLaunch reapplies a bound settings preset and reports
+// what changed on stderr, which keeps `shim env` output parseable. A failed
+// sync is reported but never blocks the launch.
+func syncSettingsBeforeLaunch(mgr *app.Manager, profile store.Profile) {
+	res, err := mgr.ApplySyncedSettings(profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s profilex: could not sync settings for %s/%s: %v\n", Yellow("⚠"), profile.Tool, profile.Name, err)
+		return
+	}
+	target := string(profile.Tool) + "/" + profile.Name
+	if len(res.Applied) > 0 {
+		fmt.Fprintf(os.Stderr, "%s profilex: applied preset %s to %s: %s\n", Cyan("↻"), res.Preset, target, strings.Join(res.Applied, ", "))
+	}
+	if len(res.Overwritten) > 0 {
+		fmt.Fprintf(os.Stderr, "%s profilex: replaced local edits to %s with preset %s\n", Cyan("↻"), strings.Join(res.Overwritten, ", "), res.Preset)
+	}
+	if len(res.Promoted) > 0 {
+		fmt.Fprintf(os.Stderr, "%s profilex: saved local edits to %s into preset %s\n", Cyan("↻"), strings.Join(res.Promoted, ", "), res.Preset)
+	}
+	if len(res.Kept) > 0 {
+		fmt.Fprintf(os.Stderr, "%s profilex: kept local edits to %s; preset %s not applied to them\n", Dim("·"), strings.Join(res.Kept, ", "), res.Preset)
+	}
+	if len(res.Conflicts) > 0 {
+		fmt.Fprintf(os.Stderr, "%s profilex: %s changed in both %s and preset %s; kept the local copy\n", Yellow("⚠"), strings.Join(res.Conflicts, ", "), target, res.Preset)
 	}
 }
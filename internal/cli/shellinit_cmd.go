@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/shim"
+)
+
+func cmdShellInit(rootDir string, args []string) error {
+	if hasHelp(args) {
+		printShellInitHelp()
+		return nil
+	}
+	prompt, rest := extractBool(args, "--prompt")
+	if len(rest) != 1 {
+		printShellInitHelp()
+		return fmt.Errorf("expected exactly one shell")
+	}
+	shell, err := shim.NormalizeShell(rest[0])
+	if err != nil {
+		return err
+	}
+
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+	script, err := shim.ShellInit(shell, st.Profiles, resolveProfileXBin(), shim.InitOptions{Prompt: prompt})
+	if err != nil {
+		return err
+	}
+	fmt.Print(script)
+	return nil
+}
+
+func printShellInitHelp() {
+	fmt.Printf(`Usage: profilex shell-init <%s> [--prompt]
+
+Print shell functions named <tool>-<profile> for every profile, plus
+completions that reuse the wrapped tool's. Load them from your shell rc
+instead of (or alongside) file shims:
+
+  bash/zsh:    eval "$(profilex shell-init bash)"
+  fish:        profilex shell-init fish | source
+  PowerShell:  profilex shell-init powershell | Out-String | Invoke-Expression
+
+--prompt also prefixes the prompt with the active tool/profile when
+PROFILEX_PROFILE is set.
+`, strings.Join(shim.Shells, "|"))
+}
@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+	"github.com/derekurban/profilex-cli/internal/usage"
+)
+
+var promptFormats = []string{"plain", "starship", "tmux"}
+
+func cmdTerm(rootDir string, args []string) error {
+	if hasHelp(args) {
+		printTermHelp()
+		return nil
+	}
+	if len(args) == 0 || args[0] == "--json" {
+		return cmdTermShow(rootDir, args)
+	}
+
+	sub := args[0]
+	rest := args[1:]
+	switch sub {
+	case "enable", "disable":
+		return cmdTermToggle(rootDir, sub == "enable", rest)
+	case "color":
+		return cmdTermColor(rootDir, rest)
+	default:
+		return fmt.Errorf("unknown term subcommand %q", sub)
+	}
+}
+
+func printTermHelp() {
+	fmt.Printf("Usage:\n")
+	fmt.Printf("  profilex term [--json]                          Show enabled terminal integrations\n")
+	fmt.Printf("  profilex term enable <integration...>           Turn integrations on\n")
+	fmt.Printf("  profilex term disable <integration...>          Turn integrations off\n")
+	fmt.Printf("  profilex term color <tool> <profile> <#hex|--clear>\n")
+	fmt.Printf("                                                  Color used for tab colors and tmux\n\n")
+	fmt.Printf("Integrations: %s (default: %s).\n", strings.Join(store.TerminalIntegrations, ", "), strings.Join(store.DefaultTerminalIntegrations, ", "))
+	fmt.Printf("Bash shims and shell-init functions apply them while the tool runs and restore the previous state on exit.\n")
+	fmt.Printf("Windows and vault shims only set the title. Set PROFILEX_TERM=none to skip all of them once.\n")
+}
+
+func cmdTermShow(rootDir string, args []string) error {
+	jsonOut, args := extractBool(args, "--json")
+	if len(args) != 0 {
+		fmt.Printf("Usage: profilex term [--json]\n")
+		return nil
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+	enabled := st.TerminalIntegrations()
+	colors := map[string]string{}
+	for _, p := range st.Profiles {
+		if p.Color != "" {
+			colors[string(p.Tool)+"/"+p.Name] = p.Color
+		}
+	}
+
+	if jsonOut {
+		b, _ := json.MarshalIndent(map[string]any{
+			"integrations": enabled,
+			"colors":       colors,
+		}, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+	for _, name := range store.TerminalIntegrations {
+		mark := Dim("·")
+		if slices.Contains(enabled, name) {
+			mark = Green("✓")
+		}
+		fmt.Printf("  %s %s\n", mark, name)
+	}
+	for _, p := range st.Profiles {
+		if p.Color != "" {
+			fmt.Printf("  %-20s %s\n", string(p.Tool)+"/"+p.Name, p.Color)
+		}
+	}
+	return nil
+}
+
+func cmdTermToggle(rootDir string, enable bool, args []string) error {
+	if len(args) == 0 {
+		fmt.Printf("Usage: profilex term enable|disable <%s...>\n", strings.Join(store.TerminalIntegrations, "|"))
+		return nil
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+	enabled := st.TerminalIntegrations()
+	for _, name := range args {
+		name = strings.ToLower(name)
+		if !slices.Contains(store.TerminalIntegrations, name) {
+			return fmt.Errorf("unknown terminal integration %q (expected %s)", name, strings.Join(store.TerminalIntegrations, ", "))
+		}
+		if enable && !slices.Contains(enabled, name) {
+			enabled = append(enabled, name)
+		}
+		if !enable {
+			enabled = slices.DeleteFunc(enabled, func(s string) bool { return s == name })
+		}
+	}
+	if err := mgr.SetTerminalIntegrations(enabled); err != nil {
+		return err
+	}
+	if len(enabled) == 0 {
+		fmt.Printf("%s Terminal integrations off\n", Green("✓"))
+	} else {
+		fmt.Printf("%s Terminal integrations: %s\n", Green("✓"), strings.Join(enabled, ", "))
+	}
+	return nil
+}
+
+func cmdTermColor(rootDir string, args []string) error {
+	clear, args := extractBool(args, "--clear")
+	if len(args) != 3 && !(clear && len(args) == 2) {
+		fmt.Printf("Usage: profilex term color <tool> <profile> <#hex|--clear>\n")
+		return nil
+	}
+	tool, err := parseTool(args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	color := ""
+	if !clear {
+		color = args[2]
+	}
+	color, err = mgr.SetProfileColor(tool, args[1], color)
+	if err != nil {
+		return err
+	}
+	if color == "" {
+		fmt.Printf("%s Cleared the color for %s/%s\n", Green("✓"), tool, args[1])
+	} else {
+		fmt.Printf("%s %s/%s is %s\n", Green("✓"), tool, args[1], color)
+	}
+	return nil
+}
+
+// cmdPrompt prints a one-line status segment for the active profile, taken
+// from PROFILEX_TOOL/PROFILEX_PROFILE or --profile. It prints nothing when
+// no profile is active, so it can sit in a prompt unconditionally.
+func cmdPrompt(rootDir string, args []string) error {
+	format, args := extractFlag(args, "--format")
+	active, args := extractFlag(args, "--profile")
+	noUsage, args := extractBool(args, "--no-usage")
+	if hasHelp(args) || len(args) != 0 {
+		fmt.Printf("Usage: profilex prompt [--format %s] [--profile <tool>/<profile>] [--no-usage]\n", strings.Join(promptFormats, "|"))
+		return nil
+	}
+	if format == "" {
+		format = "plain"
+	}
+	if !slices.Contains(promptFormats, format) {
+		return fmt.Errorf("unknown prompt format %q (expected %s)", format, strings.Join(promptFormats, ", "))
+	}
+
+	toolName, name := os.Getenv("PROFILEX_TOOL"), os.Getenv("PROFILEX_PROFILE")
+	if active != "" {
+		toolName, name, _ = strings.Cut(active, "/")
+	}
+	if toolName == "" || name == "" {
+		return nil
+	}
+	tool, err := parseTool(toolName)
+	if err != nil {
+		return err
+	}
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+	st, err := mgr.Load()
+	if err != nil {
+		return err
+	}
+	_, profile := store.FindProfile(st, tool, name)
+	if profile == nil {
+		return nil
+	}
+
+	segment := string(profile.Tool) + "/" + profile.Name
+	if !noUsage {
+		// A status line is no place for errors; leave usage off instead.
+		if totals, err := mgr.UsageToday(st, *profile); err == nil && totals.Events > 0 {
+			segment += " " + formatDayUsage(totals)
+		}
+	}
+	if format == "tmux" && profile.Color != "" {
+		segment = "#[fg=" + profile.Color + "]" + segment + "#[default]"
+	}
+	fmt.Println(segment)
+	return nil
+}
+
+// formatDayUsage renders today's totals compactly, e.g. "1.2M tok $3.40".
+func formatDayUsage(t usage.DayTotals) string {
+	var tokens string
+	switch {
+	case t.Tokens >= 1_000_000:
+		tokens = fmt.Sprintf("%.1fM", float64(t.Tokens)/1_000_000)
+	case t.Tokens >= 1_000:
+		tokens = fmt.Sprintf("%.1fk", float64(t.Tokens)/1_000)
+	default:
+		tokens = fmt.Sprintf("%d", t.Tokens)
+	}
+	out := tokens + " tok"
+	if t.CostUSD > 0 {
+		out += fmt.Sprintf(" $%.2f", t.CostUSD)
+	}
+	return out
+}
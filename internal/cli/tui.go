@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -12,6 +11,7 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/derekurban/profilex-cli/internal/adapters"
 	"github.com/derekurban/profilex-cli/internal/app"
 	"github.com/derekurban/profilex-cli/internal/shim"
 	"github.com/derekurban/profilex-cli/internal/store"
@@ -41,6 +41,7 @@ type tuiDataMsg struct {
 	Presets       []store.SettingsPreset
 	SessionShared map[string]bool
 	SkillsShared  map[string]bool
+	Accounts      map[string]adapters.Status
 }
 
 type tuiOpMsg struct {
@@ -103,6 +104,8 @@ type model struct {
 	presets       []store.SettingsPreset
 	sessionShared map[string]bool
 	skillsShared  map[string]bool
+	accounts      map[string]adapters.Status
+	authWindow    time.Duration
 
 	sidebar []sidebarItem
 	cursor  int
@@ -127,12 +130,10 @@ type model struct {
 	templateName   textinput.Model
 	mainCursor     int
 
-	templateWizardStep        int // -1=inactive, 0=tool, 1=source, 2=name, 3=preview
-	templateWizardToolIdx     int
-	templatePreviewPath       string
-	templatePreviewText       string
-	templatePreviewTruncated  bool
-	templatePreviewHasProblem bool
+	templateWizardStep    int // -1=inactive, 0=tool, 1=source, 2=name, 3=preview
+	templateWizardToolIdx int
+	templateDiffBase      string
+	templateDiffLines     []string
 
 	mode       modeKind
 	prompt     textinput.Model
@@ -181,11 +182,18 @@ func newModel(rootDir string) model {
 	p.CharLimit = 64
 	p.Width = 28
 
+	window, err := authWarnWindow()
+	if err != nil {
+		window = app.DefaultAuthWarnWindow
+	}
+
 	return model{
 		rootDir:               rootDir,
 		width:                 120,
 		height:                24,
 		sessionShared:         map[string]bool{},
+		accounts:              map[string]adapters.Status{},
+		authWindow:            window,
 		skillsShared:          map[string]bool{},
 		welcomeActive:         true,
 		wizardStep:            -1,
@@ -219,6 +227,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case tuiDataMsg:
 		m.state, m.presets, m.sessionShared, m.skillsShared = msg.State, msg.Presets, msg.SessionShared, msg.SkillsShared
+		m.accounts = msg.Accounts
 		m.sidebar = buildSidebar(msg.State)
 		m.cursor = selectableCursor(m.sidebar, m.cursor)
 		m.mainCursor = clampIndex(m.mainCursor, m.mainMenuCount())
@@ -261,10 +270,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.Refresh && !msg.IsError() && m.templateWizardStep >= 0 {
 			m.templateWizardStep = -1
 			m.templateName.SetValue("")
-			m.templatePreviewPath = ""
-			m.templatePreviewText = ""
-			m.templatePreviewTruncated = false
-			m.templatePreviewHasProblem = false
+			m.templateDiffBase = ""
+			m.templateDiffLines = nil
 		}
 		return m, tea.Batch(cmds...)
 	case skillsMergeConfirmMsg:
@@ -486,10 +493,8 @@ func (m model) updateTemplatesMain(key tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		m.templateSource = 0
 		m.templateName.SetValue("")
-		m.templatePreviewPath = ""
-		m.templatePreviewText = ""
-		m.templatePreviewTruncated = false
-		m.templatePreviewHasProblem = false
+		m.templateDiffBase = ""
+		m.templateDiffLines = nil
 		return m, nil
 	}
 	return m, nil
@@ -711,17 +716,15 @@ func (m model) updateTemplateWizard(key tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 			opts := m.sourceProfilesForTool(m.templateWizardTool())
 			source := opts[clampIndex(m.templateSource, len(opts))]
-			path, preview, truncated, hasProblem, err := loadTemplatePreview(m.rootDir, m.templateWizardTool(), source)
+			base, lines, err := loadTemplateDiff(m.rootDir, m.templateWizardTool(), source, name)
 			if err != nil {
 				m.statusMsg = err.Error()
 				m.statusIsError = true
 				m.statusExpiry = time.Now().Add(5 * time.Second)
 				return m, tea.Tick(5*time.Second, func(time.Time) tea.Msg { return statusClearMsg{} })
 			}
-			m.templatePreviewPath = path
-			m.templatePreviewText = preview
-			m.templatePreviewTruncated = truncated
-			m.templatePreviewHasProblem = hasProblem
+			m.templateDiffBase = base
+			m.templateDiffLines = lines
 			m.templateWizardStep = 3
 			return m, nil
 		case "esc":
@@ -902,20 +905,15 @@ func (m model) renderHeader() string {
 	info := fmt.Sprintf("ProfileX v%s", ver)
 
 	if m.state != nil && len(m.state.Profiles) > 0 {
-		claudeCount, codexCount := 0, 0
+		counts := map[store.Tool]int{}
 		for _, p := range m.state.Profiles {
-			if p.Tool == store.ToolClaude {
-				claudeCount++
-			} else {
-				codexCount++
-			}
+			counts[p.Tool]++
 		}
 		parts := []string{fmt.Sprintf("%d profiles", len(m.state.Profiles))}
-		if claudeCount > 0 {
-			parts = append(parts, fmt.Sprintf("%d claude", claudeCount))
-		}
-		if codexCount > 0 {
-			parts = append(parts, fmt.Sprintf("%d codex", codexCount))
+		for _, tool := range store.SupportedTools {
+			if counts[tool] > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", counts[tool], tool))
+			}
 		}
 		info += " | " + strings.Join(parts, ", ")
 	}
@@ -1082,12 +1080,7 @@ func (m model) renderWizard(w int) string {
 		lines = append(lines, "Choose your tool:\n")
 		for i, tool := range store.SupportedTools {
 			badge := renderToolBadge(tool)
-			desc := ""
-			if tool == store.ToolClaude {
-				desc = "Anthropic's Claude Code CLI"
-			} else {
-				desc = "OpenAI's Codex CLI"
-			}
+			desc := toolSummary(tool)
 			cursor := "  "
 			if i == m.addToolIdx {
 				cursor = "> "
@@ -1164,10 +1157,7 @@ func (m model) renderTemplateWizard(w int) string {
 		lines := []string{title}
 		lines = append(lines, "Choose tool to template:\n")
 		for i, t := range store.SupportedTools {
-			desc := "Anthropic's Claude Code CLI"
-			if t == store.ToolCodex {
-				desc = "OpenAI's Codex CLI"
-			}
+			desc := toolSummary(t)
 			if i == m.templateWizardToolIdx {
 				lines = append(lines, styleSuccess.Render("> ")+renderToolBadge(t)+"  "+desc)
 			} else {
@@ -1205,20 +1195,26 @@ func (m model) renderTemplateWizard(w int) string {
 			"  Tool:      " + renderToolBadge(tool),
 			"  Source:    " + styleSectionTitle.Render(sourceLabel),
 			"  Template:  " + styleSectionTitle.Render(strings.TrimSpace(m.templateName.Value())),
-			"  File:      " + styleMuted.Render(m.templatePreviewPath),
+			"  Compared:  " + styleMuted.Render(m.templateDiffBase),
 			renderDivider(divW),
 			"",
 		}
 
-		if m.templatePreviewHasProblem {
-			lines = append(lines, styleWarning.Render("Preview note: source settings file missing; template will clear this file when applied."))
-		}
-		lines = append(lines, styleSectionTitle.Render("Preview:"))
-		for _, line := range strings.Split(m.templatePreviewText, "\n") {
-			lines = append(lines, "  "+line)
-		}
-		if m.templatePreviewTruncated {
-			lines = append(lines, "", styleMuted.Render("(preview truncated)"))
+		lines = append(lines, styleSectionTitle.Render("Changes:"))
+		if len(m.templateDiffLines) == 0 {
+			lines = append(lines, "", styleMuted.Render("  No differences."))
+		}
+		for _, line := range m.templateDiffLines {
+			switch {
+			case line == "" || !strings.HasPrefix(line, " "):
+				lines = append(lines, line)
+			case strings.HasPrefix(line, "  +"):
+				lines = append(lines, styleSuccess.Render(line))
+			case strings.HasPrefix(line, "  -"):
+				lines = append(lines, styleError.Render(line))
+			default:
+				lines = append(lines, styleWarning.Render(line))
+			}
 		}
 		lines = append(lines, "", renderDivider(divW), "", styleSuccess.Render("Press Enter to save template")+"  "+styleMuted.Render("Esc to go back"))
 		return strings.Join(lines, "\n")
@@ -1326,12 +1322,24 @@ func (m model) renderProfileCard(w int) string {
 	sessionOn := m.sessionShared[key]
 	skillsOn := m.skillsShared[key]
 
-	lines := []string{
-		badge + "  " + name + defaultTag,
+	lines := []string{badge + "  " + name + defaultTag}
+	if account, ok := m.accounts[key]; ok {
+		if summary := accountSummary(account, time.Now()); summary != "" {
+			style := styleMuted
+			switch app.EvaluateAuth(account, time.Now(), m.authWindow) {
+			case app.AuthHealthExpired:
+				style = styleError
+			case app.AuthHealthExpiring:
+				style = styleWarning
+			}
+			lines = append(lines, style.Render(summary))
+		}
+	}
+	lines = append(lines,
 		renderDivider(divW),
 		m.renderMainItem(0, fmt.Sprintf("Session sharing   %s", renderToggle(sessionOn))),
 		m.renderMainItem(1, fmt.Sprintf("Skills sharing    %s", renderToggle(skillsOn))),
-	}
+	)
 	lines = append(lines, renderDivider(divW))
 	lines = append(lines, m.renderMainItem(2, "Rename profile"))
 	lines = append(lines, m.renderMainItem(3, "Delete profile"))
@@ -1471,6 +1479,13 @@ func (m model) selected() sidebarItem {
 	return sidebarItem{Kind: sidebarHeading}
 }
 
+func toolSummary(tool store.Tool) string {
+	if def, ok := adapters.Lookup(tool); ok {
+		return def.Summary()
+	}
+	return string(tool)
+}
+
 func (m model) templateTool() store.Tool {
 	if len(m.presets) > 0 {
 		return m.presets[m.templateCursor].Tool
@@ -1612,62 +1627,35 @@ func isNativeProfileAlias(name string) bool {
 	}
 }
 
-func loadTemplatePreview(rootDir string, tool store.Tool, source string) (string, string, bool, bool, error) {
+// loadTemplateDiff compares a snapshot source with what the template holds
+// now: the existing preset when the name is taken, or nothing for a new one.
+func loadTemplateDiff(rootDir string, tool store.Tool, source, name string) (string, []string, error) {
 	mgr, err := newManager(rootDir)
 	if err != nil {
-		return "", "", false, false, err
+		return "", nil, err
 	}
 	st, err := mgr.Load()
 	if err != nil {
-		return "", "", false, false, err
+		return "", nil, err
 	}
-
-	source = strings.TrimSpace(source)
 	base := ""
-	if isNativeProfileAlias(source) {
-		base, err = mgr.NativeConfigDir(tool)
-		if err != nil {
-			return "", "", false, false, err
-		}
-	} else {
-		p, err := mgr.GetProfile(st, tool, source)
-		if err != nil {
-			return "", "", false, false, err
-		}
-		base = p.Dir
+	if _, p := store.FindSettingsPreset(st, tool, name); p != nil {
+		base = "preset:" + name
 	}
-
-	rel := settingsPathHint(tool)
-	if rel == "(unknown)" {
-		return "", "", false, false, fmt.Errorf("unsupported tool %q for template preview", tool)
+	target := "profile:" + strings.TrimSpace(source)
+	diff, err := mgr.DiffSettings(tool, base, target)
+	if err != nil && base != "" {
+		// The name may only carry paths or a strategy so far.
+		base = ""
+		diff, err = mgr.DiffSettings(tool, base, target)
 	}
-	path := filepath.Join(base, rel)
-	b, err := os.ReadFile(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return path, "(no settings file found in source)", false, true, nil
-		}
-		return path, "", false, false, err
-	}
-
-	text := strings.ReplaceAll(string(b), "\r\n", "\n")
-	text = strings.TrimRight(text, "\n")
-	if text == "" {
-		text = "(settings file is empty)"
-	}
-	lines := strings.Split(text, "\n")
-	const maxLines = 12
-	truncated := false
-	if len(lines) > maxLines {
-		lines = lines[:maxLines]
-		truncated = true
+		return "", nil, err
 	}
-	for i := range lines {
-		if len(lines[i]) > 120 {
-			lines[i] = lines[i][:117] + "..."
-		}
+	if base == "" {
+		return "new template", formatSettingsDiff(diff), nil
 	}
-	return path, strings.Join(lines, "\n"), truncated, false, nil
+	return diff.A, formatSettingsDiff(diff), nil
 }
 
 func refreshCmd(rootDir string) tea.Cmd {
@@ -1686,6 +1674,7 @@ func refreshCmd(rootDir string) tea.Cmd {
 		}
 		shared := map[string]bool{}
 		skills := map[string]bool{}
+		accounts := map[string]adapters.Status{}
 		for _, p := range st.Profiles {
 			prof, e := mgr.GetProfile(st, p.Tool, p.Name)
 			if e != nil {
@@ -1699,8 +1688,13 @@ func refreshCmd(rootDir string) tea.Cmd {
 			if e == nil {
 				skills[pk(prof.Tool, prof.Name)] = skillsOn
 			}
+			if row, ok := mgr.CachedStatus(prof); ok && row.Error == "" {
+				accounts[pk(prof.Tool, prof.Name)] = row.Status
+			} else if account, ok, e := mgr.OfflineStatusForProfile(prof); e == nil && ok {
+				accounts[pk(prof.Tool, prof.Name)] = account
+			}
 		}
-		return tuiDataMsg{State: st, Presets: presets, SessionShared: shared, SkillsShared: skills}
+		return tuiDataMsg{State: st, Presets: presets, SessionShared: shared, SkillsShared: skills, Accounts: accounts}
 	}
 }
 
@@ -1728,6 +1722,7 @@ func addProfileCmd(rootDir string, tool store.Tool, name string, share, shareSki
 			}
 		}
 		_, _ = installShimForProfile(p)
+		_, _ = repairShimDrift(mgr)
 		return tuiOpMsg{Info: fmt.Sprintf("Created %s/%s", tool, name), Refresh: true}
 	}
 }
@@ -1833,6 +1828,7 @@ func renameProfileCmd(rootDir string, tool store.Tool, oldName, newName string)
 				_, _ = installShimForProfile(*p)
 			}
 		}
+		_, _ = repairShimDrift(mgr)
 		return tuiOpMsg{Info: "Profile renamed", Refresh: true}
 	}
 }
@@ -1849,6 +1845,7 @@ func deleteProfileCmd(rootDir string, tool store.Tool, name string) tea.Cmd {
 		if err := mgr.RemoveProfile(tool, name, false); err != nil {
 			return tuiOpMsg{Err: err}
 		}
+		_, _ = repairShimDrift(mgr)
 		return tuiOpMsg{Info: "Profile deleted", Refresh: true}
 	}
 }
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/app"
+)
+
+func cmdVault(rootDir string, args []string) error {
+	if len(args) == 0 || hasHelp(args) {
+		printVaultHelp()
+		return nil
+	}
+
+	sub := args[0]
+	rest := args[1:]
+	switch sub {
+	case "enable", "disable", "status":
+	default:
+		return fmt.Errorf("unknown vault subcommand %q", sub)
+	}
+
+	jsonOut, rest := extractBool(rest, "--json")
+	if hasHelp(rest) || len(rest) != 2 {
+		printVaultHelp()
+		return nil
+	}
+	tool, err := parseTool(rest[0])
+	if err != nil {
+		return err
+	}
+	name := rest[1]
+
+	mgr, err := newManager(rootDir)
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "enable":
+		profile, err := mgr.EnableVault(tool, name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Vault enabled for %s/%s\n", Green("✓"), tool, Bold(name))
+		fmt.Printf("   🔒 Credentials are sealed while the profile is idle.\n")
+		if path, err := installShimForProfile(profile); err == nil {
+			fmt.Printf("   🔗 Shim now launches through profilex run: %s\n", Dim(path))
+		} else {
+			fmt.Printf("   %s Shim not updated: %v\n", Yellow("⚠"), err)
+		}
+		reportShimRepairs(repairShimDrift(mgr))
+		return nil
+	case "disable":
+		profile, err := mgr.DisableVault(tool, name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Vault disabled for %s/%s\n", Green("✓"), tool, Bold(name))
+		fmt.Printf("   🔓 Credentials restored to the profile directory.\n")
+		if _, err := installShimForProfile(profile); err != nil {
+			fmt.Printf("   %s Shim not updated: %v\n", Yellow("⚠"), err)
+		}
+		reportShimRepairs(repairShimDrift(mgr))
+		return nil
+	default:
+		status, err := mgr.VaultStatus(tool, name)
+		if err != nil {
+			return err
+		}
+		if jsonOut {
+			b, _ := json.MarshalIndent(status, "", "  ")
+			fmt.Println(string(b))
+			return nil
+		}
+		printVaultStatus(status)
+		return nil
+	}
+}
+
+func printVaultHelp() {
+	fmt.Printf("Usage:\n")
+	fmt.Printf("  profilex vault enable <tool> <profile>    Seal credential files while the profile is idle\n")
+	fmt.Printf("  profilex vault disable <tool> <profile>   Restore credential files in plaintext\n")
+	fmt.Printf("  profilex vault status <tool> <profile> [--json]\n\n")
+	fmt.Printf("Vault profiles are unsealed just before launch and re-sealed on exit. They use the\n")
+	fmt.Printf("same key as `profilex secret` (key file or passphrase).\n")
+}
+
+func printVaultStatus(s app.VaultStatus) {
+	label := string(s.Profile.Tool) + "/" + s.Profile.Name
+	if !s.Enabled {
+		fmt.Printf("  %s %s %s\n", Dim("○"), label, Dim("vault disabled"))
+		return
+	}
+	switch {
+	case s.Lease != nil:
+		fmt.Printf("  %s %s %s\n", Yellow("●"), label, Yellow(fmt.Sprintf("unsealed (running, pid %v)", s.Lease.PIDs)))
+	case len(s.Plaintext) > 0:
+		fmt.Printf("  %s %s %s\n", Red("✗"), label, Red("plaintext credentials present: "+strings.Join(s.Plaintext, ", ")))
+	case s.Sealed:
+		fmt.Printf("  %s %s %s\n", Green("🔒"), label, Green("sealed"))
+	default:
+		fmt.Printf("  %s %s %s\n", Dim("○"), label, Dim("no credentials yet (log in with profilex login)"))
+	}
+	if s.Recovered {
+		fmt.Printf("    %s\n", Dim("re-sealed credentials left behind by a crashed run"))
+	}
+}
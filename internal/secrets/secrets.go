@@ -0,0 +1,338 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+const (
+	// EnvPassphrase supplies the store passphrase without prompting.
+	EnvPassphrase = "PROFILEX_PASSPHRASE"
+	// EnvKeyFile points at a key file for headless use. It takes precedence
+	// over the default <root>/secrets/key.
+	EnvKeyFile = "PROFILEX_KEY_FILE"
+
+	kdfPBKDF2  = "pbkdf2-sha256"
+	kdfKeyFile = "keyfile"
+)
+
+// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256.
+// Tests lower it to keep runs fast.
+var pbkdf2Iterations = 600_000
+
+// ErrNoKey is returned when neither a key file nor a passphrase is available.
+var ErrNoKey = errors.New("no secret key available (set " + EnvPassphrase + ", " + EnvKeyFile + " or run `profilex secret keygen`)")
+
+// Key unlocks the store. Exactly one of KeyFile or Passphrase is used, with
+// KeyFile taking precedence.
+type Key struct {
+	KeyFile    string
+	Passphrase []byte
+}
+
+// PromptFunc asks the user for the passphrase. confirm is true when a new
+// secret is being written and the passphrase should be entered twice.
+type PromptFunc func(confirm bool) ([]byte, error)
+
+type Store struct {
+	dir     string
+	keyFile string
+}
+
+type envelope struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+type Entry struct {
+	Tool    store.Tool `json:"tool"`
+	Profile string     `json:"profile"`
+	KDF     string     `json:"kdf"`
+}
+
+func New(root string) *Store {
+	dir := filepath.Join(root, "secrets")
+	return &Store{dir: dir, keyFile: filepath.Join(dir, "key")}
+}
+
+// Vault returns the store holding sealed profile credentials. It lives under
+// <root>/secrets/vault and shares the API key store's key file.
+func (s *Store) Vault() *Store {
+	return &Store{dir: filepath.Join(s.dir, "vault"), keyFile: s.keyFile}
+}
+
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// DefaultKeyFile is where `profilex secret keygen` writes its key.
+func (s *Store) DefaultKeyFile() string {
+	return s.keyFile
+}
+
+func (s *Store) path(tool store.Tool, profile string) string {
+	return filepath.Join(s.dir, string(tool), profile+".json")
+}
+
+// ResolveKey picks the key file from PROFILEX_KEY_FILE or the default
+// location, then PROFILEX_PASSPHRASE, then prompt (which may be nil).
+func (s *Store) ResolveKey(prompt PromptFunc, confirm bool) (Key, error) {
+	if path := strings.TrimSpace(os.Getenv(EnvKeyFile)); path != "" {
+		return Key{KeyFile: path}, nil
+	}
+	if _, err := os.Stat(s.DefaultKeyFile()); err == nil {
+		return Key{KeyFile: s.DefaultKeyFile()}, nil
+	}
+	if pass := os.Getenv(EnvPassphrase); pass != "" {
+		return Key{Passphrase: []byte(pass)}, nil
+	}
+	if prompt == nil {
+		return Key{}, ErrNoKey
+	}
+	pass, err := prompt(confirm)
+	if err != nil {
+		return Key{}, err
+	}
+	if len(pass) == 0 {
+		return Key{}, errors.New("empty passphrase")
+	}
+	return Key{Passphrase: pass}, nil
+}
+
+// UnlockKey resolves the key needed to decrypt an existing secret, based on
+// how that secret was encrypted.
+func (s *Store) UnlockKey(tool store.Tool, profile string, prompt PromptFunc) (Key, error) {
+	env, err := s.readEnvelope(tool, profile)
+	if err != nil {
+		return Key{}, err
+	}
+	if env.KDF == kdfKeyFile {
+		if path := strings.TrimSpace(os.Getenv(EnvKeyFile)); path != "" {
+			return Key{KeyFile: path}, nil
+		}
+		return Key{KeyFile: s.DefaultKeyFile()}, nil
+	}
+	if pass := os.Getenv(EnvPassphrase); pass != "" {
+		return Key{Passphrase: []byte(pass)}, nil
+	}
+	if prompt == nil {
+		return Key{}, ErrNoKey
+	}
+	pass, err := prompt(false)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{Passphrase: pass}, nil
+}
+
+// GenerateKeyFile writes 32 random bytes, hex-encoded, to path with 0600
+// permissions. It refuses to overwrite an existing key.
+func GenerateKeyFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("key file already exists: %s", path)
+		}
+		return err
+	}
+	if _, err := f.WriteString(hex.EncodeToString(raw) + "\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *Store) Has(tool store.Tool, profile string) bool {
+	_, err := os.Stat(s.path(tool, profile))
+	return err == nil
+}
+
+// Set encrypts value and stores it for tool/profile.
+func (s *Store) Set(tool store.Tool, profile, value string, key Key) error {
+	env := envelope{Version: 1}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	env.Salt = hex.EncodeToString(salt)
+	if key.KeyFile != "" {
+		env.KDF = kdfKeyFile
+	} else {
+		env.KDF = kdfPBKDF2
+		env.Iterations = pbkdf2Iterations
+	}
+	aead, err := newAEAD(env, key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	env.Nonce = hex.EncodeToString(nonce)
+	env.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonce, []byte(value), additionalData(tool)))
+
+	b, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := s.path(tool, profile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get decrypts the secret for tool/profile. ok is false when none is stored.
+func (s *Store) Get(tool store.Tool, profile string, key Key) (string, bool, error) {
+	env, err := s.readEnvelope(tool, profile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	aead, err := newAEAD(env, key)
+	if err != nil {
+		return "", false, err
+	}
+	nonce, err := hex.DecodeString(env.Nonce)
+	if err != nil {
+		return "", false, err
+	}
+	ct, err := hex.DecodeString(env.Ciphertext)
+	if err != nil {
+		return "", false, err
+	}
+	plain, err := aead.Open(nil, nonce, ct, additionalData(tool))
+	if err != nil {
+		return "", false, fmt.Errorf("decrypt secret for %s/%s: wrong passphrase or key file", tool, profile)
+	}
+	return string(plain), true, nil
+}
+
+func (s *Store) Delete(tool store.Tool, profile string) error {
+	if err := os.Remove(s.path(tool, profile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Rename moves a secret along with its profile.
+func (s *Store) Rename(tool store.Tool, oldName, newName string) error {
+	if !s.Has(tool, oldName) {
+		return nil
+	}
+	return os.Rename(s.path(tool, oldName), s.path(tool, newName))
+}
+
+// List returns stored secrets without decrypting them.
+func (s *Store) List() ([]Entry, error) {
+	out := []Entry{}
+	for _, tool := range store.SupportedTools {
+		matches, err := filepath.Glob(filepath.Join(s.dir, string(tool), "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			var env envelope
+			if b, err := os.ReadFile(m); err == nil {
+				_ = json.Unmarshal(b, &env)
+			}
+			out = append(out, Entry{Tool: tool, Profile: strings.TrimSuffix(filepath.Base(m), ".json"), KDF: env.KDF})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Tool == out[j].Tool {
+			return out[i].Profile < out[j].Profile
+		}
+		return out[i].Tool < out[j].Tool
+	})
+	return out, nil
+}
+
+func (s *Store) readEnvelope(tool store.Tool, profile string) (envelope, error) {
+	var env envelope
+	b, err := os.ReadFile(s.path(tool, profile))
+	if err != nil {
+		return env, err
+	}
+	if err := json.Unmarshal(b, &env); err != nil {
+		return env, fmt.Errorf("read secret for %s/%s: %w", tool, profile, err)
+	}
+	return env, nil
+}
+
+func newAEAD(env envelope, key Key) (cipher.AEAD, error) {
+	salt, err := hex.DecodeString(env.Salt)
+	if err != nil {
+		return nil, err
+	}
+	var derived []byte
+	switch env.KDF {
+	case kdfKeyFile:
+		if key.KeyFile == "" {
+			return nil, errors.New("secret was encrypted with a key file; set " + EnvKeyFile)
+		}
+		raw, err := os.ReadFile(key.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read key file: %w", err)
+		}
+		material := strings.TrimSpace(string(raw))
+		if len(material) < 32 {
+			return nil, fmt.Errorf("key file %s is too short", key.KeyFile)
+		}
+		sum := sha256.Sum256(append(salt, material...))
+		derived = sum[:]
+	case kdfPBKDF2:
+		if len(key.Passphrase) == 0 {
+			return nil, errors.New("secret was encrypted with a passphrase; set " + EnvPassphrase)
+		}
+		derived, err = pbkdf2.Key(sha256.New, string(key.Passphrase), salt, env.Iterations, 32)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported secret kdf %q", env.KDF)
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds ciphertexts to their tool so a Claude key cannot be
+// swapped into a Codex slot. Profile names are left out so renames are a
+// plain file move.
+func additionalData(tool store.Tool) []byte {
+	return []byte("profilex:" + string(tool))
+}
@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestPassphraseRoundTrip(t *testing.T) {
+	pbkdf2Iterations = 1000
+	s := New(t.TempDir())
+	key := Key{Passphrase: []byte("correct horse")}
+	if err := s.Set(store.ToolClaude, "work", "sk-ant-secret", key); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(s.Dir(), "claude", "work.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "sk-ant-secret") {
+		t.Fatalf("secret stored in plaintext: %s", raw)
+	}
+
+	got, ok, err := s.Get(store.ToolClaude, "work", key)
+	if err != nil || !ok || got != "sk-ant-secret" {
+		t.Fatalf("unexpected round trip: %q ok=%v err=%v", got, ok, err)
+	}
+	if _, _, err := s.Get(store.ToolClaude, "work", Key{Passphrase: []byte("wrong")}); err == nil {
+		t.Fatalf("wrong passphrase should fail")
+	}
+	if _, ok, err := s.Get(store.ToolCodex, "work", key); err != nil || ok {
+		t.Fatalf("missing secret should report ok=false, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestKeyFileRoundTripAndRename(t *testing.T) {
+	s := New(t.TempDir())
+	if err := GenerateKeyFile(s.DefaultKeyFile()); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateKeyFile(s.DefaultKeyFile()); err == nil {
+		t.Fatalf("keygen must not overwrite an existing key")
+	}
+	t.Setenv(EnvKeyFile, "")
+	t.Setenv(EnvPassphrase, "")
+
+	key, err := s.ResolveKey(nil, true)
+	if err != nil || key.KeyFile != s.DefaultKeyFile() {
+		t.Fatalf("expected default key file, got %+v err=%v", key, err)
+	}
+	if err := s.Set(store.ToolCodex, "ci", "sk-openai", key); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rename(store.ToolCodex, "ci", "build"); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := s.UnlockKey(store.ToolCodex, "build", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := s.Get(store.ToolCodex, "build", unlock)
+	if err != nil || !ok || got != "sk-openai" {
+		t.Fatalf("unexpected value after rename: %q ok=%v err=%v", got, ok, err)
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Profile != "build" || entries[0].KDF != kdfKeyFile {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+}
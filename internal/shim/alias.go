@@ -0,0 +1,93 @@
+package shim
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// Target is one shim file: a profile launched under a name.
+type Target struct {
+	Name    string
+	Profile store.Profile
+	// Alias is set for names that come from an alias or a tool's name
+	// template rather than the <tool>-<profile> default.
+	Alias bool
+}
+
+// Targets lists every shim the state asks for: each profile under its
+// default name, then under its tool's name template, then aliases. A name
+// already taken by an earlier target is skipped.
+func Targets(st *store.State) []Target {
+	out := []Target{}
+	taken := map[string]bool{}
+	add := func(t Target) {
+		if !taken[t.Name] {
+			taken[t.Name] = true
+			out = append(out, t)
+		}
+	}
+	for _, p := range st.Profiles {
+		add(Target{Name: Name(p.Tool, p.Name), Profile: p})
+	}
+	for _, p := range st.Profiles {
+		if tmpl := st.ShimNameTemplates[p.Tool]; tmpl != "" {
+			add(Target{Name: store.ExpandShimNameTemplate(tmpl, p.Tool, p.Name), Profile: p, Alias: true})
+		}
+	}
+	for _, a := range st.Aliases {
+		if _, p := store.FindProfile(st, a.Tool, a.Profile); p != nil {
+			add(Target{Name: a.Name, Profile: *p, Alias: true})
+		}
+	}
+	return out
+}
+
+// TargetsFor returns the targets that launch profile.
+func TargetsFor(st *store.State, profile store.Profile) []Target {
+	out := []Target{}
+	for _, t := range Targets(st) {
+		if t.Profile.Tool == profile.Tool && t.Profile.Name == profile.Name {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// NameOwner returns the target already using name, if any.
+func NameOwner(st *store.State, name string) (Target, bool) {
+	for _, t := range Targets(st) {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Target{}, false
+}
+
+// PathCollision returns the executable name already resolves to on PATH
+// outside shimDir. A shim there would shadow it, or be shadowed by it,
+// depending on PATH order.
+func PathCollision(shimDir, name string) (string, bool) {
+	found, err := exec.LookPath(name)
+	if err != nil {
+		return "", false
+	}
+	if abs, err := filepath.Abs(found); err == nil {
+		found = abs
+	}
+	if sameDir(filepath.Dir(found), shimDir) {
+		return "", false
+	}
+	return found, true
+}
+
+func sameDir(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
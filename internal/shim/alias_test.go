@@ -0,0 +1,71 @@
+package shim
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestAliasTargetsAreCheckedAndRemovedLikeDefaultShims(t *testing.T) {
+	dir := t.TempDir()
+	st := &store.State{
+		Profiles: []store.Profile{
+			{Tool: store.ToolClaude, Name: "work"},
+			{Tool: store.ToolCodex, Name: "main"},
+		},
+		ShimNameTemplates: map[store.Tool]string{store.ToolClaude: "cc-{profile}"},
+		Aliases: []store.Alias{
+			{Name: "cx", Tool: store.ToolCodex, Profile: "main"},
+			{Name: "cc-work", Tool: store.ToolCodex, Profile: "main"},
+		},
+	}
+	names := []string{}
+	for _, tg := range Targets(st) {
+		names = append(names, tg.Name)
+	}
+	if got := strings.Join(names, " "); got != "claude-work codex-main cc-work cx" {
+		t.Fatalf("unexpected targets %v", names)
+	}
+
+	if _, err := Install(dir, st.Profiles[0], "profilex"); err != nil {
+		t.Fatal(err)
+	}
+	gone := Target{Name: "old-alias", Profile: st.Profiles[0], Alias: true}
+	if _, _, err := Ensure(dir, gone, "profilex"); err != nil {
+		t.Fatal(err)
+	}
+	// Mentions profilex but has no header, so it is not ours to remove.
+	notes := Path(dir, "notes")
+	if err := os.WriteFile(notes, []byte("#!/bin/sh\n# generated by profilex, then edited by hand\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	fixed, err := Repair(dir, Targets(st), "profilex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := map[string]State{}
+	for _, s := range fixed {
+		states[s.Name] = s.State
+	}
+	// cc-work follows the installed claude-work shim; cx waits for codex-main.
+	if len(fixed) != 2 || states["cc-work"] != StateMissing || states["old-alias"] != StateOrphaned {
+		t.Fatalf("expected cc-work created and old-alias removed, got %+v", fixed)
+	}
+	if _, err := os.Stat(Path(dir, "cx")); !os.IsNotExist(err) {
+		t.Fatalf("cx should not be created before codex-main's shim")
+	}
+
+	removed, err := RemoveAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Fatalf("expected claude-work and cc-work removed, got %v", removed)
+	}
+	if _, err := os.Stat(notes); err != nil {
+		t.Fatalf("unmanaged file should remain: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package shim
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// compiledDirName is where compiled environments live under the state root.
+const compiledDirName = "shims"
+
+// CompiledPath returns the sidecar file holding the compiled environment of
+// profile's shim. Shims look for it under PROFILEX_HOME or ~/.profilex, the
+// same root `profilex shim env` reads.
+func CompiledPath(root string, profile store.Profile) string {
+	return filepath.Join(root, compiledDirName, Name(profile.Tool, profile.Name)+".env")
+}
+
+// WriteCompiled stores env for profile's shim, stamped with the state
+// generation it was resolved from. Shims use it only while the generation
+// file still holds that number. The format matches `profilex shim env`
+// output after a first line with the generation.
+func WriteCompiled(root string, profile store.Profile, generation uint64, env []adapters.EnvAssignment) error {
+	dir := filepath.Join(root, compiledDirName)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\n", generation)
+	for _, e := range env {
+		if strings.ContainsAny(e.Value, "\r\n") {
+			return fmt.Errorf("%s contains a line break", e.Name)
+		}
+		b.WriteString(e.String())
+		b.WriteByte('\n')
+	}
+
+	tmp, err := os.CreateTemp(dir, ".compiled-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), CompiledPath(root, profile))
+}
+
+// RemoveCompiled deletes profile's compiled environment, if any.
+func RemoveCompiled(root string, profile store.Profile) error {
+	if err := os.Remove(CompiledPath(root, profile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ClearCompiled deletes every compiled environment under root.
+func ClearCompiled(root string) error {
+	return os.RemoveAll(filepath.Join(root, compiledDirName))
+}
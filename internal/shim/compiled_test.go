@@ -0,0 +1,77 @@
+package shim
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestBashShimUsesCompiledEnvUntilGenerationChanges(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash shim only")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	root := filepath.Join(dir, "home")
+	fakeProfilex := filepath.Join(dir, "fake-profilex")
+	log := filepath.Join(dir, "profilex.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\nprintf 'CLAUDE_CONFIG_DIR=/tmp/dynamic\\n'\n"
+	if err := os.WriteFile(fakeProfilex, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "claude"), []byte("#!/bin/sh\necho \"key=${ANTHROPIC_API_KEY-unset} dir=$CLAUDE_CONFIG_DIR\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p := store.Profile{Tool: store.ToolClaude, Name: "work"}
+	path, err := Install(dir, p, fakeProfilex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := []adapters.EnvAssignment{{Name: "ANTHROPIC_API_KEY"}, {Name: "CLAUDE_CONFIG_DIR", Value: "/tmp/compiled"}}
+	if err := WriteCompiled(root, p, 7, env); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(generation string) string {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, store.GenerationFileName), []byte(generation+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(path)
+		cmd.Env = append(os.Environ(),
+			"PATH="+dir+string(os.PathListSeparator)+os.Getenv("PATH"),
+			"PROFILEX_HOME="+root,
+			"ANTHROPIC_API_KEY=sk-other-account",
+		)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out)
+	}
+
+	if out := run("7"); !strings.Contains(out, "key=unset dir=/tmp/compiled") {
+		t.Fatalf("expected compiled env while generation matches, got %q", out)
+	}
+	if _, err := os.Stat(log); !os.IsNotExist(err) {
+		t.Fatalf("compiled launch should not run profilex")
+	}
+	if out := run("8"); !strings.Contains(out, "dir=/tmp/dynamic") {
+		t.Fatalf("expected dynamic env after generation changed, got %q", out)
+	}
+	calls, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(calls)); got != "shim env --compile claude work" {
+		t.Fatalf("unexpected profilex call %q", got)
+	}
+}
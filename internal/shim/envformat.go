@@ -0,0 +1,72 @@
+package shim
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+)
+
+// EnvFormats lists the formats FormatEnv accepts.
+var EnvFormats = []string{"plain", "bash", "zsh", "fish", "powershell", "dotenv"}
+
+// FormatEnv renders env for format. An assignment with an empty value
+// unsets an inherited variable, matching `profilex shim env`; dotenv has no
+// unset, so it writes an empty value instead.
+func FormatEnv(format string, env []adapters.EnvAssignment) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "plain":
+		format = "plain"
+	case "dotenv", "env":
+		format = "dotenv"
+	case "sh":
+		format = "bash"
+	default:
+		shell, err := NormalizeShell(format)
+		if err != nil {
+			return "", fmt.Errorf("unsupported format %q (expected %s)", format, strings.Join(EnvFormats, ", "))
+		}
+		format = shell
+	}
+
+	var b strings.Builder
+	for _, e := range env {
+		unset := e.Value == ""
+		switch format {
+		case "plain":
+			b.WriteString(e.String())
+		case "bash", "zsh":
+			if unset {
+				fmt.Fprintf(&b, "unset %s", e.Name)
+			} else {
+				fmt.Fprintf(&b, "export %s=%s", e.Name, shellQuote(e.Value))
+			}
+		case "fish":
+			if unset {
+				fmt.Fprintf(&b, "set -e %s", e.Name)
+			} else {
+				fmt.Fprintf(&b, "set -gx %s %s", e.Name, fishQuote(e.Value))
+			}
+		case "powershell":
+			if unset {
+				fmt.Fprintf(&b, "Remove-Item Env:%s -ErrorAction SilentlyContinue", e.Name)
+			} else {
+				fmt.Fprintf(&b, "$env:%s = %s", e.Name, psQuote(e.Value))
+			}
+		case "dotenv":
+			fmt.Fprintf(&b, "%s=%s", e.Name, dotenvQuote(e.Value))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// dotenvQuote leaves simple values bare and double-quotes the rest, which
+// dotenv loaders unescape.
+func dotenvQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\r\n\"'`$#\\=") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, `$`, `\$`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,34 @@
+package shim
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+)
+
+func TestFormatEnvQuotesForEachFormat(t *testing.T) {
+	env := []adapters.EnvAssignment{
+		{Name: "ANTHROPIC_API_KEY"},
+		{Name: "CLAUDE_CONFIG_DIR", Value: "/home/o'neil/my profile"},
+	}
+	cases := map[string]string{
+		"":       "ANTHROPIC_API_KEY=\nCLAUDE_CONFIG_DIR=/home/o'neil/my profile\n",
+		"bash":   "unset ANTHROPIC_API_KEY\nexport CLAUDE_CONFIG_DIR='/home/o'\\''neil/my profile'\n",
+		"fish":   "set -e ANTHROPIC_API_KEY\nset -gx CLAUDE_CONFIG_DIR '/home/o\\'neil/my profile'\n",
+		"pwsh":   "Remove-Item Env:ANTHROPIC_API_KEY -ErrorAction SilentlyContinue\n$env:CLAUDE_CONFIG_DIR = '/home/o''neil/my profile'\n",
+		"dotenv": "ANTHROPIC_API_KEY=\nCLAUDE_CONFIG_DIR=\"/home/o'neil/my profile\"\n",
+	}
+	for format, want := range cases {
+		got, err := FormatEnv(format, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("%q: expected %q, got %q", format, want, got)
+		}
+	}
+	if _, err := FormatEnv("tcsh", env); err == nil || !strings.Contains(err.Error(), "dotenv") {
+		t.Fatalf("expected unsupported format error, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package shim
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/adapters"
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// Shells lists the shells ShellInit can generate integration for.
+var Shells = []string{"bash", "zsh", "fish", "powershell"}
+
+// InitOptions tunes ShellInit output.
+type InitOptions struct {
+	// Prompt adds a hook that prefixes the prompt with the active
+	// PROFILEX_TOOL/PROFILEX_PROFILE.
+	Prompt bool
+}
+
+// NormalizeShell maps shell names and common aliases to an entry of Shells.
+func NormalizeShell(shell string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(shell)) {
+	case "bash":
+		return "bash", nil
+	case "zsh":
+		return "zsh", nil
+	case "fish":
+		return "fish", nil
+	case "powershell", "pwsh":
+		return "powershell", nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q (expected %s)", shell, strings.Join(Shells, ", "))
+	}
+}
+
+// ShellInit returns a script that defines a <tool>-<profile> function for
+// every profile, as an alternative to file shims. Like the shims, the
+// functions read the profile environment from `profilex shim env` on each
+// call, so pins and isolation changes apply without re-running shell-init.
+// Profiles whose tool has no registered adapter are skipped.
+func ShellInit(shell string, profiles []store.Profile, profilexBin string, opts InitOptions) (string, error) {
+	shell, err := NormalizeShell(shell)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s shell-init %s\n", marker, shell)
+	switch shell {
+	case "bash", "zsh":
+		writePosixInit(&b, shell, profiles, profilexBin, opts)
+	case "fish":
+		writeFishInit(&b, profiles, profilexBin, opts)
+	case "powershell":
+		writePowerShellInit(&b, profiles, profilexBin, opts)
+	}
+	return b.String(), nil
+}
+
+func writePosixInit(b *strings.Builder, shell string, profiles []store.Profile, profilexBin string, opts InitOptions) {
+	fmt.Fprintf(b, `__profilex_exec() {
+  local bin="$1" tool="$2" profile="$3" env_lines
+  shift 3
+  env_lines="$(%s shim env "$tool" "$profile")" || return $?
+  (
+    export PROFILEX_TOOL_BIN="$bin"
+    while IFS= read -r line; do
+      case "$line" in
+        *=) unset "${line%%=}" ;;
+        *) export "$line" ;;
+      esac
+    done <<< "$env_lines"
+    exec bash -c %s profilex "$@"
+  )
+}
+`, shellQuote(profilexBin), shellQuote(terminalCommand))
+	if shell == "bash" {
+		// bash-completion loads specs lazily, so ask it to load the wrapped
+		// tool's spec before copying it.
+		b.WriteString(`__profilex_complete_as() {
+  local spec
+  if ! spec="$(complete -p "$2" 2>/dev/null)"; then
+    declare -F _completion_loader >/dev/null 2>&1 && _completion_loader "$2" >/dev/null 2>&1
+    spec="$(complete -p "$2" 2>/dev/null)" || return 0
+  fi
+  eval "${spec% *} $1"
+}
+`)
+	}
+	for _, p := range profiles {
+		adapter, err := adapters.Get(p.Tool)
+		if err != nil {
+			continue
+		}
+		name := Name(p.Tool, p.Name)
+		if p.Vault {
+			fmt.Fprintf(b, "%s() { %s run %s %s -- \"$@\"; }\n", name, shellQuote(profilexBin), p.Tool, shellQuote(p.Name))
+		} else {
+			fmt.Fprintf(b, "%s() { __profilex_exec %s %s %s \"$@\"; }\n", name, shellQuote(adapter.Binary()), p.Tool, shellQuote(p.Name))
+		}
+		if shell == "bash" {
+			fmt.Fprintf(b, "__profilex_complete_as %s %s\n", name, shellQuote(adapter.Binary()))
+		} else {
+			fmt.Fprintf(b, "(( $+functions[compdef] )) && compdef %s=%s\n", name, shellQuote(adapter.Binary()))
+		}
+	}
+	if opts.Prompt {
+		b.WriteString(PromptHook(shell))
+	}
+}
+
+func writeFishInit(b *strings.Builder, profiles []store.Profile, profilexBin string, opts InitOptions) {
+	// fish has no subshells, so the environment is applied through env(1)
+	// rather than exported into the interactive shell. Both shells hand the
+	// launch to bash so terminal integrations run as in the bash shim.
+	fmt.Fprintf(b, `function __profilex_exec
+    set -l bin $argv[1]
+    set -l env_lines (%s shim env $argv[2] $argv[3]); or return
+    set -l env_args
+    for line in $env_lines
+        set -l kv (string split -m 1 = -- $line)
+        if test -z "$kv[2]"
+            set -a env_args -u $kv[1]
+        else if test "$kv[1]" = PROFILEX_TOOL_BIN
+            set bin $kv[2]
+        else
+            set -a env_args $line
+        end
+    end
+    env $env_args PROFILEX_TOOL_BIN=$bin bash -c %s profilex $argv[4..-1]
+end
+`, fishQuote(profilexBin), fishQuote(terminalCommand))
+	for _, p := range profiles {
+		adapter, err := adapters.Get(p.Tool)
+		if err != nil {
+			continue
+		}
+		name := Name(p.Tool, p.Name)
+		if p.Vault {
+			fmt.Fprintf(b, "function %s; %s run %s %s -- $argv; end\n", name, fishQuote(profilexBin), p.Tool, fishQuote(p.Name))
+		} else {
+			fmt.Fprintf(b, "function %s; __profilex_exec %s %s %s $argv; end\n", name, fishQuote(adapter.Binary()), p.Tool, fishQuote(p.Name))
+		}
+		fmt.Fprintf(b, "complete -c %s -w %s\n", name, fishQuote(adapter.Binary()))
+	}
+	if opts.Prompt {
+		b.WriteString(PromptHook("fish"))
+	}
+}
+
+// writePowerShellInit applies the profile environment around the call and
+// restores the previous values afterwards. PowerShell cannot reuse another
+// command's argument completer, so wrappers only complete by name.
+func writePowerShellInit(b *strings.Builder, profiles []store.Profile, profilexBin string, opts InitOptions) {
+	fmt.Fprintf(b, `function global:__profilex_exec {
+  $rest = @($args | Select-Object -Skip 3)
+  $lines = @('PROFILEX_TOOL_BIN=' + $args[0]) + @(& %s shim env $args[1] $args[2])
+  if ($LASTEXITCODE -ne 0) { return }
+  $saved = @{}
+  try {
+    foreach ($line in $lines) {
+      $i = $line.IndexOf('=')
+      if ($i -lt 1) { continue }
+      $key = $line.Substring(0, $i)
+      $value = $line.Substring($i + 1)
+      if (-not $saved.ContainsKey($key)) { $saved[$key] = [Environment]::GetEnvironmentVariable($key) }
+      if ($value -eq '') { Remove-Item "Env:$key" -ErrorAction SilentlyContinue } else { Set-Item "Env:$key" $value }
+    }
+    & $env:PROFILEX_TOOL_BIN @rest
+  } finally {
+    foreach ($key in $saved.Keys) {
+      if ($null -eq $saved[$key]) { Remove-Item "Env:$key" -ErrorAction SilentlyContinue } else { Set-Item "Env:$key" $saved[$key] }
+    }
+  }
+}
+`, psQuote(profilexBin))
+	for _, p := range profiles {
+		adapter, err := adapters.Get(p.Tool)
+		if err != nil {
+			continue
+		}
+		name := Name(p.Tool, p.Name)
+		if p.Vault {
+			fmt.Fprintf(b, "function global:%s { & %s run %s %s -- @args }\n", name, psQuote(profilexBin), p.Tool, psQuote(p.Name))
+		} else {
+			fmt.Fprintf(b, "function global:%s { __profilex_exec %s %s %s @args }\n", name, psQuote(adapter.Binary()), p.Tool, psQuote(p.Name))
+		}
+	}
+	if opts.Prompt {
+		b.WriteString(PromptHook("powershell"))
+	}
+}
+
+// PromptHook returns a snippet that prefixes the prompt with the active
+// PROFILEX_TOOL/PROFILEX_PROFILE whenever PROFILEX_PROFILE is set. Sourcing it
+// twice is harmless.
+func PromptHook(shell string) string {
+	switch shell {
+	case "bash", "zsh":
+		promptVar, setup := "PS1", ""
+		if shell == "zsh" {
+			promptVar, setup = "PROMPT", "setopt PROMPT_SUBST\n"
+		}
+		return setup + fmt.Sprintf(`__profilex_prompt() {
+  [ -n "${PROFILEX_PROFILE:-}" ] && printf '(%%s/%%s) ' "${PROFILEX_TOOL:-}" "$PROFILEX_PROFILE"
+}
+case "$%[1]s" in
+  *'$(__profilex_prompt)'*) ;;
+  *) %[1]s='$(__profilex_prompt)'"$%[1]s" ;;
+esac
+`, promptVar)
+	case "fish":
+		return `function __profilex_prompt
+    set -q PROFILEX_PROFILE; and printf '(%s/%s) ' "$PROFILEX_TOOL" "$PROFILEX_PROFILE"
+end
+if functions -q fish_prompt; and not functions -q __profilex_orig_prompt
+    functions -c fish_prompt __profilex_orig_prompt
+    function fish_prompt
+        __profilex_prompt
+        __profilex_orig_prompt
+    end
+end
+`
+	case "powershell":
+		return `if (-not (Test-Path Function:\__profilex_orig_prompt)) {
+  $function:global:__profilex_orig_prompt = $function:prompt
+  function global:prompt {
+    $prefix = if ($env:PROFILEX_PROFILE) { "($env:PROFILEX_TOOL/$env:PROFILEX_PROFILE) " } else { '' }
+    $prefix + (__profilex_orig_prompt)
+  }
+}
+`
+	default:
+		return ""
+	}
+}
+
+func fishQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(s) + "'"
+}
+
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,83 @@
+package shim
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestShellInitBashFunctionsLoadProfileEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash functions only")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	fakeProfilex := filepath.Join(dir, "fake-profilex")
+	if err := os.WriteFile(fakeProfilex, []byte("#!/bin/sh\nprintf 'ANTHROPIC_API_KEY=\\nCLAUDE_CONFIG_DIR=/tmp/%s\\nPROFILEX_PROFILE=%s\\n' \"$4\" \"$4\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "claude"), []byte("#!/bin/sh\necho \"key=${ANTHROPIC_API_KEY-unset} dir=$CLAUDE_CONFIG_DIR args=$*\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	profiles := []store.Profile{{Tool: store.ToolClaude, Name: "work"}, {Tool: store.ToolCodex, Name: "team.v2"}}
+	script, err := ShellInit("bash", profiles, fakeProfilex, InitOptions{Prompt: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(script, "codex-team.v2() { __profilex_exec 'codex' codex 'team.v2' \"$@\"; }") {
+		t.Fatalf("expected quoted codex function, got:\n%s", script)
+	}
+
+	cmd := exec.Command("bash", "-c", script+"\nclaude-work --resume 'a b'\necho \"outer=${ANTHROPIC_API_KEY-unset} ${PROFILEX_PROFILE-none}\"\nPROFILEX_TOOL=claude PROFILEX_PROFILE=work; __profilex_prompt\n")
+	cmd.Env = append(os.Environ(), "PATH="+dir+string(os.PathListSeparator)+os.Getenv("PATH"), "ANTHROPIC_API_KEY=sk-other-account")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %v\n%s", err, out)
+	}
+	got := string(out)
+	if !strings.Contains(got, "key=unset dir=/tmp/work args=--resume a b\n") {
+		t.Fatalf("expected the function to run claude with the profile env, got %q", got)
+	}
+	if strings.Contains(got, "\033") {
+		t.Fatalf("escape sequences written to a pipe: %q", got)
+	}
+	if !strings.Contains(got, "outer=sk-other-account none\n") {
+		t.Fatalf("profile env leaked into the calling shell: %q", got)
+	}
+	if !strings.HasSuffix(got, "(claude/work) ") {
+		t.Fatalf("expected prompt prefix, got %q", got)
+	}
+}
+
+func TestShellInitQuotesForEachShell(t *testing.T) {
+	profiles := []store.Profile{{Tool: store.ToolClaude, Name: "work"}, {Tool: store.ToolCodex, Name: "team", Vault: true}}
+	cases := map[string][]string{
+		"zsh":  {"claude-work() { __profilex_exec 'claude' claude 'work' \"$@\"; }", "compdef claude-work='claude'"},
+		"fish": {"function claude-work; __profilex_exec 'claude' claude 'work' $argv; end", "complete -c claude-work -w 'claude'", "function codex-team; '/opt/it\\'s/profilex' run codex 'team' -- $argv; end"},
+		"pwsh": {"function global:claude-work { __profilex_exec 'claude' claude 'work' @args }", "function global:codex-team { & '/opt/it''s/profilex' run codex 'team' -- @args }"},
+	}
+	for shell, wants := range cases {
+		script, err := ShellInit(shell, profiles, "/opt/it's/profilex", InitOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(script, want) {
+				t.Fatalf("%s: expected %q in:\n%s", shell, want, script)
+			}
+		}
+		if strings.Contains(script, "__profilex_prompt") {
+			t.Fatalf("%s: prompt hook should be opt-in", shell)
+		}
+	}
+	if _, err := ShellInit("tcsh", profiles, "profilex", InitOptions{}); err == nil {
+		t.Fatalf("expected unsupported shell error")
+	}
+}
@@ -2,11 +2,13 @@ package shim
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/derekurban/profilex-cli/internal/adapters"
 	"github.com/derekurban/profilex-cli/internal/store"
 )
 
@@ -24,59 +26,262 @@ func Name(tool store.Tool, profile string) string {
 	return fmt.Sprintf("%s-%s", tool, profile)
 }
 
+// Install writes the shim for profile under its default name, replacing
+// whatever is at its path.
 func Install(shimDir string, profile store.Profile, profilexBin string) (string, error) {
+	path, _, err := write(shimDir, Target{Name: Name(profile.Tool, profile.Name), Profile: profile}, profilexBin, true)
+	return path, err
+}
+
+// Ensure writes the shim for target only when its content differs from what
+// Install would write, and reports whether it changed anything.
+func Ensure(shimDir string, target Target, profilexBin string) (string, bool, error) {
+	return write(shimDir, target, profilexBin, false)
+}
+
+func write(shimDir string, target Target, profilexBin string, force bool) (string, bool, error) {
 	if err := os.MkdirAll(shimDir, 0o755); err != nil {
-		return "", err
+		return "", false, err
+	}
+	shimPath, content, err := render(shimDir, target, profilexBin)
+	if err != nil {
+		return "", false, err
 	}
-	baseName := Name(profile.Tool, profile.Name)
-	shimPath := filepath.Join(shimDir, baseName)
-	var content string
 	if runtime.GOOS == "windows" {
 		// Remove legacy extensionless shim so PowerShell resolves the .cmd wrapper.
-		if err := os.Remove(shimPath); err != nil && !os.IsNotExist(err) {
-			return "", err
+		legacy := strings.TrimSuffix(shimPath, ".cmd")
+		if err := os.Remove(legacy); err != nil && !os.IsNotExist(err) {
+			return "", false, err
 		}
-		shimPath += ".cmd"
-		content = fmt.Sprintf(`@echo off
+	}
+	if !force {
+		if existing, err := os.ReadFile(shimPath); err == nil && string(existing) == content {
+			return shimPath, false, nil
+		}
+	}
+	if err := os.WriteFile(shimPath, []byte(content), 0o755); err != nil {
+		return "", false, err
+	}
+	return shimPath, true, nil
+}
+
+// Path returns where the shim called name lives in shimDir.
+func Path(shimDir, name string) string {
+	path := filepath.Join(shimDir, name)
+	if runtime.GOOS == "windows" {
+		path += ".cmd"
+	}
+	return path
+}
+
+// render returns the shim path and content, including a header line that
+// records the template version, a hash of the rest of the file and the
+// profilex binary it calls. Aliases get the same content as the default
+// shim, so they share its title and compiled environment.
+func render(shimDir string, target Target, profilexBin string) (string, string, error) {
+	profile := target.Profile
+	adapter, err := adapters.Get(profile.Tool)
+	if err != nil {
+		return "", "", err
+	}
+	binary := adapter.Binary()
+	baseName := Name(profile.Tool, profile.Name)
+	var content, comment string
+	if runtime.GOOS == "windows" {
+		content, comment = cmdShim(baseName, binary, profile, profilexBin), "REM "
+	} else {
+		content, comment = bashShim(baseName, binary, profile, profilexBin), "# "
+	}
+	markerLine := comment + marker + "\n"
+	header := comment + formatHeader(TemplateVersion, contentHash(content), profilexBin) + "\n"
+	content = strings.Replace(content, markerLine, markerLine+header, 1)
+	return Path(shimDir, target.Name), content, nil
+}
+
+// cmdShim and bashShim import the profile environment from `profilex shim
+// env`, which may set several variables, and launch the tool binary
+// directly (see bashTerminal for what bash does around it).
+// PROFILEX_TOOL_BIN defaults to the adapter's binary; `shim env` replaces it
+// with the profile's pinned binary, so pins apply without reinstalling.
+// Vault profiles instead launch through `profilex run` so credentials can be
+// unsealed before the tool starts and re-sealed after it exits.
+//
+// With compiled shims enabled, `shim env --compile` also leaves the
+// environment in a sidecar file (see WriteCompiled). Later launches read it
+// directly while its generation matches the state's, skipping both the
+// profilex process and the state lock, and fall back to `shim env` as soon
+// as anything is saved. PROFILEX_ENV_POLICY always takes the dynamic path,
+// since it changes what is stripped.
+func cmdShim(baseName, binary string, profile store.Profile, profilexBin string) string {
+	if profile.Vault {
+		return fmt.Sprintf(`@echo off
 REM %s
 setlocal
 title %s
+call %s run %s %s -- %%*
+exit /b %%ERRORLEVEL%%
+`, marker, baseName, cmdQuote(profilexBin), profile.Tool, cmdQuote(profile.Name))
+	}
+	return fmt.Sprintf(`@echo off
+REM %[1]s
+setlocal
+title %[2]s
+set "PROFILEX_TOOL_BIN=%[3]s"
+set "PROFILEX_ROOT=%%PROFILEX_HOME%%"
+if not defined PROFILEX_ROOT set "PROFILEX_ROOT=%%USERPROFILE%%\.profilex"
+set "PROFILEX_COMPILED=%%PROFILEX_ROOT%%\%[6]s\%[2]s.env"
+if defined PROFILEX_ENV_POLICY goto dynamic
+if not exist "%%PROFILEX_COMPILED%%" goto dynamic
+if not exist "%%PROFILEX_ROOT%%\%[7]s" goto dynamic
+set "PROFILEX_GEN="
+set "PROFILEX_COMPILED_GEN="
+set /p PROFILEX_GEN=<"%%PROFILEX_ROOT%%\%[7]s"
+set /p PROFILEX_COMPILED_GEN=<"%%PROFILEX_COMPILED%%"
+if not defined PROFILEX_GEN goto dynamic
+if not "%%PROFILEX_GEN%%"=="%%PROFILEX_COMPILED_GEN%%" goto dynamic
+for /f "usebackq skip=1 delims=" %%%%A in ("%%PROFILEX_COMPILED%%") do set "%%%%A"
+goto launch
+:dynamic
 set "PROFILEX_ENV_FILE=%%TEMP%%\profilex-env-%%RANDOM%%-%%RANDOM%%.tmp"
-%s shim env %s %s > "%%PROFILEX_ENV_FILE%%"
+%[4]s shim env --compile %[5]s %[8]s > "%%PROFILEX_ENV_FILE%%"
 if errorlevel 1 (
   if exist "%%PROFILEX_ENV_FILE%%" del /f /q "%%PROFILEX_ENV_FILE%%" >nul 2>&1
   exit /b %%ERRORLEVEL%%
 )
 for /f "usebackq delims=" %%%%A in ("%%PROFILEX_ENV_FILE%%") do set "%%%%A"
 del /f /q "%%PROFILEX_ENV_FILE%%" >nul 2>&1
-call %s %%*
+:launch
+call "%%PROFILEX_TOOL_BIN%%" %%*
 exit /b %%ERRORLEVEL%%
 `,
-			marker,
-			baseName,
-			cmdQuote(profilexBin),
-			profile.Tool,
-			cmdQuote(profile.Name),
-			profile.Tool,
-		)
-	} else {
-		content = fmt.Sprintf(`#!/usr/bin/env bash
+		marker,
+		baseName,
+		binary,
+		cmdQuote(profilexBin),
+		profile.Tool,
+		compiledDirName,
+		store.GenerationFileName,
+		cmdQuote(profile.Name),
+	)
+}
+
+func bashShim(baseName, binary string, profile store.Profile, profilexBin string) string {
+	if profile.Vault {
+		return fmt.Sprintf(`#!/usr/bin/env bash
 # %s
 set -euo pipefail
 # Set terminal/tab title to the active shim profile.
 printf '\033]0;%s\007'
-env_lines="$(%s shim env %s %s)"
-while IFS= read -r line; do
-  export "$line"
-done <<< "$env_lines"
-exec %s "$@"
-`, marker, baseName, shellQuote(profilexBin), profile.Tool, shellQuote(profile.Name), profile.Tool)
+exec %s run %s %s -- "$@"
+`, marker, baseName, shellQuote(profilexBin), profile.Tool, shellQuote(profile.Name))
 	}
-	if err := os.WriteFile(shimPath, []byte(content), 0o755); err != nil {
-		return "", err
-	}
-	return shimPath, nil
+	return fmt.Sprintf(`#!/usr/bin/env bash
+# %[1]s
+set -euo pipefail
+PROFILEX_TOOL_BIN=%[3]s
+label=%[9]s
+active=%[10]s
+apply_env() {
+  while IFS= read -r line; do
+    case "$line" in
+      *=) unset "${line%%=}" ;;
+      *) export "$line" ;;
+    esac
+  done
 }
+root="${PROFILEX_HOME:-$HOME/.profilex}"
+compiled="$root/%[6]s/%[2]s.env"
+generation=""
+compiled_generation=""
+if [ -z "${PROFILEX_ENV_POLICY:-}" ] && [ -r "$compiled" ] && [ -r "$root/%[7]s" ]; then
+  IFS= read -r generation < "$root/%[7]s" || true
+  {
+    IFS= read -r compiled_generation || true
+    if [ -n "$generation" ] && [ "$generation" = "$compiled_generation" ]; then
+      apply_env
+    fi
+  } < "$compiled"
+fi
+if [ -z "$generation" ] || [ "$generation" != "$compiled_generation" ]; then
+  env_lines="$(%[4]s shim env --compile %[5]s %[8]s)"
+  apply_env <<< "$env_lines"
+fi
+`, marker, baseName, shellQuote(binary), shellQuote(profilexBin), profile.Tool, compiledDirName, store.GenerationFileName, shellQuote(profile.Name),
+		shellQuote(baseName), shellQuote(string(profile.Tool)+"/"+profile.Name)) + bashTerminal
+}
+
+// bashTerminal runs the integrations listed in PROFILEX_TERM around the tool
+// and undoes them when it exits, so the shim no longer execs the tool unless
+// every integration is off. Escape sequences are only written to a
+// terminal; tmux is driven through its CLI. The title is pushed on and
+// popped off the xterm title stack, which most terminals support.
+const bashTerminal = `term=",${PROFILEX_TERM:-title},"
+if [ "$term" = ",none," ]; then
+  exec "$PROFILEX_TOOL_BIN" "$@"
+fi
+set +e
+color="${PROFILEX_COLOR:-}"
+tty=""
+[ -t 1 ] && tty=1
+has() { case "$term" in *",$1,"*) return 0 ;; esac; return 1; }
+in_term() { [ -n "$tty" ] && has "$1" && [ "${TERM_PROGRAM:-}" = "$2" ]; }
+user_var() { printf '\033]1337;SetUserVar=%s=%s\007' "$1" "$(printf '%s' "$2" | base64 | tr -d '\n')"; }
+tmux_name=""
+tmux_auto=""
+restore() {
+  if [ -n "$tmux_name" ]; then
+    tmux rename-window -- "$tmux_name" 2>/dev/null
+    case "$tmux_auto" in
+      "") tmux set-option -wqu automatic-rename 2>/dev/null ;;
+      on) tmux set-option -wq automatic-rename on 2>/dev/null ;;
+    esac
+    tmux set-option -pqu @profilex_profile 2>/dev/null
+    tmux set-option -pqu @profilex_color 2>/dev/null
+  fi
+  if in_term iterm iTerm.app; then
+    printf '\033]6;1;bg;*;default\007'
+    user_var profilex_profile ""
+  fi
+  if in_term wezterm WezTerm; then
+    user_var profilex_profile ""
+    user_var profilex_color ""
+  fi
+  if [ -n "$tty" ] && has title; then
+    printf '\033[23;0t'
+  fi
+}
+if [ -n "$tty" ] && has title; then
+  printf '\033[22;0t\033]0;%s\007' "$label"
+fi
+if has tmux && [ -n "${TMUX:-}" ]; then
+  tmux_name="$(tmux display-message -p '#{window_name}' 2>/dev/null)"
+  tmux_auto="$(tmux show-options -wv automatic-rename 2>/dev/null)"
+  tmux rename-window -- "$label" 2>/dev/null
+  tmux set-option -pq @profilex_profile "$active" 2>/dev/null
+  [ -n "$color" ] && tmux set-option -pq @profilex_color "$color" 2>/dev/null
+fi
+if in_term iterm iTerm.app; then
+  user_var profilex_profile "$active"
+  if [ -n "$color" ]; then
+    printf '\033]6;1;bg;red;brightness;%d\007\033]6;1;bg;green;brightness;%d\007\033]6;1;bg;blue;brightness;%d\007' \
+      "0x${color:1:2}" "0x${color:3:2}" "0x${color:5:2}"
+  fi
+fi
+if in_term wezterm WezTerm; then
+  user_var profilex_profile "$active"
+  user_var profilex_color "$color"
+fi
+trap restore EXIT
+"$PROFILEX_TOOL_BIN" "$@"
+exit $?
+`
+
+// terminalCommand is bashTerminal as a `bash -c` script for shell-init
+// wrappers, which run it with the profile environment applied; the label
+// and active profile come from the variables `shim env` sets.
+const terminalCommand = `label="${PROFILEX_SHIM_NAME:-$PROFILEX_TOOL-$PROFILEX_PROFILE}"
+active="$PROFILEX_TOOL/$PROFILEX_PROFILE"
+` + bashTerminal
 
 func Remove(shimDir string, profile store.Profile) error {
 	base := filepath.Join(shimDir, Name(profile.Tool, profile.Name))
@@ -92,6 +297,19 @@ func Remove(shimDir string, profile store.Profile) error {
 	return nil
 }
 
+// RemoveManaged removes the shim called name if profilex wrote it, and
+// reports whether it did.
+func RemoveManaged(shimDir, name string) (bool, error) {
+	path := Path(shimDir, name)
+	if !isManaged(path) {
+		return false, nil
+	}
+	if err := os.Remove(path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func RemoveAll(shimDir string) ([]string, error) {
 	entries, err := os.ReadDir(shimDir)
 	if err != nil {
@@ -105,16 +323,8 @@ func RemoveAll(shimDir string) ([]string, error) {
 		if e.IsDir() {
 			continue
 		}
-		name := e.Name()
-		if !(strings.HasPrefix(name, "claude-") || strings.HasPrefix(name, "codex-")) {
-			continue
-		}
-		path := filepath.Join(shimDir, name)
-		b, err := os.ReadFile(path)
-		if err != nil {
-			continue
-		}
-		if !strings.Contains(string(b), marker) {
+		path := filepath.Join(shimDir, e.Name())
+		if !isManaged(path) {
 			continue
 		}
 		if err := os.Remove(path); err == nil {
@@ -124,6 +334,35 @@ func RemoveAll(shimDir string) ([]string, error) {
 	return removed, nil
 }
 
+// isManaged reports whether path is a shim profilex wrote. Shims named
+// <tool>-<profile> only need the marker; any other name, such as an alias,
+// also needs the header line, so unrelated files that happen to mention
+// profilex are never touched. Only the start of the file is read, since
+// shim directories also hold large binaries.
+func isManaged(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	buf := make([]byte, 1024)
+	n, _ := io.ReadFull(f, buf)
+	head := string(buf[:n])
+	if !strings.Contains(head, marker) {
+		return false
+	}
+	return hasToolPrefix(filepath.Base(path)) || strings.Contains(head, headerPrefix)
+}
+
+func hasToolPrefix(name string) bool {
+	for _, tool := range store.SupportedTools {
+		if strings.HasPrefix(name, string(tool)+"-") {
+			return true
+		}
+	}
+	return false
+}
+
 func shellQuote(s string) string {
 	if s == "" {
 		return "''"
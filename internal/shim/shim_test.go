@@ -2,6 +2,7 @@ package shim
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -41,23 +42,23 @@ func TestInstallAndRemove(t *testing.T) {
 		if !strings.Contains(string(content), "title claude-work") {
 			t.Fatalf("windows shim should set terminal title to shim name")
 		}
-		if !strings.Contains(string(content), "shim env claude \"work\"") {
+		if !strings.Contains(string(content), "shim env --compile claude \"work\"") {
 			t.Fatalf("windows shim should load environment via profilex shim env")
 		}
 		if !strings.Contains(string(content), `for /f "usebackq delims=" %%A in ("%PROFILEX_ENV_FILE%") do set "%%A"`) {
 			t.Fatalf("windows shim should preserve double-percent escaping in FOR variable")
 		}
-		if !strings.Contains(string(content), "\ncall claude %*\n") {
+		if !strings.Contains(string(content), `set "PROFILEX_TOOL_BIN=claude"`) || !strings.Contains(string(content), "\ncall \"%PROFILEX_TOOL_BIN%\" %*\n") {
 			t.Fatalf("windows shim should launch claude through call for cmd-wrapper compatibility")
 		}
 	} else {
-		if !strings.Contains(string(content), "printf '\\033]0;claude-work\\007'") {
+		if !strings.Contains(string(content), "label='claude-work'\n") || !strings.Contains(string(content), `printf '\033[22;0t\033]0;%s\007' "$label"`) {
 			t.Fatalf("unix shim should set terminal title to shim name")
 		}
-		if !strings.Contains(string(content), "shim env claude 'work'") {
+		if !strings.Contains(string(content), "shim env --compile claude 'work'") {
 			t.Fatalf("unix shim should load environment via profilex shim env")
 		}
-		if !strings.Contains(string(content), "exec claude \"$@\"") {
+		if !strings.Contains(string(content), "PROFILEX_TOOL_BIN='claude'\n") || !strings.Contains(string(content), "exec \"$PROFILEX_TOOL_BIN\" \"$@\"") {
 			t.Fatalf("unix shim should launch claude directly")
 		}
 	}
@@ -91,3 +92,131 @@ func TestInstallRemovesLegacyExtensionlessShimOnWindows(t *testing.T) {
 		t.Fatalf("legacy extensionless shim should be removed")
 	}
 }
+
+func TestInstallVaultProfileLaunchesThroughRun(t *testing.T) {
+	dir := t.TempDir()
+	p := store.Profile{Tool: store.ToolCodex, Name: "work", Vault: true}
+	path, err := Install(dir, p, "profilex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "exec 'profilex' run codex 'work' -- \"$@\""
+	if runtime.GOOS == "windows" {
+		want = `call "profilex" run codex "work" -- %*`
+	}
+	if !strings.Contains(string(content), want) {
+		t.Fatalf("vault shim should launch through profilex run, got:\n%s", content)
+	}
+	if strings.Contains(string(content), "shim env") {
+		t.Fatalf("vault shim must not exec the tool directly")
+	}
+}
+
+func TestBashShimUnsetsStrippedVariables(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash shim only")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	fakeProfilex := filepath.Join(dir, "fake-profilex")
+	if err := os.WriteFile(fakeProfilex, []byte("#!/bin/sh\nprintf 'ANTHROPIC_API_KEY=\\nCLAUDE_CONFIG_DIR=/tmp/work\\n'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "claude"), []byte("#!/bin/sh\necho \"key=${ANTHROPIC_API_KEY-unset} dir=$CLAUDE_CONFIG_DIR\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path, err := Install(dir, store.Profile{Tool: store.ToolClaude, Name: "work"}, fakeProfilex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), "PATH="+dir+string(os.PathListSeparator)+os.Getenv("PATH"), "ANTHROPIC_API_KEY=sk-other-account")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "key=unset dir=/tmp/work") {
+		t.Fatalf("expected inherited key to be unset, got %q", out)
+	}
+}
+
+func TestBashShimExecsPinnedBinaryFromShimEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash shim only")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	pinned := filepath.Join(dir, "claude-1.0.0")
+	if err := os.WriteFile(pinned, []byte("#!/bin/sh\necho pinned \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	fakeProfilex := filepath.Join(dir, "fake-profilex")
+	if err := os.WriteFile(fakeProfilex, []byte("#!/bin/sh\nprintf 'PROFILEX_TOOL_BIN="+pinned+"\\n'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path, err := Install(dir, store.Profile{Tool: store.ToolClaude, Name: "old"}, fakeProfilex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(path, "--resume").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(out), "pinned --resume\n") {
+		t.Fatalf("expected shim to exec the pinned binary, got %q", out)
+	}
+}
+
+func TestBashShimRestoresTmuxWindowAfterTool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("bash shim only")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "tmux.log")
+	tmux := "#!/bin/sh\necho \"$*\" >> " + log + "\ncase \"$*\" in\n  display-message*) echo editor ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(dir, "tmux"), []byte(tmux), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "claude"), []byte("#!/bin/sh\necho ran >> "+log+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	fakeProfilex := filepath.Join(dir, "fake-profilex")
+	if err := os.WriteFile(fakeProfilex, []byte("#!/bin/sh\nprintf 'PROFILEX_TERM=tmux\\nPROFILEX_COLOR=#336699\\n'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path, err := Install(dir, store.Profile{Tool: store.ToolClaude, Name: "work"}, fakeProfilex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), "PATH="+dir+string(os.PathListSeparator)+os.Getenv("PATH"), "TMUX=/tmp/tmux-test,1,0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("shim failed: %v: %s", err, out)
+	}
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	for _, want := range []string{
+		"rename-window -- claude-work\nset-option -pq @profilex_profile claude/work\nset-option -pq @profilex_color #336699\nran\n",
+		"rename-window -- editor\nset-option -wqu automatic-rename\nset-option -pqu @profilex_profile\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected tmux calls to contain %q, got:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package shim
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// TemplateVersion is bumped whenever the shim templates change, so shims
+// written by an older profilex are reported as stale.
+const TemplateVersion = 3
+
+const headerPrefix = "profilex-shim v"
+
+// State classifies a shim against the profiles it should serve.
+type State string
+
+const (
+	StateOK State = "ok"
+	// StateMissing means a profile has no shim.
+	StateMissing State = "missing"
+	// StateStale means the shim differs from what Install would write.
+	StateStale State = "stale"
+	// StateForeign means a file without the profilex marker sits at a shim path.
+	StateForeign State = "foreign"
+	// StateOrphaned means a profilex shim has no matching profile.
+	StateOrphaned State = "orphaned"
+)
+
+// Status describes one shim in a shim directory.
+type Status struct {
+	Name    string     `json:"name"`
+	Path    string     `json:"path"`
+	Tool    store.Tool `json:"tool,omitempty"`
+	Profile string     `json:"profile,omitempty"`
+	State   State      `json:"state"`
+	Detail  string     `json:"detail,omitempty"`
+	// Repairable is set when Repair may fix the shim without losing
+	// anything: it is orphaned, or stale only because profilex or the
+	// profile changed. Hand-edited shims and shims pointing at another
+	// existing profilex binary are left alone.
+	Repairable bool `json:"repairable,omitempty"`
+	// Alias marks shims named by an alias or a tool's name template.
+	Alias bool `json:"alias,omitempty"`
+
+	target Target
+}
+
+type header struct {
+	version int
+	hash    string
+	bin     string
+}
+
+func formatHeader(version int, hash, bin string) string {
+	return fmt.Sprintf("%s%d sha256=%s bin=%s", headerPrefix, version, hash, bin)
+}
+
+// parseHeader finds the header line and returns it along with the content
+// the hash covers.
+func parseHeader(content string) (header, string, bool) {
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "# "), "REM "))
+		if !strings.HasPrefix(text, headerPrefix) {
+			continue
+		}
+		fields := strings.SplitN(strings.TrimPrefix(text, headerPrefix), " ", 3)
+		if len(fields) != 3 || !strings.HasPrefix(fields[1], "sha256=") || !strings.HasPrefix(fields[2], "bin=") {
+			return header{}, "", false
+		}
+		version, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return header{}, "", false
+		}
+		body := strings.Join(lines[:i], "") + strings.Join(lines[i+1:], "")
+		return header{
+			version: version,
+			hash:    strings.TrimPrefix(fields[1], "sha256="),
+			bin:     strings.TrimPrefix(fields[2], "bin="),
+		}, body, true
+	}
+	return header{}, "", false
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Check compares the shims in shimDir with targets (see Targets) when
+// calling profilexBin. Results are sorted by name.
+func Check(shimDir string, targets []Target, profilexBin string) ([]Status, error) {
+	out := []Status{}
+	expected := map[string]bool{}
+	for _, t := range targets {
+		path, want, err := render(shimDir, t, profilexBin)
+		if err != nil {
+			continue
+		}
+		expected[filepath.Base(path)] = true
+		st := Status{Name: t.Name, Path: path, Tool: t.Profile.Tool, Profile: t.Profile.Name, Alias: t.Alias, target: t}
+		b, err := os.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+			st.State = StateMissing
+		case err != nil:
+			st.State, st.Detail = StateMissing, err.Error()
+		case !strings.Contains(string(b), marker):
+			st.State, st.Detail = StateForeign, "not written by profilex; left untouched"
+		case string(b) == want:
+			st.State = StateOK
+		default:
+			st.State = StateStale
+			st.Detail, st.Repairable = staleReason(string(b), profilexBin)
+		}
+		out = append(out, st)
+	}
+
+	// An unreadable directory simply has no orphans to report.
+	entries, _ := os.ReadDir(shimDir)
+	for _, e := range entries {
+		if e.IsDir() || expected[e.Name()] {
+			continue
+		}
+		path := filepath.Join(shimDir, e.Name())
+		if !isManaged(path) {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), ".cmd")
+		st := Status{Name: name, Path: path, State: StateOrphaned, Detail: "no matching profile", Repairable: true}
+		if hasToolPrefix(name) {
+			st.Tool, st.Profile = splitName(name)
+		} else {
+			st.Alias, st.Detail = true, "no matching alias"
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out, nil
+}
+
+func staleReason(content, profilexBin string) (string, bool) {
+	h, body, ok := parseHeader(content)
+	if !ok {
+		return "written by an older profilex", true
+	}
+	if contentHash(body) != h.hash {
+		return "edited since it was generated; run `profilex shim install` to overwrite", false
+	}
+	if h.version != TemplateVersion {
+		return fmt.Sprintf("template v%d, current is v%d", h.version, TemplateVersion), true
+	}
+	if h.bin != profilexBin {
+		if _, err := os.Stat(h.bin); err != nil {
+			return fmt.Sprintf("calls %s, which no longer exists", h.bin), true
+		}
+		return fmt.Sprintf("calls another profilex at %s", h.bin), false
+	}
+	return "profile changed since it was generated", true
+}
+
+// Repair rewrites repairable stale shims and removes orphaned ones. Missing
+// shims are not created, since `shim uninstall` may have removed them on
+// purpose, except aliases of a profile whose default shim is installed:
+// those follow the default shim. It returns the shims it fixed.
+func Repair(shimDir string, targets []Target, profilexBin string) ([]Status, error) {
+	statuses, err := Check(shimDir, targets, profilexBin)
+	if err != nil {
+		return nil, err
+	}
+	installed := map[string]bool{}
+	for _, st := range statuses {
+		if !st.Alias && st.State != StateMissing && st.State != StateOrphaned {
+			installed[Name(st.Tool, st.Profile)] = true
+		}
+	}
+	fixed := []Status{}
+	var errs []string
+	for _, st := range statuses {
+		if st.State == StateMissing && st.Alias && installed[Name(st.Tool, st.Profile)] {
+			st.Repairable = true
+		}
+		if !st.Repairable {
+			continue
+		}
+		switch st.State {
+		case StateStale, StateMissing:
+			_, _, err = write(shimDir, st.target, profilexBin, true)
+		case StateOrphaned:
+			err = os.Remove(st.Path)
+		default:
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", st.Name, err))
+			continue
+		}
+		fixed = append(fixed, st)
+	}
+	if len(errs) > 0 {
+		return fixed, fmt.Errorf("repair shims: %s", strings.Join(errs, "; "))
+	}
+	return fixed, nil
+}
+
+// splitName splits a shim name into tool and profile, preferring the longest
+// registered tool name.
+func splitName(name string) (store.Tool, string) {
+	var best store.Tool
+	for _, tool := range store.SupportedTools {
+		if strings.HasPrefix(name, string(tool)+"-") && len(tool) > len(best) {
+			best = tool
+		}
+	}
+	if best == "" {
+		return "", name
+	}
+	return best, strings.TrimPrefix(name, string(best)+"-")
+}
@@ -0,0 +1,106 @@
+package shim
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestCheckClassifiesDriftAndRepairFixesIt(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(t.TempDir(), "profilex")
+	if err := os.WriteFile(bin, []byte("bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	profiles := []store.Profile{
+		{Tool: store.ToolClaude, Name: "current"},
+		{Tool: store.ToolClaude, Name: "moved"},
+		{Tool: store.ToolClaude, Name: "edited"},
+		{Tool: store.ToolClaude, Name: "legacy"},
+		{Tool: store.ToolClaude, Name: "absent"},
+		{Tool: store.ToolCodex, Name: "mine"},
+	}
+	byName := func(name string) store.Profile {
+		for _, p := range profiles {
+			if Name(p.Tool, p.Name) == name {
+				return p
+			}
+		}
+		return store.Profile{Tool: store.ToolClaude, Name: strings.TrimPrefix(name, "claude-")}
+	}
+
+	if _, err := Install(dir, byName("claude-current"), bin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Install(dir, byName("claude-moved"), filepath.Join(t.TempDir(), "gone", "profilex")); err != nil {
+		t.Fatal(err)
+	}
+	edited, err := Install(dir, byName("claude-edited"), bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(edited, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("# my tweak\n")
+	_ = f.Close()
+	if err := os.WriteFile(Path(dir, "claude-legacy"), []byte("#!/usr/bin/env bash\n# generated by profilex\nexec claude \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(Path(dir, "codex-mine"), []byte("#!/bin/sh\necho hand-written\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Install(dir, byName("claude-deleted"), bin); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]State{
+		"claude-current": StateOK,
+		"claude-moved":   StateStale,
+		"claude-edited":  StateStale,
+		"claude-legacy":  StateStale,
+		"claude-absent":  StateMissing,
+		"codex-mine":     StateForeign,
+		"claude-deleted": StateOrphaned,
+	}
+	targets := Targets(&store.State{Profiles: profiles})
+	statuses, err := Check(dir, targets, bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("expected %d statuses, got %+v", len(want), statuses)
+	}
+	for _, s := range statuses {
+		if s.State != want[s.Name] {
+			t.Fatalf("%s: expected %s, got %s (%s)", s.Name, want[s.Name], s.State, s.Detail)
+		}
+	}
+
+	fixed, err := Repair(dir, targets, bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixed) != 3 {
+		t.Fatalf("expected moved, legacy and deleted to be repaired, got %+v", fixed)
+	}
+	want["claude-moved"], want["claude-legacy"] = StateOK, StateOK
+	delete(want, "claude-deleted")
+	statuses, _ = Check(dir, targets, bin)
+	for _, s := range statuses {
+		if s.State != want[s.Name] {
+			t.Fatalf("after repair %s: expected %s, got %s", s.Name, want[s.Name], s.State)
+		}
+	}
+	if b, _ := os.ReadFile(edited); !strings.Contains(string(b), "# my tweak") {
+		t.Fatalf("repair must not overwrite hand edits")
+	}
+
+	if _, changed, err := Ensure(dir, Target{Name: "claude-current", Profile: byName("claude-current")}, bin); err != nil || changed {
+		t.Fatalf("expected an up-to-date shim to be left alone, changed=%v err=%v", changed, err)
+	}
+}
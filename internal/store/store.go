@@ -18,6 +18,11 @@ import (
 const stateFileName = "state.json"
 const stateLockFileName = "state.lock"
 
+// GenerationFileName holds State.Generation as a bare number next to
+// state.json, so shims can tell whether state changed without taking the
+// lock or parsing JSON.
+const GenerationFileName = "generation"
+
 const (
 	lockWaitTimeout = 15 * time.Second
 	lockPollDelay   = 50 * time.Millisecond
@@ -33,27 +38,189 @@ const (
 	ToolCodex  Tool = "codex"
 )
 
+// SupportedTools lists the tools with a registered adapter definition, in
+// registration order. The adapters package fills it at startup.
 var SupportedTools = []Tool{ToolClaude, ToolCodex}
 
+// RegisterTool adds tool to SupportedTools if it is not already there.
+func RegisterTool(tool Tool) {
+	for _, t := range SupportedTools {
+		if t == tool {
+			return
+		}
+	}
+	SupportedTools = append(SupportedTools, tool)
+}
+
+// AuthMode selects how a profile authenticates. The zero value leaves auth
+// to the tool's own credential files.
+type AuthMode string
+
+const (
+	AuthModeDefault AuthMode = ""
+	AuthModeAPIKey  AuthMode = "api_key"
+)
+
+// EnvPolicy controls what happens to inherited environment variables that
+// conflict with a profile's account. The zero value means the global default.
+type EnvPolicy string
+
+const (
+	EnvPolicyDefault EnvPolicy = ""
+	EnvPolicyStrip   EnvPolicy = "strip"
+	EnvPolicyWarn    EnvPolicy = "warn"
+)
+
 type Profile struct {
 	Tool      Tool      `json:"tool"`
 	Name      string    `json:"name"`
 	Dir       string    `json:"dir"`
+	Auth      AuthMode  `json:"auth,omitempty"`
+	Vault     bool      `json:"vault,omitempty"`
+	EnvPolicy EnvPolicy `json:"env_policy,omitempty"`
+	EnvAllow  []string  `json:"env_allow,omitempty"`
+	// Binary pins the tool executable for this profile, overriding the
+	// tool-wide pin and the adapter default.
+	Binary string `json:"binary,omitempty"`
+	// Color is a #rrggbb hex color for terminal tabs and status lines.
+	Color     string    `json:"color,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 type SettingsPreset struct {
-	Tool      Tool      `json:"tool"`
-	Name      string    `json:"name"`
+	Tool Tool   `json:"tool"`
+	Name string `json:"name"`
+	// Include adds paths, relative to the profile directory, to the tool's
+	// default settings allowlist. Exclude drops allowlisted paths, or files
+	// inside allowlisted directories; entries may be path.Match patterns.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// Paths is what the last snapshot captured. Presets snapshotted before
+	// it was recorded leave it empty.
+	Paths []string `json:"paths,omitempty"`
+	// Merge is how applying the preset combines JSON and TOML files with
+	// the profile's own; empty means MergeReplace.
+	Merge MergeStrategy `json:"merge,omitempty"`
+	// Parent names a preset of the same tool this one extends: its files
+	// are applied first and this preset's are layered on top per Merge.
+	Parent string `json:"parent,omitempty"`
+	// Revisions lists the snapshots kept for rollback, oldest first.
+	Revisions []PresetRevision `json:"revisions,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// PresetRevision is one numbered snapshot of a settings preset. Its files
+// are kept under preset-history/<tool>/<name>/<rev>.
+type PresetRevision struct {
+	Rev int `json:"rev"`
+	// Source is the profile the snapshot came from, "rollback to N", or
+	// empty for content that predates revision history.
+	Source string `json:"source"`
+	// Hash fingerprints the captured files.
+	Hash      string    `json:"hash"`
+	Paths     []string  `json:"paths,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// LatestRevision returns the preset's newest revision, or nil.
+func (p *SettingsPreset) LatestRevision() *PresetRevision {
+	if len(p.Revisions) == 0 {
+		return nil
+	}
+	return &p.Revisions[len(p.Revisions)-1]
+}
+
+// FindRevision returns revision rev of the preset, or nil.
+func (p *SettingsPreset) FindRevision(rev int) *PresetRevision {
+	for i := range p.Revisions {
+		if p.Revisions[i].Rev == rev {
+			return &p.Revisions[i]
+		}
+	}
+	return nil
+}
+
+// MergeStrategy decides how a settings preset's files are combined with a
+// profile's when applied. Only JSON and TOML files merge; everything else is
+// replaced.
+type MergeStrategy string
+
+const (
+	// MergeReplace overwrites the profile's file with the preset's.
+	MergeReplace MergeStrategy = "replace"
+	// MergeDeep merges objects and tables key by key, the preset winning
+	// on conflicts; arrays are replaced.
+	MergeDeep MergeStrategy = "merge"
+	// MergeUnion merges like MergeDeep but unions arrays.
+	MergeUnion MergeStrategy = "union"
+)
+
+var MergeStrategies = []MergeStrategy{MergeReplace, MergeDeep, MergeUnion}
+
+func ParseMergeStrategy(raw string) (MergeStrategy, error) {
+	for _, m := range MergeStrategies {
+		if strings.EqualFold(strings.TrimSpace(raw), string(m)) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("invalid merge strategy %q (expected replace, merge or union)", raw)
+}
+
+// SyncEditPolicy decides what a settings sync does with files edited in the
+// profile since the preset was last applied.
+type SyncEditPolicy string
+
+const (
+	// SyncOverwrite replaces local edits with the preset.
+	SyncOverwrite SyncEditPolicy = "overwrite"
+	// SyncPreserve keeps local edits and stops syncing those files until
+	// they match the preset again.
+	SyncPreserve SyncEditPolicy = "preserve"
+	// SyncPromote copies local edits back into the preset.
+	SyncPromote SyncEditPolicy = "promote"
+)
+
+var SyncEditPolicies = []SyncEditPolicy{SyncOverwrite, SyncPreserve, SyncPromote}
+
 type SettingsSync struct {
+	Tool    Tool   `json:"tool"`
+	Profile string `json:"profile"`
+	Preset  string `json:"preset"`
+	// LocalEdits is empty for SyncOverwrite.
+	LocalEdits SyncEditPolicy `json:"local_edits,omitempty"`
+	// Baseline maps each allowlisted path to a digest of what the profile
+	// held after the last sync ("" when absent), so local edits can be told
+	// apart from preset changes. PresetBaseline does the same for the
+	// preset, which differs from the profile's copy when presets merge.
+	Baseline       map[string]string `json:"baseline,omitempty"`
+	PresetBaseline map[string]string `json:"preset_baseline,omitempty"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// Policy returns the binding's edit policy, defaulting to SyncOverwrite.
+func (s SettingsSync) Policy() SyncEditPolicy {
+	if s.LocalEdits == "" {
+		return SyncOverwrite
+	}
+	return s.LocalEdits
+}
+
+func ParseSyncEditPolicy(raw string) (SyncEditPolicy, error) {
+	for _, p := range SyncEditPolicies {
+		if strings.EqualFold(strings.TrimSpace(raw), string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid local edit policy %q (expected overwrite, preserve or promote)", raw)
+}
+
+// Alias names an extra shim for a profile.
+type Alias struct {
+	Name      string    `json:"name"`
 	Tool      Tool      `json:"tool"`
 	Profile   string    `json:"profile"`
-	Preset    string    `json:"preset"`
-	UpdatedAt time.Time `json:"updated_at"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type State struct {
@@ -62,6 +229,67 @@ type State struct {
 	Profiles        []Profile        `json:"profiles"`
 	SettingsPresets []SettingsPreset `json:"settings_presets,omitempty"`
 	SettingsSync    []SettingsSync   `json:"settings_sync,omitempty"`
+	// ToolBinaries pins a tool executable for every profile without its own
+	// pin.
+	ToolBinaries map[Tool]string `json:"tool_binaries,omitempty"`
+	Aliases      []Alias         `json:"aliases,omitempty"`
+	// ShimNameTemplates gives every profile of a tool an extra shim named
+	// after the template, such as "cc-{profile}".
+	ShimNameTemplates map[Tool]string `json:"shim_name_templates,omitempty"`
+	// Terminal selects the terminal integrations shims run. Nil means
+	// DefaultTerminalIntegrations.
+	Terminal *TerminalConfig `json:"terminal,omitempty"`
+	// CompiledShims lets shims reuse the environment `profilex shim env`
+	// last resolved until Generation changes.
+	CompiledShims bool `json:"compiled_shims,omitempty"`
+	// Generation increases on every save.
+	Generation uint64 `json:"generation,omitempty"`
+}
+
+// Terminal integrations a shim can run around the tool.
+const (
+	TermTitle   = "title"
+	TermTmux    = "tmux"
+	TermITerm   = "iterm"
+	TermWezTerm = "wezterm"
+)
+
+var TerminalIntegrations = []string{TermTitle, TermTmux, TermITerm, TermWezTerm}
+
+var DefaultTerminalIntegrations = []string{TermTitle}
+
+type TerminalConfig struct {
+	Integrations []string `json:"integrations"`
+}
+
+// TerminalIntegrations returns the enabled integrations in
+// TerminalIntegrations order.
+func (st *State) TerminalIntegrations() []string {
+	if st.Terminal == nil {
+		return append([]string{}, DefaultTerminalIntegrations...)
+	}
+	out := []string{}
+	for _, name := range TerminalIntegrations {
+		for _, enabled := range st.Terminal.Integrations {
+			if enabled == name {
+				out = append(out, name)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// BinaryFor returns the pinned executable for p and where the pin comes
+// from ("profile" or "tool"). It returns "" when nothing is pinned.
+func (st *State) BinaryFor(p Profile) (path string, scope string) {
+	if p.Binary != "" {
+		return p.Binary, "profile"
+	}
+	if bin := st.ToolBinaries[p.Tool]; bin != "" {
+		return bin, "tool"
+	}
+	return "", ""
 }
 
 type Store struct {
@@ -101,6 +329,23 @@ func (s *Store) lockPath() string {
 	return filepath.Join(s.root, stateLockFileName)
 }
 
+func (s *Store) generationPath() string {
+	return filepath.Join(s.root, GenerationFileName)
+}
+
+// ReadGeneration returns the generation of the last save without taking the
+// lock. It returns 0 before the first save.
+func (s *Store) ReadGeneration() (uint64, error) {
+	b, err := os.ReadFile(s.generationPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
 func (s *Store) Load() (*State, error) {
 	lock, err := s.acquireLock()
 	if err != nil {
@@ -150,6 +395,17 @@ func (s *Store) Update(fn func(*State) error) error {
 	return s.writeStateUnlocked(st)
 }
 
+// WithLock runs fn while holding the state lock, for callers that coordinate
+// files outside state.json.
+func (s *Store) WithLock(fn func() error) error {
+	lock, err := s.acquireLock()
+	if err != nil {
+		return err
+	}
+	defer s.releaseLock(lock)
+	return fn()
+}
+
 func IsSupportedTool(raw string) (Tool, bool) {
 	t := Tool(strings.ToLower(strings.TrimSpace(raw)))
 	for _, s := range SupportedTools {
@@ -171,6 +427,42 @@ func ValidatePresetName(name string) error {
 	return ValidateProfileName(name)
 }
 
+var colorPattern = regexp.MustCompile(`^#?[0-9a-fA-F]{6}$`)
+
+// NormalizeColor accepts "#rrggbb" or "rrggbb" and returns the lower-case
+// "#rrggbb" form.
+func NormalizeColor(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !colorPattern.MatchString(raw) {
+		return "", fmt.Errorf("invalid color %q (expected #rrggbb)", raw)
+	}
+	return "#" + strings.ToLower(strings.TrimPrefix(raw, "#")), nil
+}
+
+func ValidateAliasName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid alias %q (allowed: letters, digits, ., _, - ; max 64 chars)", name)
+	}
+	return nil
+}
+
+// ExpandShimNameTemplate replaces {tool} and {profile} in tmpl.
+func ExpandShimNameTemplate(tmpl string, tool Tool, profile string) string {
+	return strings.NewReplacer("{tool}", string(tool), "{profile}", profile).Replace(tmpl)
+}
+
+// ValidateShimNameTemplate requires {profile}, so each profile gets its own
+// name, and a valid name once expanded.
+func ValidateShimNameTemplate(tmpl string) error {
+	if !strings.Contains(tmpl, "{profile}") {
+		return fmt.Errorf("shim name template %q must contain {profile}", tmpl)
+	}
+	if name := ExpandShimNameTemplate(tmpl, "tool", "profile"); !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid shim name template %q (allowed: letters, digits, ., _, - around {tool} and {profile})", tmpl)
+	}
+	return nil
+}
+
 func ProfileDir(root string, tool Tool, name string) string {
 	return filepath.Join(root, "profiles", string(tool), name)
 }
@@ -185,6 +477,15 @@ func FindProfile(st *State, tool Tool, name string) (int, *Profile) {
 	return -1, nil
 }
 
+func FindAlias(st *State, name string) (int, *Alias) {
+	for i := range st.Aliases {
+		if st.Aliases[i].Name == name {
+			return i, &st.Aliases[i]
+		}
+	}
+	return -1, nil
+}
+
 func DefaultProfile(st *State, tool Tool) (string, bool) {
 	v, ok := st.Defaults[tool]
 	if !ok || strings.TrimSpace(v) == "" {
@@ -237,6 +538,9 @@ func normalizeState(st *State) {
 		st.SettingsSync = []SettingsSync{}
 	}
 	st.Version = 1
+	sort.Slice(st.Aliases, func(i, j int) bool {
+		return st.Aliases[i].Name < st.Aliases[j].Name
+	})
 	sort.Slice(st.Profiles, func(i, j int) bool {
 		if st.Profiles[i].Tool == st.Profiles[j].Tool {
 			return st.Profiles[i].Name < st.Profiles[j].Name
@@ -279,6 +583,12 @@ func (s *Store) writeStateUnlocked(st *State) error {
 		return errors.New("state cannot be nil")
 	}
 	normalizeState(st)
+	// A caller may save state it loaded before someone else's save, so count
+	// on from whichever generation is newer.
+	if cur, err := s.ReadGeneration(); err == nil && cur > st.Generation {
+		st.Generation = cur
+	}
+	st.Generation++
 
 	b, err := json.MarshalIndent(st, "", "  ")
 	if err != nil {
@@ -286,7 +596,19 @@ func (s *Store) writeStateUnlocked(st *State) error {
 	}
 	b = append(b, '\n')
 
-	tmp, err := os.CreateTemp(s.root, ".state-*.tmp")
+	if err := s.replaceFile(s.statePath(), ".state-*.tmp", b); err != nil {
+		return err
+	}
+	gen := []byte(strconv.FormatUint(st.Generation, 10) + "\n")
+	if err := s.replaceFile(s.generationPath(), ".generation-*.tmp", gen); err != nil {
+		return fmt.Errorf("write generation: %w", err)
+	}
+	return nil
+}
+
+// replaceFile atomically replaces path with b through a temp file in root.
+func (s *Store) replaceFile(path, pattern string, b []byte) error {
+	tmp, err := os.CreateTemp(s.root, pattern)
 	if err != nil {
 		return err
 	}
@@ -310,14 +632,14 @@ func (s *Store) writeStateUnlocked(st *State) error {
 		return err
 	}
 
-	if err := os.Rename(tmpPath, s.statePath()); err != nil {
+	if err := os.Rename(tmpPath, path); err != nil {
 		if runtime.GOOS != "windows" {
 			return err
 		}
-		if rmErr := os.Remove(s.statePath()); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
-			return fmt.Errorf("replace state file: %w", rmErr)
+		if rmErr := os.Remove(path); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+			return fmt.Errorf("replace %s: %w", filepath.Base(path), rmErr)
 		}
-		if err := os.Rename(tmpPath, s.statePath()); err != nil {
+		if err := os.Rename(tmpPath, path); err != nil {
 			return err
 		}
 	}
@@ -402,6 +724,12 @@ func lockPID(lockPath string) int {
 	return 0
 }
 
+// ProcessAlive reports whether pid is a running process. On Windows it errs
+// on the side of reporting the process as alive.
+func ProcessAlive(pid int) bool {
+	return processExists(pid)
+}
+
 func processExists(pid int) bool {
 	if pid <= 0 {
 		return false
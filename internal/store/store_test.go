@@ -49,6 +49,36 @@ func TestStoreRoundTrip(t *testing.T) {
 	}
 }
 
+func TestSaveAdvancesGenerationPastStaleState(t *testing.T) {
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Update(func(*State) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Save(stale); err != nil {
+		t.Fatal(err)
+	}
+	gen, err := s.ReadGeneration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen != 3 || loaded.Generation != 3 {
+		t.Fatalf("expected generation 3 in file and state, got %d and %d", gen, loaded.Generation)
+	}
+}
+
 func TestStoreUpdateSerializesConcurrentWrites(t *testing.T) {
 	dir := t.TempDir()
 	s, err := New(dir)
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/derekurban/profilex-cli/internal/adapters"
 	"github.com/derekurban/profilex-cli/internal/store"
 )
 
@@ -45,7 +46,7 @@ func (r *profileResolver) resolve(tool Tool, root string) profileMatch {
 
 	if r.state != nil {
 		for _, p := range r.state.Profiles {
-			if Tool(p.Tool) != tool {
+			if usageFormatForTool(p.Tool) != tool {
 				continue
 			}
 			d := normalizePath(strings.ToLower(p.Dir))
@@ -112,15 +113,18 @@ func discoverRoots(rootDir string, st *store.State) []string {
 		set[normalizePath(expandHome(p))] = true
 	}
 
-	add(filepath.Join(home, ".config", "claude", "projects"))
-	add(filepath.Join(home, ".claude", "projects"))
-	for _, p := range splitPathList(os.Getenv("CLAUDE_CONFIG_DIR")) {
-		add(ensureLeaf(p, "projects"))
-	}
-
-	add(filepath.Join(home, ".codex", "sessions"))
-	for _, p := range splitPathList(os.Getenv("CODEX_HOME")) {
-		add(ensureLeaf(p, "sessions"))
+	for _, def := range adapters.Definitions() {
+		if def.UsageFormat == "" || def.SessionLeaf == "" {
+			continue
+		}
+		for _, dir := range def.NativeConfigDirs {
+			add(ensureLeaf(adapters.ExpandHome(dir, home), def.SessionLeaf))
+		}
+		for _, e := range def.SessionEnvLeaves() {
+			for _, p := range splitPathList(os.Getenv(e.Name)) {
+				add(ensureLeaf(p, e.Value))
+			}
+		}
 	}
 
 	for _, p := range splitPathList(os.Getenv("PROFILEX_USAGE_EXTRA_ROOTS")) {
@@ -129,11 +133,9 @@ func discoverRoots(rootDir string, st *store.State) []string {
 
 	if st != nil {
 		for _, p := range st.Profiles {
-			leaf := "projects"
-			if p.Tool == store.ToolCodex {
-				leaf = "sessions"
+			if leaf, ok := sessionLeafForProfileTool(p.Tool); ok {
+				add(ensureLeaf(p.Dir, leaf))
 			}
-			add(ensureLeaf(p.Dir, leaf))
 		}
 	}
 
@@ -187,7 +189,14 @@ func collectJSONLFiles(roots []string, deep bool, maxFiles int) ([]usageFile, er
 
 	for _, root := range roots {
 		root = normalizePath(root)
-		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		// WalkDir does not descend into a symlinked root, which is exactly how
+		// shared session pools are mounted into profiles. Walk the resolved
+		// directory but record files under the profile path they were found at.
+		walkRoot := root
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			walkRoot = resolved
+		}
+		filepath.WalkDir(walkRoot, func(path string, d fs.DirEntry, err error) error {
 			if err != nil {
 				return nil
 			}
@@ -203,6 +212,11 @@ func collectJSONLFiles(roots []string, deep bool, maxFiles int) ([]usageFile, er
 				return nil
 			}
 			if strings.HasSuffix(strings.ToLower(d.Name()), ".jsonl") {
+				if walkRoot != root {
+					if rel, err := filepath.Rel(walkRoot, path); err == nil {
+						path = filepath.Join(root, rel)
+					}
+				}
 				addPath(path, root)
 			}
 			return nil
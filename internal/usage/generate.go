@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/derekurban/profilex-cli/internal/adapters"
 	"github.com/derekurban/profilex-cli/internal/store"
 )
 
@@ -234,10 +235,11 @@ func managedContributorsForFile(st *store.State, tool Tool, file usageFile) []co
 	out := make([]contributingProfile, 0)
 	seen := map[string]bool{}
 	for _, p := range st.Profiles {
-		if Tool(p.Tool) != tool {
+		profileLeaf, ok := sessionLeafForProfileTool(p.Tool)
+		if !ok || usageFormatForTool(p.Tool) != tool {
 			continue
 		}
-		profileRoot := ensureLeaf(p.Dir, leaf)
+		profileRoot := ensureLeaf(p.Dir, profileLeaf)
 		profileCanonical := canonicalizePath(profileRoot)
 
 		matches := false
@@ -297,15 +299,29 @@ func isSharedSessionFile(file usageFile, tool Tool, contributors []contributingP
 	return len(file.AliasPaths) > 1
 }
 
-func sessionLeafForUsageTool(tool Tool) (string, bool) {
-	switch tool {
-	case ToolClaude:
-		return "projects", true
-	case ToolCodex:
-		return "sessions", true
-	default:
+// usageFormatForTool maps a profile's tool to the session log format its
+// adapter definition declares.
+func usageFormatForTool(tool store.Tool) Tool {
+	if def, ok := adapters.Lookup(tool); ok && def.UsageFormat != "" {
+		return Tool(def.UsageFormat)
+	}
+	return ToolUnknown
+}
+
+// sessionLeafForProfileTool returns the session directory of a tool whose
+// adapter declares a usage format.
+func sessionLeafForProfileTool(tool store.Tool) (string, bool) {
+	def, ok := adapters.Lookup(tool)
+	if !ok || def.UsageFormat == "" || def.SessionLeaf == "" {
 		return "", false
 	}
+	return def.SessionLeaf, true
+}
+
+// sessionLeafForUsageTool returns the session directory for a log format,
+// taken from the adapter of the same name.
+func sessionLeafForUsageTool(tool Tool) (string, bool) {
+	return sessionLeafForProfileTool(store.Tool(tool))
 }
 
 func candidateRootSet(file usageFile, leaf string) map[string]bool {
@@ -0,0 +1,74 @@
+package usage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestGenerateBundleCountsLinkedPoolOnce(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("HOME", filepath.Join(base, "home"))
+	t.Setenv("CLAUDE_CONFIG_DIR", "")
+	t.Setenv("CODEX_HOME", "")
+
+	pool := filepath.Join(base, "shared", "claude", "projects")
+	writeJSONL(t, filepath.Join(pool, "demo", "shared-1.jsonl"),
+		`{"type":"assistant","sessionId":"shared-1","requestId":"r1","timestamp":"2026-01-01T00:00:00Z","costUSD":0.5,"message":{"id":"m1","model":"claude-sonnet-4","usage":{"input_tokens":100,"output_tokens":10}}}`,
+		`{"type":"assistant","sessionId":"shared-1","requestId":"r2","timestamp":"2026-01-01T00:00:01Z","costUSD":0.5,"message":{"id":"m2","model":"claude-sonnet-4","usage":{"input_tokens":200,"output_tokens":20}}}`,
+	)
+	st := &store.State{}
+	for _, name := range []string{"work", "personal"} {
+		dir := filepath.Join(base, "profiles", "claude", name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(pool, filepath.Join(dir, "projects")); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+		st.Profiles = append(st.Profiles, store.Profile{Tool: store.ToolClaude, Name: name, Dir: dir})
+	}
+
+	// Pricing is fetched over the network; a cancelled context skips it.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	generate := func() *UnifiedLocalBundle {
+		t.Helper()
+		bundle, err := GenerateBundle(ctx, st, filepath.Join(base, "state.json"), GenerateOptions{CostMode: CostModeDisplay, Timezone: "UTC"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bundle
+	}
+	totals := func(b *UnifiedLocalBundle) (events int, tokens int64, cost float64) {
+		for _, e := range b.Events {
+			events++
+			tokens += e.RawTotalTokens
+			cost += e.EffectiveCostUSD
+		}
+		return events, tokens, cost
+	}
+
+	// Export has always counted a pool reached through its own directory;
+	// walking the profiles' links into it must count it the same, once.
+	t.Setenv("PROFILEX_USAGE_EXTRA_ROOTS", pool)
+	events, tokens, cost := totals(generate())
+	if events != 2 || tokens != 330 || cost != 1 {
+		t.Fatalf("expected 2 events, 330 tokens and $1 from the pool, got %d events, %d tokens, $%v", events, tokens, cost)
+	}
+
+	t.Setenv("PROFILEX_USAGE_EXTRA_ROOTS", "")
+	linked := generate()
+	if e2, tok2, cost2 := totals(linked); e2 != events || tok2 != tokens || cost2 != cost {
+		t.Fatalf("expected unchanged totals through the profile links alone, got %d events, %d tokens, $%v", e2, tok2, cost2)
+	}
+	if len(linked.Source.UsageFiles) != 1 {
+		t.Fatalf("expected one canonical usage file, got %v", linked.Source.UsageFiles)
+	}
+	if e := linked.Events[0]; !e.IsSharedSession || len(e.SharedSessionProfileIDs) != 2 {
+		t.Fatalf("expected the event to be attributed to both linked profiles, got %+v", e)
+	}
+}
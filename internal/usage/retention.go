@@ -0,0 +1,70 @@
+package usage
+
+import (
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// SessionFile is one canonical session JSONL together with every profile
+// path it was discovered through.
+type SessionFile struct {
+	Path       string    `json:"path"`
+	AliasPaths []string  `json:"aliasPaths,omitempty"`
+	Roots      []string  `json:"roots,omitempty"`
+	Bytes      int64     `json:"bytes"`
+	ModTime    time.Time `json:"modTime"`
+}
+
+// ManagedSessionRoots returns the session/history directory of every
+// ProfileX-managed profile. Profiles linked to a shared pool resolve to the
+// same canonical files, which ListSessionFiles collapses.
+func ManagedSessionRoots(st *store.State) []string {
+	if st == nil {
+		return nil
+	}
+	set := map[string]bool{}
+	for _, p := range st.Profiles {
+		leaf, ok := sessionLeafForProfileTool(p.Tool)
+		if !ok {
+			continue
+		}
+		root := ensureLeaf(p.Dir, leaf)
+		if info, err := os.Stat(root); err == nil && info.IsDir() {
+			set[root] = true
+		}
+	}
+	out := make([]string, 0, len(set))
+	for r := range set {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ListSessionFiles walks roots using the same canonical-path dedupe as usage
+// export, so a session reachable through several profile links appears once.
+func ListSessionFiles(roots []string) ([]SessionFile, error) {
+	files, err := collectJSONLFiles(roots, false, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SessionFile, 0, len(files))
+	for _, f := range files {
+		info, err := os.Stat(f.ParsePath)
+		if err != nil {
+			continue
+		}
+		out = append(out, SessionFile{
+			Path:       f.ParsePath,
+			AliasPaths: f.AliasPaths,
+			Roots:      f.AliasRoots,
+			Bytes:      info.Size(),
+			ModTime:    info.ModTime(),
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,60 @@
+package usage
+
+import (
+	"os"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+// DayTotals sums one profile's usage over a local calendar day.
+type DayTotals struct {
+	Date    string  `json:"date"`
+	Events  int     `json:"events"`
+	Tokens  int64   `json:"tokens"`
+	CostUSD float64 `json:"costUsd"`
+}
+
+// ProfileDay totals the usage profile logged on day's local date. Only the
+// profile's own session directory is read, and only files modified since
+// the day began, so it is cheap enough for status lines. Costs are the ones
+// the logs record; the pricing catalog is not fetched.
+func ProfileDay(st *store.State, profile store.Profile, day time.Time) (DayTotals, error) {
+	out := DayTotals{Date: day.Format("2006-01-02")}
+	leaf, ok := sessionLeafForProfileTool(profile.Tool)
+	if !ok {
+		return out, nil
+	}
+	root := ensureLeaf(profile.Dir, leaf)
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return out, nil
+	}
+	files, err := collectJSONLFiles([]string{root}, false, 0)
+	if err != nil {
+		return out, err
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	opts := GenerateOptions{Timezone: day.Location().String(), CostMode: CostModeDisplay}
+	resolver := newProfileResolver(st)
+	claudeSeen := map[string]bool{}
+	id := string(profile.Tool) + "/" + profile.Name
+	for _, file := range files {
+		if info, err := os.Stat(file.ParsePath); err != nil || info.ModTime().Before(start) {
+			continue
+		}
+		rows, _, err := parseUsageFile(file.ParsePath, resolver, opts, nil, claudeSeen)
+		if err != nil {
+			continue
+		}
+		for _, row := range annotateSharedMetadata(rows, file, st) {
+			if row.ProfileID != id || row.DateLocal != out.Date {
+				continue
+			}
+			out.Events++
+			out.Tokens += row.NormalizedTotalTokens
+			out.CostUSD += row.EffectiveCostUSD
+		}
+	}
+	return out, nil
+}
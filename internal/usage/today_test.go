@@ -0,0 +1,38 @@
+package usage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+func TestProfileDayTotalsOnlyTodaysEvents(t *testing.T) {
+	profile := store.Profile{Tool: store.ToolClaude, Name: "work", Dir: filepath.Join(t.TempDir(), "claude", "work")}
+	st := &store.State{Profiles: []store.Profile{profile}}
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	path := filepath.Join(profile.Dir, "projects", "demo", "abc-123.jsonl")
+	writeJSONL(t, path,
+		`{"type":"assistant","sessionId":"abc-123","requestId":"r0","timestamp":"`+yesterday.UTC().Format(time.RFC3339)+`","costUSD":9,"message":{"id":"m0","model":"claude-sonnet-4","usage":{"input_tokens":900,"output_tokens":100}}}`,
+		`{"type":"assistant","sessionId":"abc-123","requestId":"r1","timestamp":"`+now.UTC().Format(time.RFC3339)+`","costUSD":0.25,"message":{"id":"m1","model":"claude-sonnet-4","usage":{"input_tokens":1000,"output_tokens":500}}}`,
+	)
+
+	got, err := ProfileDay(st, profile, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Date != now.Format("2006-01-02") || got.Events != 1 || got.Tokens != 1500 || got.CostUSD != 0.25 {
+		t.Fatalf("unexpected totals: %+v", got)
+	}
+
+	old := now.AddDate(0, 0, -2)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ProfileDay(st, profile, now); got.Events != 0 {
+		t.Fatalf("expected files untouched today to be skipped, got %+v", got)
+	}
+}
@@ -0,0 +1,577 @@
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/derekurban/profilex-cli/internal/store"
+)
+
+type TranscriptFormat string
+
+const (
+	TranscriptMarkdown TranscriptFormat = "md"
+	TranscriptHTML     TranscriptFormat = "html"
+	TranscriptJSON     TranscriptFormat = "json"
+)
+
+type TurnKind string
+
+const (
+	TurnUser       TurnKind = "user"
+	TurnAssistant  TurnKind = "assistant"
+	TurnReasoning  TurnKind = "reasoning"
+	TurnToolCall   TurnKind = "tool_call"
+	TurnToolResult TurnKind = "tool_result"
+	TurnSystem     TurnKind = "system"
+)
+
+type TurnUsage struct {
+	InputTokens     int64 `json:"inputTokens"`
+	CachedTokens    int64 `json:"cachedTokens,omitempty"`
+	OutputTokens    int64 `json:"outputTokens"`
+	ReasoningTokens int64 `json:"reasoningTokens,omitempty"`
+	CacheWrite      int64 `json:"cacheWriteTokens,omitempty"`
+}
+
+type TranscriptTurn struct {
+	Kind       TurnKind   `json:"kind"`
+	Timestamp  string     `json:"timestamp,omitempty"`
+	Model      string     `json:"model,omitempty"`
+	Text       string     `json:"text,omitempty"`
+	ToolName   string     `json:"toolName,omitempty"`
+	ToolCallID string     `json:"toolCallId,omitempty"`
+	Usage      *TurnUsage `json:"usage,omitempty"`
+}
+
+type Transcript struct {
+	SessionID  string           `json:"sessionId"`
+	Tool       Tool             `json:"tool"`
+	SourceFile string           `json:"sourceFile"`
+	Project    string           `json:"project,omitempty"`
+	Cwd        string           `json:"cwd,omitempty"`
+	StartedAt  string           `json:"startedAt,omitempty"`
+	Turns      []TranscriptTurn `json:"turns"`
+	Totals     TurnUsage        `json:"totals"`
+	Malformed  int              `json:"malformedLines,omitempty"`
+}
+
+type RedactOptions struct {
+	Paths   bool
+	Secrets bool
+}
+
+// FindSessionFile resolves a session id (or a unique fragment of one) to the
+// canonical JSONL file across every discovered usage root.
+func FindSessionFile(rootDir string, st *store.State, id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", errors.New("session id cannot be empty")
+	}
+	if info, err := os.Stat(id); err == nil && !info.IsDir() {
+		return id, nil
+	}
+
+	files, err := collectJSONLFiles(discoverRoots(rootDir, st), false, math.MaxInt)
+	if err != nil {
+		return "", err
+	}
+	exact := []string{}
+	partial := []string{}
+	for _, f := range files {
+		base := strings.TrimSuffix(filepath.Base(f.ParsePath), ".jsonl")
+		switch {
+		case base == id:
+			exact = append(exact, f.ParsePath)
+		case strings.Contains(base, id):
+			partial = append(partial, f.ParsePath)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+	matches := append(exact, partial...)
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("session not found: %s", id)
+	case 1:
+		return matches[0], nil
+	default:
+		sort.Strings(matches)
+		return "", fmt.Errorf("session id %q is ambiguous (%d matches, e.g. %s)", id, len(matches), matches[0])
+	}
+}
+
+// LoadTranscript flattens a Claude or Codex session JSONL into ordered turns.
+func LoadTranscript(path string) (*Transcript, error) {
+	entries, malformed, err := flattenJSONL(path)
+	if err != nil {
+		return nil, err
+	}
+	tool := inferTool(path, entries, GenerateOptions{})
+	t := &Transcript{
+		SessionID:  strings.TrimSuffix(filepath.Base(path), ".jsonl"),
+		Tool:       tool,
+		SourceFile: normalizePath(path),
+		Turns:      []TranscriptTurn{},
+		Malformed:  malformed,
+	}
+	switch tool {
+	case ToolCodex:
+		buildCodexTranscript(t, entries)
+	default:
+		buildClaudeTranscript(t, entries)
+	}
+	for _, turn := range t.Turns {
+		if turn.Usage == nil {
+			continue
+		}
+		t.Totals.InputTokens += turn.Usage.InputTokens
+		t.Totals.CachedTokens += turn.Usage.CachedTokens
+		t.Totals.OutputTokens += turn.Usage.OutputTokens
+		t.Totals.ReasoningTokens += turn.Usage.ReasoningTokens
+		t.Totals.CacheWrite += turn.Usage.CacheWrite
+	}
+	if t.Project == "" {
+		t.Project = getProjectFromCwd(t.Cwd)
+	}
+	return t, nil
+}
+
+func buildClaudeTranscript(t *Transcript, entries []parsedEntry) {
+	seen := map[string]bool{}
+	for _, e := range entries {
+		obj := e.Obj
+		typeV := strings.ToLower(getString(obj, "type"))
+		if typeV != "user" && typeV != "assistant" && typeV != "system" {
+			continue
+		}
+		if sid := getStringAny(obj, "sessionId", "session_id"); sid != "" {
+			t.SessionID = sid
+		}
+		if cwd := getString(obj, "cwd"); cwd != "" && t.Cwd == "" {
+			t.Cwd = cwd
+		}
+		ts := getString(obj, "timestamp")
+		if t.StartedAt == "" {
+			t.StartedAt = ts
+		}
+		msg := getMap(obj, "message")
+		model := getString(msg, "model")
+
+		start := len(t.Turns)
+		switch content := getAny(msg, "content").(type) {
+		case string:
+			t.Turns = append(t.Turns, TranscriptTurn{Kind: roleKind(typeV), Timestamp: ts, Model: model, Text: content})
+		case []any:
+			for _, raw := range content {
+				block, ok := raw.(map[string]any)
+				if !ok {
+					continue
+				}
+				turn := TranscriptTurn{Timestamp: ts, Model: model}
+				switch getString(block, "type") {
+				case "text":
+					turn.Kind = roleKind(typeV)
+					turn.Text = getString(block, "text")
+				case "thinking":
+					turn.Kind = TurnReasoning
+					turn.Text = getString(block, "thinking")
+				case "tool_use":
+					turn.Kind = TurnToolCall
+					turn.ToolName = getString(block, "name")
+					turn.ToolCallID = getString(block, "id")
+					turn.Text = compactJSON(getAny(block, "input"))
+				case "tool_result":
+					turn.Kind = TurnToolResult
+					turn.ToolCallID = getString(block, "tool_use_id")
+					turn.Text = flattenContent(getAny(block, "content"))
+				default:
+					continue
+				}
+				if strings.TrimSpace(turn.Text) == "" && turn.Kind != TurnToolCall {
+					continue
+				}
+				t.Turns = append(t.Turns, turn)
+			}
+		default:
+			if text := getString(obj, "content"); text != "" {
+				t.Turns = append(t.Turns, TranscriptTurn{Kind: roleKind(typeV), Timestamp: ts, Text: text})
+			}
+		}
+
+		usage := getMap(msg, "usage")
+		if usage == nil || len(t.Turns) == start {
+			continue
+		}
+		if key := dedupeClaude(obj); key != "" {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		t.Turns[len(t.Turns)-1].Usage = &TurnUsage{
+			InputTokens:  getInt64Any(usage, "input_tokens", "inputTokens"),
+			CachedTokens: getInt64Any(usage, "cache_read_input_tokens", "cacheReadInputTokens"),
+			OutputTokens: getInt64Any(usage, "output_tokens", "outputTokens"),
+			CacheWrite:   getInt64Any(usage, "cache_creation_input_tokens", "cacheCreationInputTokens"),
+		}
+	}
+}
+
+func buildCodexTranscript(t *Transcript, entries []parsedEntry) {
+	model := ""
+	for _, e := range entries {
+		obj := e.Obj
+		typeV := strings.ToLower(getString(obj, "type"))
+		payload := getMap(obj, "payload")
+		ts := firstNonEmpty(getString(obj, "timestamp"), getString(payload, "timestamp"))
+		if t.StartedAt == "" {
+			t.StartedAt = ts
+		}
+
+		switch typeV {
+		case "session_meta":
+			if id := getString(payload, "id"); id != "" {
+				t.SessionID = id
+			}
+			if cwd := getString(payload, "cwd"); cwd != "" {
+				t.Cwd = cwd
+			}
+			if m := extractModel(payload); m != "" {
+				model = m
+			}
+		case "turn_context":
+			if m := extractModel(payload); m != "" {
+				model = m
+			}
+			if cwd := getString(payload, "cwd"); cwd != "" && t.Cwd == "" {
+				t.Cwd = cwd
+			}
+		case "response_item":
+			turn := TranscriptTurn{Timestamp: ts, Model: model}
+			switch getString(payload, "type") {
+			case "message":
+				role := getString(payload, "role")
+				if role == "developer" {
+					role = "system"
+				}
+				turn.Kind = roleKind(role)
+				turn.Text = flattenContent(getAny(payload, "content"))
+			case "reasoning":
+				turn.Kind = TurnReasoning
+				turn.Text = flattenContent(getAny(payload, "summary"))
+			case "function_call", "custom_tool_call", "local_shell_call":
+				turn.Kind = TurnToolCall
+				turn.ToolName = firstNonEmpty(getString(payload, "name"), getString(payload, "type"))
+				turn.ToolCallID = getString(payload, "call_id")
+				turn.Text = firstNonEmpty(getStringAny(payload, "arguments", "input"), compactJSON(getAny(payload, "action")))
+			case "function_call_output", "custom_tool_call_output":
+				turn.Kind = TurnToolResult
+				turn.ToolCallID = getString(payload, "call_id")
+				turn.Text = flattenContent(getAny(payload, "output"))
+			default:
+				continue
+			}
+			if strings.TrimSpace(turn.Text) == "" && turn.Kind != TurnToolCall {
+				continue
+			}
+			t.Turns = append(t.Turns, turn)
+		case "event_msg":
+			if strings.ToLower(getString(payload, "type")) != "token_count" || len(t.Turns) == 0 {
+				continue
+			}
+			info := getMap(payload, "info")
+			u := extractUsage(getAny(info, "last_token_usage"), getAny(payload, "last_token_usage"))
+			if u == nil {
+				continue
+			}
+			idx := lastTurnIndex(t.Turns, TurnAssistant)
+			if idx < 0 {
+				idx = len(t.Turns) - 1
+			}
+			t.Turns[idx].Usage = &TurnUsage{
+				InputTokens:     u.Input,
+				CachedTokens:    u.Cached,
+				OutputTokens:    u.Output,
+				ReasoningTokens: u.Reasoning,
+			}
+		}
+	}
+}
+
+func roleKind(role string) TurnKind {
+	switch strings.ToLower(role) {
+	case "assistant":
+		return TurnAssistant
+	case "system":
+		return TurnSystem
+	default:
+		return TurnUser
+	}
+}
+
+func lastTurnIndex(turns []TranscriptTurn, kind TurnKind) int {
+	for i := len(turns) - 1; i >= 0; i-- {
+		if turns[i].Kind == kind && turns[i].Usage == nil {
+			return i
+		}
+		if turns[i].Kind == TurnUser {
+			break
+		}
+	}
+	return -1
+}
+
+func flattenContent(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []any:
+		parts := []string{}
+		for _, item := range t {
+			if s := flattenContent(item); strings.TrimSpace(s) != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, "\n")
+	case map[string]any:
+		if s := getStringAny(t, "text", "output", "content"); s != "" {
+			return s
+		}
+		if inner, ok := t["content"]; ok {
+			return flattenContent(inner)
+		}
+		return compactJSON(t)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func compactJSON(v any) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-ant-[A-Za-z0-9_\-]{10,}`),
+	regexp.MustCompile(`sk-(?:proj-)?[A-Za-z0-9_\-]{20,}`),
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{20,}`),
+	regexp.MustCompile(`github_pat_[A-Za-z0-9_]{20,}`),
+	regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
+	regexp.MustCompile(`xox[abprs]-[A-Za-z0-9\-]{10,}`),
+	regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._\-]{16,}`),
+	regexp.MustCompile(`eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`),
+}
+
+var secretAssignmentPattern = regexp.MustCompile(`(?i)((?:api[_-]?key|secret|token|password|passwd)["']?\s*[:=]\s*["']?)[^\s"',;]{6,}`)
+
+// RedactTranscript rewrites turn text in place, replacing home-relative and
+// project paths and anything that looks like a credential.
+func RedactTranscript(t *Transcript, opts RedactOptions) {
+	if t == nil || (!opts.Paths && !opts.Secrets) {
+		return
+	}
+	home, _ := os.UserHomeDir()
+	redact := func(s string) string {
+		if opts.Secrets {
+			s = redactSecrets(s)
+		}
+		if opts.Paths {
+			s = redactPaths(s, t.Cwd, home)
+		}
+		return s
+	}
+	for i := range t.Turns {
+		t.Turns[i].Text = redact(t.Turns[i].Text)
+	}
+	if opts.Paths {
+		t.SourceFile = redactPaths(t.SourceFile, "", home)
+		// The project name is the cwd's last element.
+		if t.Project != "" {
+			t.Project = "<project>"
+		}
+		t.Cwd = redactPaths(t.Cwd, t.Cwd, home)
+	}
+}
+
+func redactSecrets(s string) string {
+	for _, re := range secretPatterns {
+		s = re.ReplaceAllString(s, "[REDACTED]")
+	}
+	return secretAssignmentPattern.ReplaceAllString(s, "${1}[REDACTED]")
+}
+
+func redactPaths(s, cwd, home string) string {
+	replace := func(s, prefix, with string) string {
+		prefix = strings.TrimRight(prefix, `/\`)
+		if len(prefix) < 2 {
+			return s
+		}
+		s = strings.ReplaceAll(s, prefix, with)
+		if alt := strings.ReplaceAll(prefix, `\`, "/"); alt != prefix {
+			s = strings.ReplaceAll(s, alt, with)
+		}
+		return s
+	}
+	if cwd != "" {
+		s = replace(s, cwd, "<project>")
+	}
+	if home != "" {
+		s = replace(s, home, "~")
+	}
+	return s
+}
+
+func RenderTranscript(w io.Writer, t *Transcript, format TranscriptFormat) error {
+	switch format {
+	case TranscriptJSON:
+		b, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, '\n'))
+		return err
+	case TranscriptHTML:
+		return transcriptHTML.Execute(w, t)
+	case TranscriptMarkdown, "":
+		return renderTranscriptMarkdown(w, t)
+	default:
+		return fmt.Errorf("unsupported transcript format %q (expected md|html|json)", format)
+	}
+}
+
+func renderTranscriptMarkdown(w io.Writer, t *Transcript) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Session %s\n\n", t.SessionID)
+	fmt.Fprintf(&b, "- Tool: %s\n", t.Tool)
+	if t.Project != "" {
+		fmt.Fprintf(&b, "- Project: %s\n", t.Project)
+	}
+	if t.StartedAt != "" {
+		fmt.Fprintf(&b, "- Started: %s\n", t.StartedAt)
+	}
+	fmt.Fprintf(&b, "- Source: `%s`\n", t.SourceFile)
+	fmt.Fprintf(&b, "- Tokens: %s\n\n", formatTurnUsage(t.Totals))
+
+	for _, turn := range t.Turns {
+		b.WriteString("## " + turnHeading(turn) + "\n\n")
+		switch turn.Kind {
+		case TurnToolCall, TurnToolResult:
+			fence := markdownFence(turn.Text)
+			fmt.Fprintf(&b, "%s\n%s\n%s\n\n", fence, strings.TrimRight(turn.Text, "\n"), fence)
+		case TurnReasoning:
+			for _, line := range strings.Split(strings.TrimRight(turn.Text, "\n"), "\n") {
+				b.WriteString("> " + line + "\n")
+			}
+			b.WriteString("\n")
+		default:
+			b.WriteString(strings.TrimRight(turn.Text, "\n") + "\n\n")
+		}
+		if turn.Usage != nil {
+			fmt.Fprintf(&b, "_Tokens: %s_\n\n", formatTurnUsage(*turn.Usage))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func turnHeading(turn TranscriptTurn) string {
+	label := map[TurnKind]string{
+		TurnUser:       "User",
+		TurnAssistant:  "Assistant",
+		TurnReasoning:  "Reasoning",
+		TurnToolCall:   "Tool call",
+		TurnToolResult: "Tool result",
+		TurnSystem:     "System",
+	}[turn.Kind]
+	if turn.ToolName != "" {
+		label += ": " + turn.ToolName
+	}
+	if turn.Timestamp != "" {
+		label += " (" + turn.Timestamp + ")"
+	}
+	return label
+}
+
+func markdownFence(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence
+}
+
+func formatTurnUsage(u TurnUsage) string {
+	s := fmt.Sprintf("in %d / out %d", u.InputTokens, u.OutputTokens)
+	if u.CachedTokens > 0 {
+		s += fmt.Sprintf(" / cached %d", u.CachedTokens)
+	}
+	if u.CacheWrite > 0 {
+		s += fmt.Sprintf(" / cache write %d", u.CacheWrite)
+	}
+	if u.ReasoningTokens > 0 {
+		s += fmt.Sprintf(" / reasoning %d", u.ReasoningTokens)
+	}
+	return s
+}
+
+var transcriptHTML = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"heading": turnHeading,
+	"usage": func(u any) string {
+		switch v := u.(type) {
+		case *TurnUsage:
+			return formatTurnUsage(*v)
+		case TurnUsage:
+			return formatTurnUsage(v)
+		}
+		return ""
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Session {{.SessionID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 920px; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+.meta { color: #59636e; font-size: 0.9rem; }
+.turn { border-left: 4px solid #d0d7de; margin: 1rem 0; padding: 0.25rem 1rem; }
+.turn h2 { font-size: 0.95rem; margin: 0.5rem 0; }
+.user { border-color: #0969da; }
+.assistant { border-color: #1a7f37; }
+.reasoning { border-color: #8250df; color: #59636e; font-style: italic; }
+.tool_call, .tool_result { border-color: #9a6700; }
+pre { white-space: pre-wrap; word-break: break-word; background: #f6f8fa; padding: 0.75rem; border-radius: 6px; }
+.tokens { color: #59636e; font-size: 0.8rem; }
+</style>
+</head>
+<body>
+<h1>Session {{.SessionID}}</h1>
+<p class="meta">Tool: {{.Tool}}{{if .Project}} · Project: {{.Project}}{{end}}{{if .StartedAt}} · Started: {{.StartedAt}}{{end}}<br>
+Source: <code>{{.SourceFile}}</code><br>
+Tokens: {{usage .Totals}}</p>
+{{range .Turns}}<div class="turn {{.Kind}}">
+<h2>{{heading .}}</h2>
+<pre>{{.Text}}</pre>
+{{if .Usage}}<p class="tokens">Tokens: {{usage .Usage}}</p>{{end}}
+</div>
+{{end}}</body>
+</html>
+`))
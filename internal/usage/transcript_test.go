@@ -0,0 +1,121 @@
+package usage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeJSONL(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadTranscriptClaude(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects", "demo", "abc-123.jsonl")
+	writeJSONL(t, path,
+		`{"type":"user","sessionId":"abc-123","cwd":"/work/demo","timestamp":"2026-01-01T00:00:00Z","message":{"role":"user","content":"fix the bug"}}`,
+		`{"type":"assistant","sessionId":"abc-123","requestId":"r1","timestamp":"2026-01-01T00:00:01Z","message":{"id":"m1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"thinking","thinking":"look at main.go"},{"type":"text","text":"Reading the file."},{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/work/demo/main.go"}}],"usage":{"input_tokens":10,"output_tokens":5}}}`,
+		`{"type":"user","sessionId":"abc-123","timestamp":"2026-01-01T00:00:02Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"package main"}]}}`,
+	)
+
+	tr, err := LoadTranscript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Tool != ToolClaude || tr.SessionID != "abc-123" || tr.Project != "demo" {
+		t.Fatalf("unexpected transcript header: %+v", tr)
+	}
+	kinds := []TurnKind{}
+	for _, turn := range tr.Turns {
+		kinds = append(kinds, turn.Kind)
+	}
+	want := []TurnKind{TurnUser, TurnReasoning, TurnAssistant, TurnToolCall, TurnToolResult}
+	if len(kinds) != len(want) {
+		t.Fatalf("unexpected turns: %v", kinds)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("unexpected turns: %v", kinds)
+		}
+	}
+	if tr.Totals.InputTokens != 10 || tr.Totals.OutputTokens != 5 {
+		t.Fatalf("unexpected totals: %+v", tr.Totals)
+	}
+}
+
+func TestLoadTranscriptCodexAttachesTokenCounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions", "2026", "01", "01", "rollout-xyz.jsonl")
+	writeJSONL(t, path,
+		`{"timestamp":"2026-01-01T00:00:00Z","type":"session_meta","payload":{"id":"xyz","cwd":"/work/api"}}`,
+		`{"timestamp":"2026-01-01T00:00:01Z","type":"turn_context","payload":{"model":"gpt-5-codex"}}`,
+		`{"timestamp":"2026-01-01T00:00:02Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"run tests"}]}}`,
+		`{"timestamp":"2026-01-01T00:00:03Z","type":"response_item","payload":{"type":"reasoning","summary":[{"type":"summary_text","text":"Plan: run go test"}]}}`,
+		`{"timestamp":"2026-01-01T00:00:04Z","type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{\"command\":[\"go\",\"test\"]}","call_id":"c1"}}`,
+		`{"timestamp":"2026-01-01T00:00:05Z","type":"response_item","payload":{"type":"function_call_output","call_id":"c1","output":"ok"}}`,
+		`{"timestamp":"2026-01-01T00:00:06Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"All green."}]}}`,
+		`{"timestamp":"2026-01-01T00:00:07Z","type":"event_msg","payload":{"type":"token_count","info":{"last_token_usage":{"input_tokens":100,"cached_input_tokens":40,"output_tokens":20,"reasoning_output_tokens":8}}}}`,
+	)
+
+	tr, err := LoadTranscript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Tool != ToolCodex || tr.SessionID != "xyz" {
+		t.Fatalf("unexpected transcript header: %+v", tr)
+	}
+	last := tr.Turns[len(tr.Turns)-1]
+	if last.Kind != TurnAssistant || last.Usage == nil || last.Usage.InputTokens != 100 || last.Usage.ReasoningTokens != 8 {
+		t.Fatalf("token counts should attach to the assistant turn: %+v", last)
+	}
+	if last.Model != "gpt-5-codex" {
+		t.Fatalf("expected model from turn_context, got %q", last.Model)
+	}
+}
+
+func TestRedactTranscriptAndRender(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	tr := &Transcript{
+		SessionID: "s1",
+		Tool:      ToolClaude,
+		Project:   "secret-project",
+		Cwd:       "/work/secret-project",
+		Turns: []TranscriptTurn{
+			{Kind: TurnUser, Text: "key is sk-ant-REDACTED and dir /work/secret-project/main.go"},
+			{Kind: TurnToolResult, Text: "OPENAI_API_KEY=abcdef123456 in " + filepath.Join(home, ".bashrc")},
+		},
+	}
+	RedactTranscript(tr, RedactOptions{Paths: true, Secrets: true})
+
+	var buf bytes.Buffer
+	if err := RenderTranscript(&buf, tr, TranscriptMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, leaked := range []string{"sk-ant-api03", "abcdef123456", "secret-project"} {
+		if strings.Contains(out, leaked) {
+			t.Fatalf("redacted output still contains %q:\n%s", leaked, out)
+		}
+	}
+	if !strings.Contains(out, "<project>/main.go") {
+		t.Fatalf("expected project path placeholder:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := RenderTranscript(&buf, tr, TranscriptHTML); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "&lt;project&gt;/main.go") {
+		t.Fatalf("expected escaped html output:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "secret-project") {
+		t.Fatalf("redacted html still names the project:\n%s", buf.String())
+	}
+}